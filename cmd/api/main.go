@@ -1,30 +1,40 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/gin-gonic/gin"
-
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
 	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/configs"
 	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/db"
 	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/http"
 	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/http/handlers"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/mailer"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/ratelimit"
 	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security/webauthn"
 	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
 )
 
 func main() {
 
-	// Cargar claves RSA al iniciar la aplicación
-	err := security.LoadKeys()
+	// Las claves de los archivos PEM solo se usan como clave inicial del keyring
+	legacyKey, err := security.LoadKeys()
 	if err != nil {
-		log.Fatalf("Error cargando las claves RSA: %v", err)
+		log.Printf("No se cargaron las claves de los archivos PEM: %v", err)
 	}
 
 	// Cargar variables de entorno
 	configs.LoadEnv()
 
+	// Los hashes con otros parámetros se actualizan en el siguiente inicio de sesión
+	security.SetPasswordHasher(security.NewArgon2idHasher(argon2idParamsFromEnv(security.DefaultArgon2idParams)))
+
 	// Conectar a la base de datos
 	dsn := fmt.Sprintf(
 		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
@@ -41,25 +51,242 @@ func main() {
 	}
 	defer database.Close()
 
+	// Aplicar los cambios del esquema pendientes, también en bases ya existentes
+	if err := db.Migrate(context.Background(), database); err != nil {
+		log.Fatalf("Error al migrar la base de datos: %v", err)
+	}
+
 	// Crear repositorio de usuarios
 	userRepo := db.NewUserRepositoryPg(database)
 	sessionRepo := db.NewSessionRepositorypg(database)
+	securityEventRepo := db.NewSecurityEventRepositoryPg(database)
+	mfaRepo := db.NewMFARepositoryPg(database)
+	webauthnRepo := db.NewWebAuthnRepositoryPg(database)
+	passwordResetRepo := db.NewPasswordResetRepositoryPg(database)
+	knownDeviceRepo := db.NewKnownDeviceRepositoryPg(database)
+	magicLinkRepo := db.NewMagicLinkRepositoryPg(database)
+	emailOTPRepo := db.NewEmailOTPRepositoryPg(database)
+	oauthRepo := db.NewOAuthRepositoryPg(database)
+	signingKeyRepo := db.NewSigningKeyRepositoryPg(database)
+	revokedTokenRepo := db.NewRevokedTokenRepositoryPg(database)
+	loginAttemptRepo := db.NewLoginAttemptRepositoryPg(database)
+
+	// Identidad del Relying Party para las llaves de acceso
+	webauthnConfig := webauthn.Config{
+		RPID:    configs.GetEnv("WEBAUTHN_RP_ID", "localhost"),
+		RPName:  configs.GetEnv("WEBAUTHN_RP_NAME", "Auth UCP"),
+		Origins: strings.Split(configs.GetEnv("WEBAUTHN_ORIGINS", "http://localhost:8080"), ","),
+	}
+
+	// Envío de correos con plantillas localizadas
+	mailSender, err := newMailSender(configs.GetEnv("MAIL_DRIVER", ""))
+	if err != nil {
+		log.Fatalf("Error configurando el envío de correos: %v", err)
+	}
+	mailRenderer, err := mailer.NewRenderer(configs.GetEnv("MAIL_APP_NAME", "Auth UCP"), configs.GetEnv("MAIL_DEFAULT_LOCALE", "es"))
+	if err != nil {
+		log.Fatalf("Error cargando las plantillas de correo: %v", err)
+	}
+	mail := mailer.New(mailSender, mailRenderer)
+
+	// Secreto para firmar los enlaces enviados por correo
+	linkSecret := []byte(configs.GetEnv("LINK_SIGNING_SECRET", ""))
+	if len(linkSecret) == 0 {
+		log.Println("Advertencia: sin LINK_SIGNING_SECRET se usa un secreto temporal; los enlaces enviados dejarán de funcionar al reiniciar")
+		secret, err := security.GenerateOpaqueToken()
+		if err != nil {
+			log.Fatalf("Error generando el secreto de enlaces: %v", err)
+		}
+		linkSecret = []byte(secret)
+	}
 
 	// Crear caso de uso de usuario
-	userUseCase := usecases.NewUserUseCase(userRepo)
-	authUseCase := usecases.NewAuthUseCase(userRepo, sessionRepo)
+	securityEventUseCase := usecases.NewSecurityEventUseCase(securityEventRepo)
+
+	// Claves de firma de tokens: se rotan cada JWT_KEY_ROTATION_INTERVAL ("0" desactiva la rotación programada)
+	rotationInterval, err := time.ParseDuration(configs.GetEnv("JWT_KEY_ROTATION_INTERVAL", "720h"))
+	if err != nil {
+		log.Fatalf("JWT_KEY_ROTATION_INTERVAL inválido: %v", err)
+	}
+	// Algoritmo de las claves nuevas: RS256, ES256 o EdDSA
+	signingAlg := configs.GetEnv("JWT_SIGNING_ALG", "RS256")
+	if !slices.Contains(security.SupportedAlgorithms(), signingAlg) {
+		log.Fatalf("JWT_SIGNING_ALG inválido: %s", signingAlg)
+	}
+	signingKeySecret := configs.GetEnv("SIGNING_KEY_SECRET", "")
+	if signingKeySecret == "" {
+		log.Println("Advertencia: sin SIGNING_KEY_SECRET las claves de firma se guardan sin cifrar")
+	}
+	signingKeyUseCase := usecases.NewSigningKeyUseCase(signingKeyRepo, securityEventUseCase, []byte(signingKeySecret), signingAlg, rotationInterval)
+	if err := signingKeyUseCase.Init(context.Background(), legacyKey); err != nil {
+		log.Fatalf("Error cargando las claves de firma: %v", err)
+	}
+	security.SetKeyReloader(func() error {
+		_, err := signingKeyUseCase.Reload(context.Background())
+		return err
+	})
+	signingKeyUseCase.StartRotation(context.Background(), time.Minute)
+
+	// Tokens de acceso revocados: se replican en memoria y se sincronizan entre instancias
+	tokenRevocationUseCase := usecases.NewTokenRevocationUseCase(revokedTokenRepo)
+	if err := tokenRevocationUseCase.Load(context.Background()); err != nil {
+		log.Fatalf("Error cargando los tokens revocados: %v", err)
+	}
+	tokenRevocationUseCase.StartSync(context.Background(), 15*time.Second)
+
+	emailVerificationUseCase := usecases.NewEmailVerificationUseCase(userRepo, mail, securityEventUseCase, linkSecret,
+		configs.GetEnv("EMAIL_VERIFY_URL", "http://localhost:8080/api/email/verify"))
+	userUseCase := usecases.NewUserUseCase(userRepo, emailVerificationUseCase)
+	sessionUseCase := usecases.NewSessionUseCase(sessionRepo, tokenRevocationUseCase, securityEventUseCase)
+	sessionUseCase.StartCleanup(context.Background(), time.Hour)
+	emailOTPUseCase := usecases.NewEmailOTPUseCase(userRepo, emailOTPRepo, mail, securityEventUseCase)
+	mfaUseCase := usecases.NewMFAUseCase(userRepo, mfaRepo, webauthnRepo, emailOTPUseCase, securityEventUseCase, configs.GetEnv("TOTP_ISSUER", "Auth UCP"))
+	webauthnUseCase := usecases.NewWebAuthnUseCase(userRepo, webauthnRepo, mfaUseCase, securityEventUseCase, webauthnConfig)
+	magicLinkUseCase := usecases.NewMagicLinkUseCase(userRepo, magicLinkRepo, mail, securityEventUseCase, linkSecret,
+		configs.GetEnv("MAGIC_LINK_URL", "http://localhost:8080/login/magic-link"))
+	loginAlertUseCase := usecases.NewLoginAlertUseCase(knownDeviceRepo, mail)
+
+	// Bloqueo por intentos fallidos, configurable por rol. Todas las cuentas
+	// siguen la más estricta, para que la espera no revele el rol de una cuenta.
+	rolePolicies := map[string]usecases.LockoutPolicy{
+		string(domain.RoleUser):   usecases.DefaultLockoutPolicy,
+		string(domain.RoleAdmin):  usecases.PrivilegedLockoutPolicy,
+		string(domain.RoleDoctor): usecases.PrivilegedLockoutPolicy,
+	}
+	for role, policy := range rolePolicies {
+		rolePolicies[role] = lockoutPolicyFromEnv(strings.ToUpper(role), policy)
+	}
+	loginLockoutUseCase := usecases.NewLoginLockoutUseCase(loginAttemptRepo, userRepo, securityEventUseCase,
+		usecases.DefaultLockoutPolicy, rolePolicies, lockoutPolicyFromEnv("IP", usecases.DefaultIPLockoutPolicy))
+	authUseCase := usecases.NewAuthUseCase(userRepo, sessionRepo, mfaUseCase, webauthnUseCase, magicLinkUseCase, emailOTPUseCase, loginAlertUseCase, loginLockoutUseCase, tokenRevocationUseCase, securityEventUseCase)
+	oauthUseCase := usecases.NewOAuthUseCase(oauthRepo, userRepo, sessionUseCase, tokenRevocationUseCase, securityEventUseCase,
+		configs.GetEnv("OIDC_ISSUER", "http://localhost:8080"))
+	passwordResetUseCase := usecases.NewPasswordResetUseCase(userRepo, passwordResetRepo, sessionRepo, tokenRevocationUseCase, mail, securityEventUseCase,
+		configs.GetEnv("PASSWORD_RESET_URL", "http://localhost:8080/reset-password"))
+
+	// Límites de peticiones: "memory" (por instancia) o "postgres" (compartidos entre instancias)
+	var rateLimits ratelimit.Store
+	switch backend := configs.GetEnv("RATE_LIMIT_STORE", "memory"); backend {
+	case "memory":
+		rateLimits = ratelimit.NewMemoryStore()
+	case "postgres":
+		store := ratelimit.NewPostgresStore(database)
+		store.StartCleanup(context.Background(), 10*time.Minute)
+		rateLimits = store
+	default:
+		log.Fatalf("RATE_LIMIT_STORE desconocido: %s", backend)
+	}
 
 	// Crear handlers
-	userHandler := handlers.NewUserHandler(userUseCase)
-	authHandler := handlers.NewAuthHandler(authUseCase)
+	routeHandlers := http.Handlers{
+		Auth:         handlers.NewAuthHandler(authUseCase),
+		User:         handlers.NewUserHandler(userUseCase),
+		Session:      handlers.NewSessionHandler(sessionUseCase),
+		AdminSession: handlers.NewAdminSessionHandler(sessionUseCase),
+		MFA:          handlers.NewMFAHandler(mfaUseCase),
+		WebAuthn:     handlers.NewWebAuthnHandler(webauthnUseCase, authUseCase),
+		Password:     handlers.NewPasswordHandler(passwordResetUseCase),
+		Verification: handlers.NewEmailVerificationHandler(emailVerificationUseCase),
+		SigningKey:   handlers.NewSigningKeyHandler(signingKeyUseCase),
+		LoginLockout: handlers.NewLoginLockoutHandler(loginLockoutUseCase),
+		MagicLink:    handlers.NewMagicLinkHandler(magicLinkUseCase, authUseCase, configs.GetEnv("COOKIE_SECURE", "true") == "true"),
+		OAuth:        handlers.NewOAuthHandler(oauthUseCase, configs.GetEnv("OAUTH_LOGIN_URL", "http://localhost:8080/oauth/authorize")),
+
+		TokenRevocation: tokenRevocationUseCase,
+		RateLimits:      rateLimits,
+	}
+
+	// Proxies de los que se acepta X-Forwarded-For. Sin ninguno, la IP del
+	// cliente es la de la conexión y no puede falsearse para saltar los límites.
+	var trustedProxies []string
+	for _, proxy := range strings.Split(configs.GetEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
 
 	// Crear servidor y configurar rutas
-	router := gin.Default()
-	http.SetupRoutes(router, authHandler, userHandler)
+	server, err := http.NewServer(routeHandlers, trustedProxies)
+	if err != nil {
+		log.Fatalf("Error al crear el servidor: %v", err)
+	}
 
 	// Ejecutar el servidor en el puerto 8080
-	server := http.NewServer(authHandler, userHandler)
 
 	port := configs.GetEnv("PORT", "8080") // Usa 8080 si no está en .env
 	server.Run(port)
 }
+
+// lockoutPolicyFromEnv permite ajustar una política de bloqueo con
+// LOCKOUT_<NOMBRE>_MAX_FAILURES, LOCKOUT_<NOMBRE>_DURATION y LOCKOUT_<NOMBRE>_MAX_DELAY
+func lockoutPolicyFromEnv(name string, policy usecases.LockoutPolicy) usecases.LockoutPolicy {
+	prefix := "LOCKOUT_" + name + "_"
+
+	maxFailures, err := strconv.Atoi(configs.GetEnv(prefix+"MAX_FAILURES", strconv.Itoa(policy.MaxFailures)))
+	if err != nil || maxFailures < 1 {
+		log.Fatalf("%sMAX_FAILURES inválido", prefix)
+	}
+	lockDuration, err := time.ParseDuration(configs.GetEnv(prefix+"DURATION", policy.LockDuration.String()))
+	if err != nil {
+		log.Fatalf("%sDURATION inválido: %v", prefix, err)
+	}
+	maxDelay, err := time.ParseDuration(configs.GetEnv(prefix+"MAX_DELAY", policy.MaxDelay.String()))
+	if err != nil {
+		log.Fatalf("%sMAX_DELAY inválido: %v", prefix, err)
+	}
+
+	policy.MaxFailures = maxFailures
+	policy.LockDuration = lockDuration
+	policy.MaxDelay = maxDelay
+	return policy
+}
+
+// argon2idParamsFromEnv permite ajustar el costo de los hashes de contraseña con
+// ARGON2_MEMORY_KIB, ARGON2_ITERATIONS y ARGON2_PARALLELISM
+func argon2idParamsFromEnv(params security.Argon2idParams) security.Argon2idParams {
+	memory, err := strconv.ParseUint(configs.GetEnv("ARGON2_MEMORY_KIB", strconv.FormatUint(uint64(params.Memory), 10)), 10, 32)
+	if err != nil || memory < 8*1024 {
+		log.Fatalf("ARGON2_MEMORY_KIB inválido: debe ser al menos 8192")
+	}
+	iterations, err := strconv.ParseUint(configs.GetEnv("ARGON2_ITERATIONS", strconv.FormatUint(uint64(params.Iterations), 10)), 10, 32)
+	if err != nil || iterations < 1 {
+		log.Fatalf("ARGON2_ITERATIONS inválido")
+	}
+	parallelism, err := strconv.ParseUint(configs.GetEnv("ARGON2_PARALLELISM", strconv.FormatUint(uint64(params.Parallelism), 10)), 10, 8)
+	if err != nil || parallelism < 1 {
+		log.Fatalf("ARGON2_PARALLELISM inválido")
+	}
+
+	params.Memory = uint32(memory)
+	params.Iterations = uint32(iterations)
+	params.Parallelism = uint8(parallelism)
+	return params
+}
+
+// newMailSender elige cómo se entregan los correos: "smtp", "file" (archivos
+// .eml en MAIL_DIR), "maildir" (buzón en MAIL_DIR), "memory" o "log". No hay
+// valor por omisión: los correos llevan enlaces y códigos de acceso, así que
+// el servicio no arranca hasta que se elija explícitamente dónde terminan.
+func newMailSender(driver string) (mailer.Sender, error) {
+	switch driver {
+	case "":
+		return nil, fmt.Errorf("MAIL_DRIVER no está configurado (smtp, file, maildir, memory o log)")
+	case "smtp":
+		return mailer.NewSMTPSender(mailer.SMTPConfig{
+			Host:     configs.GetEnv("SMTP_HOST", "localhost"),
+			Port:     configs.GetEnv("SMTP_PORT", "587"),
+			Username: configs.GetEnv("SMTP_USERNAME", ""),
+			Password: configs.GetEnv("SMTP_PASSWORD", ""),
+			From:     configs.GetEnv("MAIL_FROM", "no-reply@localhost"),
+		}), nil
+	case "file":
+		return mailer.NewFileSender(configs.GetEnv("MAIL_DIR", "mail"))
+	case "maildir":
+		return mailer.NewMaildirSender(configs.GetEnv("MAIL_DIR", "mail"))
+	case "memory":
+		return mailer.NewMemorySender(), nil
+	case "log":
+		return mailer.NewLogSender(), nil
+	}
+	return nil, fmt.Errorf("MAIL_DRIVER desconocido: %s", driver)
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"time"
+)
+
+// Propósitos de un código enviado por correo. Cada usuario tiene como máximo un
+// código vigente por propósito.
+const (
+	EmailOTPPurposeLogin = "login"
+	EmailOTPPurposeMFA   = "mfa"
+)
+
+// EmailOTP es un código numérico de un solo uso enviado por correo, válido
+// como inicio de sesión sin contraseña o como segundo factor de respaldo
+type EmailOTP struct {
+	UserID    string    `json:"user_id"`
+	Purpose   string    `json:"purpose"`
+	CodeHash  string    `json:"-"`
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"time"
+)
+
+// KnownDevice es un dispositivo desde el que el usuario ya inició sesión. Se
+// identifica por el hash de su user agent.
+type KnownDevice struct {
+	UserID      string    `json:"user_id"`
+	Fingerprint string    `json:"-"`
+	UserAgent   string    `json:"user_agent"`
+	LastIP      string    `json:"last_ip"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
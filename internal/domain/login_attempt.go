@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// LoginIdentifier es lo que identifica la cuenta al iniciar sesión con
+// contraseña: el correo, o el tipo y número del documento de identidad
+type LoginIdentifier struct {
+	Email          string
+	DocumentType   string
+	Identification string
+}
+
+// Key devuelve el identificador normalizado: el correo en minúsculas o el
+// documento como "CC:1020304050"
+func (id LoginIdentifier) Key() string {
+	if id.Email != "" {
+		return strings.ToLower(strings.TrimSpace(id.Email))
+	}
+	return strings.ToUpper(strings.TrimSpace(id.DocumentType)) + ":" + NormalizeIdentification(id.Identification)
+}
+
+// LoginAttempt es un intento de inicio de sesión con contraseña. UserID queda
+// vacío si el identificador no corresponde a ninguna cuenta.
+type LoginAttempt struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id,omitempty"`
+	Identifier string    `json:"identifier"`
+	ClientIP   string    `json:"client_ip"`
+	UserAgent  string    `json:"user_agent"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Contadores de fallos: por cuenta (el identificador normalizado) y por IP
+const (
+	LoginThrottleAccount = "account"
+	LoginThrottleIP      = "ip"
+)
+
+// LoginThrottle cuenta los fallos consecutivos de una cuenta o de una IP.
+// Tras cada fallo se exige esperar hasta NextAttemptAt, y al llegar al máximo
+// de fallos se bloquea hasta LockedUntil.
+type LoginThrottle struct {
+	Kind          string
+	Key           string
+	Failures      int
+	NextAttemptAt *time.Time
+	LockedUntil   *time.Time
+	UpdatedAt     time.Time
+}
+
+// Reserve cuenta un intento como fallido antes de verificar la contraseña, si
+// no hay que esperar. delays[i] es la espera exigida tras i+1 fallos y al
+// llegar a len(delays) fallos se bloquea durante lockDuration. El contador
+// vuelve a empezar si el último fallo es anterior a resetBefore o si el
+// bloqueo anterior ya terminó.
+func (t *LoginThrottle) Reserve(now, resetBefore time.Time, delays []time.Duration, lockDuration time.Duration) bool {
+	if t.RetryAfter(now) > 0 {
+		return false
+	}
+	if t.UpdatedAt.Before(resetBefore) || t.LockedUntil != nil {
+		t.Failures = 0
+	}
+
+	t.Failures++
+	t.LockedUntil = nil
+	next := now
+	if len(delays) > 0 {
+		next = now.Add(delays[min(t.Failures, len(delays))-1])
+		if t.Failures >= len(delays) {
+			until := now.Add(lockDuration)
+			t.LockedUntil = &until
+		}
+	}
+	t.NextAttemptAt = &next
+	t.UpdatedAt = now
+	return true
+}
+
+// RetryAfter devuelve cuánto falta para poder intentar de nuevo, o cero si ya se puede
+func (t *LoginThrottle) RetryAfter(now time.Time) time.Duration {
+	var until time.Time
+	if t.LockedUntil != nil && t.LockedUntil.After(until) {
+		until = *t.LockedUntil
+	}
+	if t.NextAttemptAt != nil && t.NextAttemptAt.After(until) {
+		until = *t.NextAttemptAt
+	}
+	if !until.After(now) {
+		return 0
+	}
+	return until.Sub(now)
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginThrottleReserve(t *testing.T) {
+	delays := []time.Duration{0, time.Second, 4 * time.Second}
+	lockDuration := 15 * time.Minute
+	now := time.Unix(1700000000, 0)
+	resetBefore := now.Add(-time.Hour)
+
+	throttle := &LoginThrottle{Kind: LoginThrottleAccount, Key: "usuario-1"}
+
+	// Primer fallo: sin espera
+	if !throttle.Reserve(now, resetBefore, delays, lockDuration) {
+		t.Fatal("primer intento rechazado")
+	}
+	if throttle.Failures != 1 || throttle.RetryAfter(now) != 0 {
+		t.Fatalf("fallos %d, espera %v", throttle.Failures, throttle.RetryAfter(now))
+	}
+
+	// Segundo fallo: exige esperar un segundo
+	if !throttle.Reserve(now, resetBefore, delays, lockDuration) {
+		t.Fatal("segundo intento rechazado")
+	}
+	if got := throttle.RetryAfter(now); got != time.Second {
+		t.Fatalf("se esperaba 1s de espera, se obtuvo %v", got)
+	}
+	if throttle.Reserve(now, resetBefore, delays, lockDuration) {
+		t.Fatal("intento aceptado antes de terminar la espera")
+	}
+	if throttle.Failures != 2 {
+		t.Fatalf("un intento rechazado contó como fallo: %d", throttle.Failures)
+	}
+
+	// Tercer fallo: llega al máximo y bloquea
+	now = now.Add(time.Second)
+	if !throttle.Reserve(now, resetBefore, delays, lockDuration) {
+		t.Fatal("tercer intento rechazado tras la espera")
+	}
+	if throttle.LockedUntil == nil || !throttle.LockedUntil.Equal(now.Add(lockDuration)) {
+		t.Fatalf("se esperaba un bloqueo hasta %v, se obtuvo %v", now.Add(lockDuration), throttle.LockedUntil)
+	}
+	if got := throttle.RetryAfter(now); got != lockDuration {
+		t.Fatalf("se esperaba %v de espera, se obtuvo %v", lockDuration, got)
+	}
+
+	// Al terminar el bloqueo el contador vuelve a empezar
+	now = now.Add(lockDuration)
+	if !throttle.Reserve(now, now.Add(-time.Hour), delays, lockDuration) {
+		t.Fatal("intento rechazado tras terminar el bloqueo")
+	}
+	if throttle.Failures != 1 || throttle.LockedUntil != nil {
+		t.Fatalf("se esperaba el contador reiniciado, fallos %d, bloqueo %v", throttle.Failures, throttle.LockedUntil)
+	}
+}
+
+func TestLoginThrottleReserveResetsStaleFailures(t *testing.T) {
+	delays := []time.Duration{0, 0, 0, time.Second}
+	now := time.Unix(1700000000, 0)
+
+	throttle := &LoginThrottle{Failures: 3, UpdatedAt: now.Add(-2 * time.Hour)}
+	if !throttle.Reserve(now, now.Add(-time.Hour), delays, time.Minute) {
+		t.Fatal("intento rechazado")
+	}
+	if throttle.Failures != 1 {
+		t.Fatalf("se esperaba el contador reiniciado, fallos %d", throttle.Failures)
+	}
+
+	throttle = &LoginThrottle{Failures: 3, UpdatedAt: now.Add(-30 * time.Minute)}
+	if !throttle.Reserve(now, now.Add(-time.Hour), delays, time.Minute) {
+		t.Fatal("intento rechazado")
+	}
+	if throttle.Failures != 4 || throttle.LockedUntil == nil {
+		t.Fatalf("se esperaba el bloqueo al cuarto fallo, fallos %d, bloqueo %v", throttle.Failures, throttle.LockedUntil)
+	}
+}
+
+func TestLoginThrottleReserveWithoutDelays(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	throttle := &LoginThrottle{}
+	for i := 0; i < 3; i++ {
+		if !throttle.Reserve(now, now.Add(-time.Hour), nil, time.Minute) {
+			t.Fatalf("intento %d rechazado sin política de espera", i+1)
+		}
+	}
+	if throttle.LockedUntil != nil {
+		t.Fatal("bloqueo sin política de espera")
+	}
+}
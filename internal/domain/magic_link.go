@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"time"
+)
+
+// MagicLink es un enlace de inicio de sesión sin contraseña enviado por correo.
+// Solo funciona en el navegador que lo pidió, que guarda el nonce en una cookie.
+type MagicLink struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	NonceHash string     `json:"-"`
+	UserAgent string     `json:"user_agent"`
+	ClientIP  string     `json:"client_ip"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	MFAMethodTOTP         = "totp"
+	MFAMethodRecoveryCode = "recovery_code"
+	MFAMethodWebAuthn     = "webauthn"
+	MFAMethodEmailOTP     = "email_otp"
+)
+
+// Métodos con los que se valida el primer factor de un inicio de sesión
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodEmailOTP  = "email_otp"
+	LoginMethodMagicLink = "magic_link"
+)
+
+// TOTPFactor es el secreto TOTP de un usuario. Solo cuenta como segundo factor
+// una vez confirmado con un primer código válido.
+type TOTPFactor struct {
+	UserID       string     `json:"user_id"`
+	Secret       string     `json:"-"`
+	LastUsedStep int64      `json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// MFAChallenge es el reto pendiente entre la validación del primer factor y la
+// del segundo. FirstFactor indica con qué método se abrió.
+type MFAChallenge struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	TokenHash   string    `json:"-"`
+	Attempts    int       `json:"attempts"`
+	FirstFactor string    `json:"first_factor"`
+	UserAgent   string    `json:"user_agent"`
+	ClientIP    string    `json:"client_ip"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RecoveryCode es un código de recuperación de un solo uso que reemplaza al
+// segundo factor cuando el usuario pierde su dispositivo
+type RecoveryCode struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	CodeHash  string     `json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	// CodePrefix son los primeros caracteres del código, en claro. Vacío en
+	// los códigos generados antes de guardarlo.
+	CodePrefix string `json:"-"`
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"time"
+)
+
+// Tipos de cliente OAuth 2.0 (RFC 6749, sección 2.1). Solo los confidenciales
+// pueden guardar un secreto.
+const (
+	OAuthClientConfidential = "confidential"
+	OAuthClientPublic       = "public"
+)
+
+// Scopes estándar de OpenID Connect, disponibles para todos los clientes
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// OIDCScopes lista los scopes estándar de OpenID Connect
+var OIDCScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+// OAuthClient es una aplicación registrada que delega en este servicio el
+// inicio de sesión de sus usuarios, o un servicio que se autentica con sus
+// propias credenciales (client_credentials)
+type OAuthClient struct {
+	ClientID     string    `json:"client_id"`
+	SecretHash   string    `json:"-"`
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	GrantTypes   []string  `json:"grant_types"`
+	CreatedBy    string    `json:"created_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Confidential indica si el cliente debe autenticarse con su secreto
+func (c *OAuthClient) Confidential() bool {
+	return c.Type == OAuthClientConfidential
+}
+
+// AllowsGrant indica si el cliente puede usar el grant_type
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	for _, allowed := range c.GrantTypes {
+		if allowed == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsRedirectURI indica si la URI coincide exactamente con una registrada
+func (c *OAuthClient) AllowsRedirectURI(uri string) bool {
+	for _, registered := range c.RedirectURIs {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthAuthorizationCode es un código de autorización de un solo uso, ligado al
+// cliente, a la URI de redirección y al reto PKCE de la petición que lo originó
+type OAuthAuthorizationCode struct {
+	CodeHash            string    `json:"-"`
+	ClientID            string    `json:"client_id"`
+	UserID              string    `json:"user_id"`
+	RedirectURI         string    `json:"redirect_uri"`
+	Scopes              []string  `json:"scopes"`
+	CodeChallenge       string    `json:"-"`
+	CodeChallengeMethod string    `json:"code_challenge_method"`
+	Nonce               string    `json:"-"`
+	ExpiresAt           time.Time `json:"expires_at"`
+	CreatedAt           time.Time `json:"created_at"`
+}
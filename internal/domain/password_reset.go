@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"time"
+)
+
+// PasswordResetToken es un enlace de un solo uso para restablecer la contraseña.
+// Solo se guarda el hash del token enviado por correo.
+type PasswordResetToken struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
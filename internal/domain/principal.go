@@ -0,0 +1,43 @@
+package domain
+
+// Tipos de principal: un usuario que inició sesión o un servicio autenticado
+// con sus credenciales de cliente
+const (
+	PrincipalUser    = "user"
+	PrincipalService = "service"
+)
+
+// Principal es quien hace una petición autenticada. Los usuarios tienen
+// UserID y Role; los servicios solo ClientID.
+type Principal struct {
+	Type      string
+	UserID    string
+	Role      string
+	SessionID string
+	ClientID  string
+	Scopes    []string
+}
+
+// IsUser indica si el principal es un usuario
+func (p *Principal) IsUser() bool {
+	return p.Type == PrincipalUser
+}
+
+// IsService indica si el principal es un servicio
+func (p *Principal) IsService() bool {
+	return p.Type == PrincipalService
+}
+
+// HasScope indica si al principal se le concedió el scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeMFAEnrollment marca los tokens que se entregan a los usuarios cuyo rol
+// exige segundo factor y aún no lo configuraron. Solo sirven para registrarlo.
+const ScopeMFAEnrollment = "mfa_enrollment"
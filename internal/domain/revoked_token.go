@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"time"
+)
+
+// RevokedToken es un token de acceso revocado antes de expirar. Se guarda por
+// su jti hasta ExpiresAt; a partir de ahí el token ya no es válido de todas formas.
+type RevokedToken struct {
+	JTI       string
+	UserID    string
+	ClientID  string // Vacío en los tokens de la propia API
+	ExpiresAt time.Time
+	RevokedAt time.Time
+}
+
+// Alcance de una revocación de sesiones
+const (
+	SessionRevocationFamily = "session" // Una sesión (familia de refresh tokens), por el claim sid
+	SessionRevocationUser   = "user"    // Todas las sesiones de un usuario, por el claim user_id
+)
+
+// SessionRevocation invalida los tokens de acceso de una sesión o de un usuario
+// emitidos hasta RevokedAt. Se guarda hasta ExpiresAt, cuando el último de
+// esos tokens ya expiró; los emitidos después, como los de una sesión
+// desbloqueada, siguen siendo válidos.
+type SessionRevocation struct {
+	Kind      string
+	Subject   string // family_id o user_id, según Kind
+	RevokedAt time.Time
+	ExpiresAt time.Time
+}
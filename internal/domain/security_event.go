@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"time"
+)
+
+type SecurityEventType string
+
+const (
+	EventRefreshTokenReuse   SecurityEventType = "refresh_token_reuse"
+	EventAdminSessionSearch  SecurityEventType = "admin_session_search"
+	EventSessionBlocked      SecurityEventType = "session_blocked"
+	EventSessionUnblocked    SecurityEventType = "session_unblocked"
+	EventUserSessionsRevoked SecurityEventType = "user_sessions_revoked"
+	EventMFAEnabled          SecurityEventType = "mfa_enabled"
+	EventMFADisabled         SecurityEventType = "mfa_disabled"
+	EventMFAFailed           SecurityEventType = "mfa_failed"
+	EventRecoveryCodeUsed    SecurityEventType = "recovery_code_used"
+	EventRecoveryCodesIssued SecurityEventType = "recovery_codes_issued"
+	EventWebAuthnRegistered  SecurityEventType = "webauthn_registered"
+	EventWebAuthnRemoved     SecurityEventType = "webauthn_removed"
+	EventWebAuthnCloned      SecurityEventType = "webauthn_cloned_authenticator"
+	EventPasswordResetSent   SecurityEventType = "password_reset_sent"
+	EventPasswordReset       SecurityEventType = "password_reset"
+	EventEmailVerified       SecurityEventType = "email_verified"
+	EventMagicLinkUsed       SecurityEventType = "magic_link_used"
+	EventEmailOTPLogin       SecurityEventType = "email_otp_login"
+	EventOAuthClientCreated  SecurityEventType = "oauth_client_created"
+	EventOAuthClientDeleted  SecurityEventType = "oauth_client_deleted"
+	EventOAuthAuthorized     SecurityEventType = "oauth_authorized"
+	EventSigningKeyRotated   SecurityEventType = "signing_key_rotated"
+	EventAccountLocked       SecurityEventType = "account_locked"
+	EventAccountUnlocked     SecurityEventType = "account_unlocked"
+	EventIPLocked            SecurityEventType = "ip_locked"
+)
+
+// SecurityEvent registra una acción relevante para la auditoría de seguridad.
+// UserID es el usuario afectado y ActorID quien ejecutó la acción, si es otro.
+type SecurityEvent struct {
+	ID        string            `json:"id"`
+	UserID    string            `json:"user_id,omitempty"`
+	ActorID   string            `json:"actor_id,omitempty"`
+	Type      SecurityEventType `json:"type"`
+	ClientIP  string            `json:"client_ip"`
+	UserAgent string            `json:"user_agent"`
+	Details   string            `json:"details"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -4,14 +4,31 @@ import (
 	"time"
 )
 
+// Session representa un refresh token emitido a un dispositivo. Cada rotación
+// crea una nueva fila que comparte el FamilyID de la sesión original.
 type Session struct {
-	ID           string    `json:"id"`
-	UserID       string    `json:"user_id"`
-	RefreshToken string    `json:"refresh_token"`
-	UserAgent    string    `json:"user_agent"`
-	ClientIP     string    `json:"client_ip"`
-	IsBlocked    bool      `json:"is_blocked"`
-	ExpiresAt    time.Time `json:"expires_at"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-}
\ No newline at end of file
+	ID            string     `json:"id"`
+	FamilyID      string     `json:"family_id"`
+	UserID        string     `json:"user_id"`
+	RefreshToken  string     `json:"-"`
+	UserAgent     string     `json:"user_agent"`
+	ClientIP      string     `json:"client_ip"`
+	IsBlocked     bool       `json:"is_blocked"`
+	BlockedReason string     `json:"blocked_reason,omitempty"`
+	BlockedBy     string     `json:"blocked_by,omitempty"`
+	BlockedAt     *time.Time `json:"blocked_at,omitempty"`
+	RotatedAt     *time.Time `json:"rotated_at,omitempty"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// SessionFilter define los criterios de búsqueda de sesiones para administradores
+type SessionFilter struct {
+	FamilyID  string
+	UserID    string
+	ClientIP  string
+	UserAgent string
+	Limit     int
+	Offset    int
+}
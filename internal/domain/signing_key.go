@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+)
+
+// SigningKey es una clave de firma de tokens. Al retirarse deja de firmar, pero
+// sigue publicada en el JWKS hasta PublishedUntil, cuando ya expiraron todos
+// los tokens que firmó.
+type SigningKey struct {
+	ID             string     `json:"kid"`
+	Algorithm      string     `json:"alg"`
+	PrivateKey     string     `json:"-"`
+	CreatedAt      time.Time  `json:"created_at"`
+	RetiredAt      *time.Time `json:"retired_at,omitempty"`
+	PublishedUntil *time.Time `json:"published_until,omitempty"`
+}
+
+// Active indica si la clave firma los tokens nuevos
+func (k *SigningKey) Active() bool {
+	return k.RetiredAt == nil
+}
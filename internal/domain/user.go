@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,24 +10,61 @@ import (
 type UserRole string
 
 const (
-	RoleUser  UserRole = "usuario"
-	RoleAdmin UserRole = "admin"
+	RoleUser   UserRole = "usuario"
+	RoleAdmin  UserRole = "admin"
 	RoleDoctor UserRole = "doctor"
 )
 
+// Tipos de documento de identidad
+const (
+	DocumentTypeCC  = "CC"  // Cédula de ciudadanía
+	DocumentTypeTI  = "TI"  // Tarjeta de identidad, para menores de edad
+	DocumentTypeCE  = "CE"  // Cédula de extranjería
+	DocumentTypePEP = "PEP" // Permiso especial de permanencia
+	DocumentTypePA  = "PA"  // Pasaporte
+	DocumentTypePPT = "PPT" // Permiso por protección temporal
+)
 
 type User struct {
-	ID        			uuid.UUID `json:"id"`         		
-	Identification	string		`json:"identification"`	
-	Name      			string    `json:"name"`       		
-	Lastname  			string    `json:"lastname"`   		
-	Email     			string    `json:"email"`      		
-	Password  			string    `json:"password,omitempty"`   
-	Role         	 	string  	`json:"role"`        			
-	CreatedAt 			time.Time `json:"created_at"` 		
-	UpdatedAt 			time.Time `json:"updated_at"` 		
-	LastLoginAt 		time.Time `json:"lastlogin_at"`		
-	Active					bool			`json:"active"`		
+	ID             uuid.UUID `json:"id"`
+	DocumentType   string    `json:"document_type"`
+	Identification string    `json:"identification"`
+	Name           string    `json:"name"`
+	Lastname       string    `json:"lastname"`
+	Email          string    `json:"email"`
+	Password       string    `json:"password,omitempty"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	LastLoginAt    time.Time `json:"lastlogin_at"`
+	Active         bool      `json:"active"`
+	MFAEnabled     bool      `json:"mfa_enabled"`
+	// EmailVerifiedAt es nil hasta que el usuario confirma su correo
+	EmailVerifiedAt    *time.Time `json:"email_verified_at,omitempty"`
+	VerificationSentAt *time.Time `json:"-"`
+}
+
+// EmailVerified indica si el usuario confirmó que el correo le pertenece
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
+// LoginIdentifiers devuelve los identificadores con los que el usuario puede iniciar sesión
+func (u *User) LoginIdentifiers() []LoginIdentifier {
+	return []LoginIdentifier{
+		{Email: u.Email},
+		{DocumentType: u.DocumentType, Identification: u.Identification},
+	}
+}
+
+// NormalizeIdentification quita los espacios y pasa a mayúsculas el número de
+// documento, que en los pasaportes puede llevar letras
+func NormalizeIdentification(identification string) string {
+	return strings.ToUpper(strings.TrimSpace(identification))
+}
 
+// RequiresMFA indica si la política de seguridad exige segundo factor para el rol,
+// ya que administradores y doctores acceden a datos de salud sensibles
+func (u *User) RequiresMFA() bool {
+	return u.Role == string(RoleAdmin) || u.Role == string(RoleDoctor)
+}
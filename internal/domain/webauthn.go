@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"time"
+)
+
+// Ceremonias WebAuthn para las que se emite un reto
+const (
+	WebAuthnCeremonyRegistration = "registration"
+	WebAuthnCeremonyLogin        = "login"
+	WebAuthnCeremonyMFA          = "mfa"
+)
+
+// WebAuthnCredential es una llave de acceso o llave de seguridad registrada por el usuario
+type WebAuthnCredential struct {
+	ID           string     `json:"id"`
+	UserID       string     `json:"user_id"`
+	CredentialID string     `json:"credential_id"` // base64url
+	PublicKey    []byte     `json:"-"`             // Clave pública COSE
+	SignCount    uint32     `json:"-"`
+	Transports   []string   `json:"transports"`
+	AAGUID       string     `json:"aaguid"`
+	Nickname     string     `json:"nickname"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+}
+
+// WebAuthnChallenge es el reto emitido al iniciar una ceremonia WebAuthn.
+// UserID está vacío en el inicio de sesión sin contraseña, donde el usuario se
+// conoce recién por la credencial presentada.
+type WebAuthnChallenge struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id,omitempty"`
+	Ceremony  string    `json:"ceremony"`
+	Challenge string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
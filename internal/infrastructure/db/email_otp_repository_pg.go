@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+type emailOTPRepositoryPg struct {
+	db *sql.DB
+}
+
+// NewEmailOTPRepositoryPg crea una nueva instancia del repositorio de códigos enviados por correo
+func NewEmailOTPRepositoryPg(db *sql.DB) *emailOTPRepositoryPg {
+	return &emailOTPRepositoryPg{db: db}
+}
+
+// SaveOTP guarda el código reemplazando al anterior del mismo propósito, salvo
+// que este se haya enviado después de notAfter
+func (r *emailOTPRepositoryPg) SaveOTP(ctx context.Context, otp *domain.EmailOTP, notAfter time.Time) (bool, error) {
+	query := `
+		INSERT INTO email_otps (user_id, purpose, code_hash, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, purpose) DO UPDATE
+		SET code_hash = EXCLUDED.code_hash, attempts = EXCLUDED.attempts,
+		    expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at
+		WHERE email_otps.created_at < $7
+	`
+	res, err := r.db.ExecContext(ctx, query,
+		otp.UserID, otp.Purpose, otp.CodeHash, otp.Attempts, otp.ExpiresAt, otp.CreatedAt, notAfter,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error al guardar el código de verificación: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// GetOTP obtiene el código vigente del usuario para el propósito
+func (r *emailOTPRepositoryPg) GetOTP(ctx context.Context, userID, purpose string) (*domain.EmailOTP, error) {
+	query := `
+		SELECT user_id, purpose, code_hash, attempts, expires_at, created_at
+		FROM email_otps WHERE user_id = $1 AND purpose = $2
+	`
+	otp := &domain.EmailOTP{}
+	err := r.db.QueryRowContext(ctx, query, userID, purpose).Scan(
+		&otp.UserID, &otp.Purpose, &otp.CodeHash, &otp.Attempts, &otp.ExpiresAt, &otp.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecases.ErrInvalidEmailOTP
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el código de verificación: %w", err)
+	}
+	return otp, nil
+}
+
+// ReserveOTPAttempt suma un intento al código en una sola sentencia, de modo
+// que las peticiones simultáneas no pueden superar maxAttempts
+func (r *emailOTPRepositoryPg) ReserveOTPAttempt(ctx context.Context, userID, purpose string, maxAttempts int) (bool, error) {
+	query := `UPDATE email_otps SET attempts = attempts + 1 WHERE user_id = $1 AND purpose = $2 AND attempts < $3`
+	res, err := r.db.ExecContext(ctx, query, userID, purpose, maxAttempts)
+	if err != nil {
+		return false, fmt.Errorf("error al actualizar el código de verificación: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// DeleteOTP consume el código. Devuelve ErrInvalidEmailOTP si ya fue usado.
+func (r *emailOTPRepositoryPg) DeleteOTP(ctx context.Context, userID, purpose string) error {
+	query := `DELETE FROM email_otps WHERE user_id = $1 AND purpose = $2`
+	res, err := r.db.ExecContext(ctx, query, userID, purpose)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el código de verificación: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrInvalidEmailOTP
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type knownDeviceRepositoryPg struct {
+	db *sql.DB
+}
+
+// NewKnownDeviceRepositoryPg crea una nueva instancia del repositorio de dispositivos conocidos
+func NewKnownDeviceRepositoryPg(db *sql.DB) *knownDeviceRepositoryPg {
+	return &knownDeviceRepositoryPg{db: db}
+}
+
+// TouchDevice inserta el dispositivo o actualiza su último uso. Devuelve true si
+// la fila se insertó, es decir, si el dispositivo era nuevo.
+func (r *knownDeviceRepositoryPg) TouchDevice(ctx context.Context, device *domain.KnownDevice) (bool, error) {
+	query := `
+		INSERT INTO known_devices (user_id, fingerprint, user_agent, last_ip, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, fingerprint) DO UPDATE
+		SET last_ip = EXCLUDED.last_ip, last_seen_at = EXCLUDED.last_seen_at
+		RETURNING (xmax = 0)
+	`
+	var inserted bool
+	err := r.db.QueryRowContext(ctx, query,
+		device.UserID, device.Fingerprint, device.UserAgent, device.LastIP, device.FirstSeenAt, device.LastSeenAt,
+	).Scan(&inserted)
+	if err != nil {
+		return false, fmt.Errorf("error al registrar el dispositivo: %w", err)
+	}
+	return inserted, nil
+}
+
+// CountDevices cuenta los dispositivos conocidos del usuario
+func (r *knownDeviceRepositoryPg) CountDevices(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM known_devices WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error al contar los dispositivos: %w", err)
+	}
+	return count, nil
+}
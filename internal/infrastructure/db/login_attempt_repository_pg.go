@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type loginAttemptRepositoryPg struct {
+	db *sql.DB
+}
+
+// NewLoginAttemptRepositoryPg crea una nueva instancia del repositorio de intentos de inicio de sesión
+func NewLoginAttemptRepositoryPg(db *sql.DB) *loginAttemptRepositoryPg {
+	return &loginAttemptRepositoryPg{db: db}
+}
+
+// RecordLoginAttempt guarda un intento de inicio de sesión, exitoso o fallido
+func (r *loginAttemptRepositoryPg) RecordLoginAttempt(ctx context.Context, attempt *domain.LoginAttempt) error {
+	query := `
+		INSERT INTO login_attempts (id, user_id, identifier, client_ip, user_agent, success, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		attempt.ID, nullString(attempt.UserID), attempt.Identifier, attempt.ClientIP, attempt.UserAgent, attempt.Success, attempt.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error al registrar el intento de inicio de sesión: %w", err)
+	}
+	return nil
+}
+
+// ReserveLoginAttempt cuenta un intento antes de verificar la contraseña. El
+// contador se bloquea durante la transacción, de modo que dos peticiones
+// simultáneas no pasan ambas la misma espera.
+func (r *loginAttemptRepositoryPg) ReserveLoginAttempt(ctx context.Context, kind, key string, now, resetBefore time.Time, delays []time.Duration, lockDuration time.Duration) (throttle *domain.LoginThrottle, reserved bool, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("error al iniciar la reserva del intento: %w", err)
+	}
+	defer func() {
+		if err != nil || !reserved {
+			_ = tx.Rollback()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO login_throttles (kind, throttle_key, failures, updated_at)
+		VALUES ($1, $2, 0, $3)
+		ON CONFLICT (kind, throttle_key) DO NOTHING
+	`, kind, key, now)
+	if err != nil {
+		return nil, false, fmt.Errorf("error al crear el contador de intentos fallidos: %w", err)
+	}
+
+	throttle = &domain.LoginThrottle{Kind: kind, Key: key}
+	var nextAttemptAt, lockedUntil sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT failures, next_attempt_at, locked_until, updated_at
+		FROM login_throttles WHERE kind = $1 AND throttle_key = $2 FOR UPDATE
+	`, kind, key).Scan(&throttle.Failures, &nextAttemptAt, &lockedUntil, &throttle.UpdatedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("error al obtener los intentos fallidos: %w", err)
+	}
+	if nextAttemptAt.Valid {
+		throttle.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if lockedUntil.Valid {
+		throttle.LockedUntil = &lockedUntil.Time
+	}
+
+	if !throttle.Reserve(now, resetBefore, delays, lockDuration) {
+		return throttle, false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE login_throttles SET failures = $1, next_attempt_at = $2, locked_until = $3, updated_at = $4
+		WHERE kind = $5 AND throttle_key = $6
+	`, throttle.Failures, throttle.NextAttemptAt, throttle.LockedUntil, throttle.UpdatedAt, kind, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("error al registrar el intento fallido: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("error al registrar el intento fallido: %w", err)
+	}
+	return throttle, true, nil
+}
+
+// ReleaseLoginAttempt descuenta un intento reservado. La espera que fijó la
+// reserva se mantiene hasta que venza.
+func (r *loginAttemptRepositoryPg) ReleaseLoginAttempt(ctx context.Context, kind, key string) error {
+	query := `UPDATE login_throttles SET failures = GREATEST(failures - 1, 0) WHERE kind = $1 AND throttle_key = $2`
+	if _, err := r.db.ExecContext(ctx, query, kind, key); err != nil {
+		return fmt.Errorf("error al descontar el intento reservado: %w", err)
+	}
+	return nil
+}
+
+// DeleteLoginThrottle reinicia el contador de fallos, lo que también desbloquea
+func (r *loginAttemptRepositoryPg) DeleteLoginThrottle(ctx context.Context, kind, key string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM login_throttles WHERE kind = $1 AND throttle_key = $2`, kind, key); err != nil {
+		return fmt.Errorf("error al reiniciar los intentos fallidos: %w", err)
+	}
+	return nil
+}
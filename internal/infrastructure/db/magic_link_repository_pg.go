@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+type magicLinkRepositoryPg struct {
+	db *sql.DB
+}
+
+// NewMagicLinkRepositoryPg crea una nueva instancia del repositorio de enlaces de inicio de sesión
+func NewMagicLinkRepositoryPg(db *sql.DB) *magicLinkRepositoryPg {
+	return &magicLinkRepositoryPg{db: db}
+}
+
+// CreateMagicLink guarda un enlace de inicio de sesión pendiente
+func (r *magicLinkRepositoryPg) CreateMagicLink(ctx context.Context, link *domain.MagicLink) error {
+	query := `
+		INSERT INTO magic_links (id, user_id, nonce_hash, user_agent, client_ip, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		link.ID, link.UserID, link.NonceHash, link.UserAgent, link.ClientIP, link.ExpiresAt, link.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error al guardar el enlace de inicio de sesión: %w", err)
+	}
+	return nil
+}
+
+// GetMagicLink obtiene un enlace de inicio de sesión por su ID
+func (r *magicLinkRepositoryPg) GetMagicLink(ctx context.Context, id string) (*domain.MagicLink, error) {
+	query := `
+		SELECT id, user_id, nonce_hash, user_agent, client_ip, expires_at, used_at, created_at
+		FROM magic_links WHERE id = $1
+	`
+	link := &domain.MagicLink{}
+	var usedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&link.ID, &link.UserID, &link.NonceHash, &link.UserAgent, &link.ClientIP, &link.ExpiresAt, &usedAt, &link.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecases.ErrInvalidMagicLink
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el enlace de inicio de sesión: %w", err)
+	}
+	if usedAt.Valid {
+		link.UsedAt = &usedAt.Time
+	}
+	return link, nil
+}
+
+// MarkMagicLinkUsed consume el enlace. Falla si ya fue usado.
+func (r *magicLinkRepositoryPg) MarkMagicLinkUsed(ctx context.Context, id string) error {
+	query := `UPDATE magic_links SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("error al usar el enlace de inicio de sesión: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrInvalidMagicLink
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+type mfaRepositoryPg struct {
+	db *sql.DB
+}
+
+// NewMFARepositoryPg crea una nueva instancia del repositorio de segundo factor
+func NewMFARepositoryPg(db *sql.DB) *mfaRepositoryPg {
+	return &mfaRepositoryPg{db: db}
+}
+
+// SaveTOTPFactor guarda un secreto TOTP pendiente de confirmación, reemplazando
+// cualquier enrolamiento previo del usuario
+func (r *mfaRepositoryPg) SaveTOTPFactor(ctx context.Context, factor *domain.TOTPFactor) error {
+	query := `
+		INSERT INTO mfa_totp (user_id, secret, last_used_step, confirmed_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = EXCLUDED.last_used_step,
+		    confirmed_at = EXCLUDED.confirmed_at, created_at = EXCLUDED.created_at
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		factor.UserID, factor.Secret, factor.LastUsedStep, factor.ConfirmedAt, factor.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error al guardar el factor TOTP: %w", err)
+	}
+	return nil
+}
+
+// GetTOTPFactor obtiene el factor TOTP de un usuario
+func (r *mfaRepositoryPg) GetTOTPFactor(ctx context.Context, userID string) (*domain.TOTPFactor, error) {
+	query := `SELECT user_id, secret, last_used_step, confirmed_at, created_at FROM mfa_totp WHERE user_id = $1`
+
+	factor := &domain.TOTPFactor{}
+	var confirmedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&factor.UserID, &factor.Secret, &factor.LastUsedStep, &confirmedAt, &factor.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecases.ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el factor TOTP: %w", err)
+	}
+	if confirmedAt.Valid {
+		factor.ConfirmedAt = &confirmedAt.Time
+	}
+	return factor, nil
+}
+
+// ConfirmTOTPFactor marca el factor como confirmado con el paso del primer código
+func (r *mfaRepositoryPg) ConfirmTOTPFactor(ctx context.Context, userID string, step int64) error {
+	query := `UPDATE mfa_totp SET confirmed_at = $1, last_used_step = $2 WHERE user_id = $3 AND confirmed_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, time.Now(), step, userID)
+	if err != nil {
+		return fmt.Errorf("error al confirmar el factor TOTP: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrMFANotEnrolled
+	}
+	return nil
+}
+
+// UpdateTOTPLastStep registra el último paso usado. Falla si el paso no es
+// posterior al anterior, lo que impide reutilizar un mismo código.
+func (r *mfaRepositoryPg) UpdateTOTPLastStep(ctx context.Context, userID string, step int64) error {
+	query := `UPDATE mfa_totp SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`
+	res, err := r.db.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el factor TOTP: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrInvalidMFACode
+	}
+	return nil
+}
+
+// DeleteTOTPFactor elimina el factor TOTP de un usuario
+func (r *mfaRepositoryPg) DeleteTOTPFactor(ctx context.Context, userID string) error {
+	query := `DELETE FROM mfa_totp WHERE user_id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el factor TOTP: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes reemplaza todos los códigos de recuperación del usuario
+func (r *mfaRepositoryPg) ReplaceRecoveryCodes(ctx context.Context, userID string, codes []*domain.RecoveryCode) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar el reemplazo de códigos de recuperación: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("error al eliminar los códigos de recuperación: %w", err)
+	}
+
+	query := `INSERT INTO mfa_recovery_codes (id, user_id, code_hash, code_prefix, created_at) VALUES ($1, $2, $3, $4, $5)`
+	for _, code := range codes {
+		if _, err := tx.ExecContext(ctx, query, code.ID, code.UserID, code.CodeHash, code.CodePrefix, code.CreatedAt); err != nil {
+			return fmt.Errorf("error al guardar el código de recuperación: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar los códigos de recuperación: %w", err)
+	}
+	return nil
+}
+
+// ListUnusedRecoveryCodes lista los códigos de recuperación aún disponibles
+func (r *mfaRepositoryPg) ListUnusedRecoveryCodes(ctx context.Context, userID string) ([]*domain.RecoveryCode, error) {
+	query := `SELECT id, user_id, code_hash, code_prefix, created_at FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar los códigos de recuperación: %w", err)
+	}
+	defer rows.Close()
+
+	codes := []*domain.RecoveryCode{}
+	for rows.Next() {
+		code := &domain.RecoveryCode{}
+		if err := rows.Scan(&code.ID, &code.UserID, &code.CodeHash, &code.CodePrefix, &code.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error al leer el código de recuperación: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al listar los códigos de recuperación: %w", err)
+	}
+	return codes, nil
+}
+
+// MarkRecoveryCodeUsed consume un código de recuperación. Falla si ya fue usado.
+func (r *mfaRepositoryPg) MarkRecoveryCodeUsed(ctx context.Context, id string) error {
+	query := `UPDATE mfa_recovery_codes SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("error al usar el código de recuperación: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrInvalidMFACode
+	}
+	return nil
+}
+
+// DeleteRecoveryCodes elimina todos los códigos de recuperación del usuario
+func (r *mfaRepositoryPg) DeleteRecoveryCodes(ctx context.Context, userID string) error {
+	query := `DELETE FROM mfa_recovery_codes WHERE user_id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar los códigos de recuperación: %w", err)
+	}
+	return nil
+}
+
+// CreateChallenge guarda un reto MFA pendiente
+func (r *mfaRepositoryPg) CreateChallenge(ctx context.Context, challenge *domain.MFAChallenge) error {
+	query := `
+		INSERT INTO mfa_challenges (id, user_id, token_hash, attempts, first_factor, user_agent, client_ip, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		challenge.ID, challenge.UserID, challenge.TokenHash, challenge.Attempts, challenge.FirstFactor,
+		challenge.UserAgent, challenge.ClientIP, challenge.ExpiresAt, challenge.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error al crear el reto MFA: %w", err)
+	}
+	return nil
+}
+
+// GetChallengeByTokenHash busca un reto MFA por el hash de su token
+func (r *mfaRepositoryPg) GetChallengeByTokenHash(ctx context.Context, tokenHash string) (*domain.MFAChallenge, error) {
+	query := `
+		SELECT id, user_id, token_hash, attempts, first_factor, user_agent, client_ip, expires_at, created_at
+		FROM mfa_challenges WHERE token_hash = $1
+	`
+	challenge := &domain.MFAChallenge{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&challenge.ID, &challenge.UserID, &challenge.TokenHash, &challenge.Attempts, &challenge.FirstFactor,
+		&challenge.UserAgent, &challenge.ClientIP, &challenge.ExpiresAt, &challenge.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecases.ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el reto MFA: %w", err)
+	}
+	return challenge, nil
+}
+
+// ReserveChallengeAttempt suma un intento al reto en una sola sentencia, de
+// modo que las peticiones simultáneas no pueden superar maxAttempts
+func (r *mfaRepositoryPg) ReserveChallengeAttempt(ctx context.Context, id string, maxAttempts int) (bool, error) {
+	query := `UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = $1 AND attempts < $2`
+	res, err := r.db.ExecContext(ctx, query, id, maxAttempts)
+	if err != nil {
+		return false, fmt.Errorf("error al actualizar el reto MFA: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// DeleteChallenge consume un reto MFA. Devuelve ErrInvalidMFAChallenge si ya fue usado.
+func (r *mfaRepositoryPg) DeleteChallenge(ctx context.Context, id string) error {
+	query := `DELETE FROM mfa_challenges WHERE id = $1`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el reto MFA: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrInvalidMFAChallenge
+	}
+	return nil
+}
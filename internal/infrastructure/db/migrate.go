@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/kevinhc2110/Auth_UCP/migrations"
+)
+
+// migrationLockID identifica el bloqueo consultivo que evita que dos
+// instancias migren a la vez
+const migrationLockID = 727100001
+
+// Migrate lleva el esquema a la versión actual en cada arranque. init.sql solo
+// lo ejecuta docker-entrypoint-initdb.d con el volumen vacío, así que una base
+// creada con una versión anterior necesita los cambios de upgrades/, que se
+// aplican una sola vez y quedan en schema_migrations. Después se vuelve a
+// ejecutar init.sql, que solo crea las tablas e índices que falten.
+func Migrate(ctx context.Context, database *sql.DB) (err error) {
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error al obtener una conexión para migrar: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("error al bloquear las migraciones: %w", err)
+	}
+	defer func() {
+		if _, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); unlockErr != nil && err == nil {
+			err = fmt.Errorf("error al desbloquear las migraciones: %w", unlockErr)
+		}
+	}()
+
+	schema, err := migrations.FS.ReadFile("init.sql")
+	if err != nil {
+		return fmt.Errorf("error al leer init.sql: %w", err)
+	}
+
+	// Una base vacía recibe el esquema completo, que ya registra las actualizaciones
+	var users sql.NullString
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass('users')::text`).Scan(&users); err != nil {
+		return fmt.Errorf("error al consultar el esquema: %w", err)
+	}
+	if !users.Valid {
+		if _, err := conn.ExecContext(ctx, string(schema)); err != nil {
+			return fmt.Errorf("error al crear el esquema: %w", err)
+		}
+	}
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version TEXT PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		return fmt.Errorf("error al crear schema_migrations: %w", err)
+	}
+
+	upgrades, err := fs.Glob(migrations.FS, "upgrades/*.sql")
+	if err != nil {
+		return fmt.Errorf("error al listar las actualizaciones del esquema: %w", err)
+	}
+	for _, name := range upgrades {
+		if err := applyUpgrade(ctx, conn, name); err != nil {
+			return err
+		}
+	}
+
+	if _, err := conn.ExecContext(ctx, string(schema)); err != nil {
+		return fmt.Errorf("error al completar el esquema: %w", err)
+	}
+	return nil
+}
+
+// applyUpgrade aplica una actualización del esquema si no se aplicó antes
+func applyUpgrade(ctx context.Context, conn *sql.Conn, name string) (err error) {
+	version := strings.TrimSuffix(path.Base(name), ".sql")
+
+	var applied bool
+	if err := conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied); err != nil {
+		return fmt.Errorf("error al consultar la migración %s: %w", version, err)
+	}
+	if applied {
+		return nil
+	}
+
+	upgrade, err := migrations.FS.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("error al leer la migración %s: %w", version, err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar la migración %s: %w", version, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, string(upgrade)); err != nil {
+		return fmt.Errorf("error al aplicar la migración %s: %w", version, err)
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+		return fmt.Errorf("error al registrar la migración %s: %w", version, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar la migración %s: %w", version, err)
+	}
+
+	log.Printf("Migración %s aplicada", version)
+	return nil
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+	"github.com/lib/pq"
+)
+
+type oauthRepositoryPg struct {
+	db *sql.DB
+}
+
+// NewOAuthRepositoryPg crea una nueva instancia del repositorio de clientes y códigos OAuth
+func NewOAuthRepositoryPg(db *sql.DB) *oauthRepositoryPg {
+	return &oauthRepositoryPg{db: db}
+}
+
+const oauthClientColumns = `client_id, secret_hash, name, client_type, redirect_uris, scopes, grant_types, created_by, created_at`
+
+func scanOAuthClient(row interface{ Scan(...any) error }) (*domain.OAuthClient, error) {
+	client := &domain.OAuthClient{}
+	var createdBy sql.NullString
+	err := row.Scan(
+		&client.ClientID, &client.SecretHash, &client.Name, &client.Type,
+		pq.Array(&client.RedirectURIs), pq.Array(&client.Scopes), pq.Array(&client.GrantTypes), &createdBy, &client.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	client.CreatedBy = createdBy.String
+	return client, nil
+}
+
+// CreateClient registra un cliente OAuth
+func (r *oauthRepositoryPg) CreateClient(ctx context.Context, client *domain.OAuthClient) error {
+	query := `INSERT INTO oauth_clients (` + oauthClientColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := r.db.ExecContext(ctx, query,
+		client.ClientID, client.SecretHash, client.Name, client.Type,
+		pq.Array(client.RedirectURIs), pq.Array(client.Scopes), pq.Array(client.GrantTypes), nullString(client.CreatedBy), client.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error al registrar el cliente OAuth: %w", err)
+	}
+	return nil
+}
+
+// GetClient obtiene un cliente OAuth por su client_id
+func (r *oauthRepositoryPg) GetClient(ctx context.Context, clientID string) (*domain.OAuthClient, error) {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE client_id = $1`
+	client, err := scanOAuthClient(r.db.QueryRowContext(ctx, query, clientID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecases.ErrOAuthClientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el cliente OAuth: %w", err)
+	}
+	return client, nil
+}
+
+// ListClients lista los clientes OAuth registrados
+func (r *oauthRepositoryPg) ListClients(ctx context.Context) ([]*domain.OAuthClient, error) {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar los clientes OAuth: %w", err)
+	}
+	defer rows.Close()
+
+	clients := []*domain.OAuthClient{}
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer el cliente OAuth: %w", err)
+		}
+		clients = append(clients, client)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al listar los clientes OAuth: %w", err)
+	}
+	return clients, nil
+}
+
+// DeleteClient elimina un cliente OAuth junto con sus códigos pendientes
+func (r *oauthRepositoryPg) DeleteClient(ctx context.Context, clientID string) error {
+	query := `DELETE FROM oauth_clients WHERE client_id = $1`
+	res, err := r.db.ExecContext(ctx, query, clientID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el cliente OAuth: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+// CreateAuthorizationCode guarda un código de autorización pendiente de canje
+func (r *oauthRepositoryPg) CreateAuthorizationCode(ctx context.Context, code *domain.OAuthAuthorizationCode) error {
+	query := `
+		INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes,
+			code_challenge, code_challenge_method, nonce, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, pq.Array(code.Scopes),
+		code.CodeChallenge, code.CodeChallengeMethod, code.Nonce, code.ExpiresAt, code.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error al guardar el código de autorización: %w", err)
+	}
+	return nil
+}
+
+// ConsumeAuthorizationCode elimina el código y lo devuelve. Si ya fue canjeado
+// devuelve ErrOAuthInvalidGrant.
+func (r *oauthRepositoryPg) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*domain.OAuthAuthorizationCode, error) {
+	query := `
+		DELETE FROM oauth_authorization_codes WHERE code_hash = $1
+		RETURNING code_hash, client_id, user_id, redirect_uri, scopes,
+			code_challenge, code_challenge_method, nonce, expires_at, created_at
+	`
+	code := &domain.OAuthAuthorizationCode{}
+	err := r.db.QueryRowContext(ctx, query, codeHash).Scan(
+		&code.CodeHash, &code.ClientID, &code.UserID, &code.RedirectURI, pq.Array(&code.Scopes),
+		&code.CodeChallenge, &code.CodeChallengeMethod, &code.Nonce, &code.ExpiresAt, &code.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecases.ErrOAuthInvalidGrant
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al canjear el código de autorización: %w", err)
+	}
+	return code, nil
+}
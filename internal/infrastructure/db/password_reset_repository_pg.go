@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+type passwordResetRepositoryPg struct {
+	db *sql.DB
+}
+
+// NewPasswordResetRepositoryPg crea una nueva instancia del repositorio de enlaces de restablecimiento
+func NewPasswordResetRepositoryPg(db *sql.DB) *passwordResetRepositoryPg {
+	return &passwordResetRepositoryPg{db: db}
+}
+
+// CreateToken guarda un nuevo token de restablecimiento
+func (r *passwordResetRepositoryPg) CreateToken(ctx context.Context, token *domain.PasswordResetToken) error {
+	query := `INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.ExecContext(ctx, query, token.ID, token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error al guardar el token de restablecimiento: %w", err)
+	}
+	return nil
+}
+
+// GetTokenByHash busca un token de restablecimiento por su hash
+func (r *passwordResetRepositoryPg) GetTokenByHash(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	query := `SELECT id, user_id, token_hash, expires_at, used_at, created_at FROM password_reset_tokens WHERE token_hash = $1`
+
+	token := &domain.PasswordResetToken{}
+	var usedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &usedAt, &token.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecases.ErrInvalidResetToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el token de restablecimiento: %w", err)
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return token, nil
+}
+
+// MarkTokenUsed consume el token. Falla si ya fue usado.
+func (r *passwordResetRepositoryPg) MarkTokenUsed(ctx context.Context, id string) error {
+	query := `UPDATE password_reset_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("error al usar el token de restablecimiento: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrInvalidResetToken
+	}
+	return nil
+}
+
+// DeleteTokensByUserID elimina los tokens pendientes del usuario
+func (r *passwordResetRepositoryPg) DeleteTokensByUserID(ctx context.Context, userID string) error {
+	query := `DELETE FROM password_reset_tokens WHERE user_id = $1 AND used_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar los tokens de restablecimiento: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type revokedTokenRepositoryPg struct {
+	db *sql.DB
+}
+
+// NewRevokedTokenRepositoryPg crea una nueva instancia del repositorio de tokens revocados
+func NewRevokedTokenRepositoryPg(db *sql.DB) *revokedTokenRepositoryPg {
+	return &revokedTokenRepositoryPg{db: db}
+}
+
+// RevokeToken guarda el jti del token revocado hasta que expire
+func (r *revokedTokenRepositoryPg) RevokeToken(ctx context.Context, token *domain.RevokedToken) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, client_id, expires_at, revoked_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		token.JTI, nullString(token.UserID), token.ClientID, token.ExpiresAt, token.RevokedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error al revocar el token: %w", err)
+	}
+	return nil
+}
+
+// ListRevokedTokens lista los tokens revocados desde since que aún no expiran
+func (r *revokedTokenRepositoryPg) ListRevokedTokens(ctx context.Context, since, now time.Time) ([]*domain.RevokedToken, error) {
+	query := `
+		SELECT jti, user_id, client_id, expires_at, revoked_at
+		FROM revoked_tokens
+		WHERE revoked_at >= $1 AND expires_at > $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, since, now)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar los tokens revocados: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*domain.RevokedToken{}
+	for rows.Next() {
+		token := &domain.RevokedToken{}
+		var userID sql.NullString
+		if err := rows.Scan(&token.JTI, &userID, &token.ClientID, &token.ExpiresAt, &token.RevokedAt); err != nil {
+			return nil, fmt.Errorf("error al leer el token revocado: %w", err)
+		}
+		token.UserID = userID.String
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al listar los tokens revocados: %w", err)
+	}
+	return tokens, nil
+}
+
+// DeleteExpiredRevokedTokens elimina los tokens revocados que ya expiraron
+func (r *revokedTokenRepositoryPg) DeleteExpiredRevokedTokens(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("error al eliminar los tokens revocados expirados: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// RevokeSessions guarda la revocación de una sesión o de un usuario. Si ya
+// existía se conserva la fecha más reciente, que cubre a la anterior.
+func (r *revokedTokenRepositoryPg) RevokeSessions(ctx context.Context, revocation *domain.SessionRevocation) error {
+	query := `
+		INSERT INTO session_revocations (kind, subject, revoked_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (kind, subject) DO UPDATE
+		SET revoked_at = GREATEST(session_revocations.revoked_at, EXCLUDED.revoked_at),
+		    expires_at = GREATEST(session_revocations.expires_at, EXCLUDED.expires_at)
+	`
+	_, err := r.db.ExecContext(ctx, query, revocation.Kind, revocation.Subject, revocation.RevokedAt, revocation.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("error al revocar las sesiones: %w", err)
+	}
+	return nil
+}
+
+// ListSessionRevocations lista las revocaciones de sesiones desde since que aún no expiran
+func (r *revokedTokenRepositoryPg) ListSessionRevocations(ctx context.Context, since, now time.Time) ([]*domain.SessionRevocation, error) {
+	query := `
+		SELECT kind, subject, revoked_at, expires_at
+		FROM session_revocations
+		WHERE revoked_at >= $1 AND expires_at > $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, since, now)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar las sesiones revocadas: %w", err)
+	}
+	defer rows.Close()
+
+	revocations := []*domain.SessionRevocation{}
+	for rows.Next() {
+		revocation := &domain.SessionRevocation{}
+		if err := rows.Scan(&revocation.Kind, &revocation.Subject, &revocation.RevokedAt, &revocation.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("error al leer la sesión revocada: %w", err)
+		}
+		revocations = append(revocations, revocation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al listar las sesiones revocadas: %w", err)
+	}
+	return revocations, nil
+}
+
+// DeleteExpiredSessionRevocations elimina las revocaciones de sesiones cuyos tokens ya expiraron
+func (r *revokedTokenRepositoryPg) DeleteExpiredSessionRevocations(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM session_revocations WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("error al eliminar las sesiones revocadas expiradas: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type securityEventRepositoryPg struct {
+	db *sql.DB
+}
+
+// NewSecurityEventRepositoryPg crea una nueva instancia del repositorio de eventos de seguridad
+func NewSecurityEventRepositoryPg(db *sql.DB) *securityEventRepositoryPg {
+	return &securityEventRepositoryPg{db: db}
+}
+
+// CreateEvent guarda un evento de seguridad
+func (r *securityEventRepositoryPg) CreateEvent(ctx context.Context, event *domain.SecurityEvent) error {
+	query := `
+		INSERT INTO security_events (id, user_id, actor_id, event_type, client_ip, user_agent, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		event.ID, nullString(event.UserID), nullString(event.ActorID), event.Type, event.ClientIP, event.UserAgent,
+		event.Details, event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error al guardar el evento de seguridad: %w", err)
+	}
+	return nil
+}
+
+// nullString convierte una cadena vacía en NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -5,11 +5,14 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/kevinhc2110/Auth_UCP/internal/domain"
 	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
 )
 
+const sessionColumns = `id, family_id, user_id, refresh_token, user_agent, client_ip, is_blocked, blocked_reason, blocked_by, blocked_at, rotated_at, expires_at, created_at, updated_at`
+
 type sessionRepositorypg struct {
 	db *sql.DB
 }
@@ -19,17 +22,48 @@ func NewSessionRepositorypg(db *sql.DB) *sessionRepositorypg {
 	return &sessionRepositorypg{db: db}
 }
 
-// CreateSession guarda una nueva sesión en la base de datos
-func (r *sessionRepositorypg) CreateSession(ctx context.Context, session *domain.Session) error {
+// scanSession lee una fila con las columnas de sessionColumns
+func scanSession(row interface{ Scan(dest ...any) error }) (*domain.Session, error) {
+	session := &domain.Session{}
+	var blockedBy sql.NullString
+	var blockedAt, rotatedAt sql.NullTime
+	err := row.Scan(
+		&session.ID, &session.FamilyID, &session.UserID, &session.RefreshToken, &session.UserAgent,
+		&session.ClientIP, &session.IsBlocked, &session.BlockedReason, &blockedBy, &blockedAt,
+		&rotatedAt, &session.ExpiresAt, &session.CreatedAt, &session.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	session.BlockedBy = blockedBy.String
+	if blockedAt.Valid {
+		session.BlockedAt = &blockedAt.Time
+	}
+	if rotatedAt.Valid {
+		session.RotatedAt = &rotatedAt.Time
+	}
+	return session, nil
+}
+
+// insertSession guarda una sesión usando la conexión o transacción indicada
+func insertSession(ctx context.Context, exec interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}, session *domain.Session) error {
 	query := `
-		INSERT INTO sessions (id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO sessions (` + sessionColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
-	_, err := r.db.ExecContext(ctx, query,
-		session.ID, session.UserID, session.RefreshToken, session.UserAgent, session.ClientIP,
-		session.IsBlocked, session.ExpiresAt, session.CreatedAt, session.UpdatedAt,
+	_, err := exec.ExecContext(ctx, query,
+		session.ID, session.FamilyID, session.UserID, session.RefreshToken, session.UserAgent, session.ClientIP,
+		session.IsBlocked, session.BlockedReason, nullString(session.BlockedBy), session.BlockedAt,
+		session.RotatedAt, session.ExpiresAt, session.CreatedAt, session.UpdatedAt,
 	)
-	if err != nil {
+	return err
+}
+
+// CreateSession guarda una nueva sesión en la base de datos
+func (r *sessionRepositorypg) CreateSession(ctx context.Context, session *domain.Session) error {
+	if err := insertSession(ctx, r.db, session); err != nil {
 		return fmt.Errorf("error al crear la sesión: %w", err)
 	}
 	return nil
@@ -37,14 +71,8 @@ func (r *sessionRepositorypg) CreateSession(ctx context.Context, session *domain
 
 // GetSessionByID busca una sesión por ID
 func (r *sessionRepositorypg) GetSessionByID(ctx context.Context, id string) (*domain.Session, error) {
-	query := `SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, updated_at FROM sessions WHERE id = $1`
-	row := r.db.QueryRowContext(ctx, query, id)
-
-	session := &domain.Session{}
-	err := row.Scan(
-		&session.ID, &session.UserID, &session.RefreshToken, &session.UserAgent,
-		&session.ClientIP, &session.IsBlocked, &session.ExpiresAt, &session.CreatedAt, &session.UpdatedAt,
-	)
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+	session, err := scanSession(r.db.QueryRowContext(ctx, query, id))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, usecases.ErrSessionNotFound
 	}
@@ -56,14 +84,8 @@ func (r *sessionRepositorypg) GetSessionByID(ctx context.Context, id string) (*d
 
 // GetSessionByToken busca una sesión por refresh token
 func (r *sessionRepositorypg) GetSessionByToken(ctx context.Context, refreshToken string) (*domain.Session, error) {
-	query := `SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, updated_at FROM sessions WHERE refresh_token = $1`
-	row := r.db.QueryRowContext(ctx, query, refreshToken)
-
-	session := &domain.Session{}
-	err := row.Scan(
-		&session.ID, &session.UserID, &session.RefreshToken, &session.UserAgent,
-		&session.ClientIP, &session.IsBlocked, &session.ExpiresAt, &session.CreatedAt, &session.UpdatedAt,
-	)
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE refresh_token = $1`
+	session, err := scanSession(r.db.QueryRowContext(ctx, query, refreshToken))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, usecases.ErrInvalidSession
 	}
@@ -82,6 +104,78 @@ func (r *sessionRepositorypg) GetSessionByToken(ctx context.Context, refreshToke
 	return session, nil
 }
 
+// ListActiveSessionsByUserID lista el token vigente de cada sesión activa del usuario
+func (r *sessionRepositorypg) ListActiveSessionsByUserID(ctx context.Context, userID string) ([]*domain.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + ` FROM sessions
+		WHERE user_id = $1 AND rotated_at IS NULL AND is_blocked = FALSE AND expires_at > NOW()
+		ORDER BY updated_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar las sesiones del usuario: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*domain.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la sesión: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al listar las sesiones del usuario: %w", err)
+	}
+	return sessions, nil
+}
+
+// SearchSessions busca las sesiones vigentes de cualquier usuario según el filtro
+func (r *sessionRepositorypg) SearchSessions(ctx context.Context, filter domain.SessionFilter) ([]*domain.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE rotated_at IS NULL`
+	args := []any{}
+
+	if filter.FamilyID != "" {
+		args = append(args, filter.FamilyID)
+		query += fmt.Sprintf(" AND family_id = $%d", len(args))
+	}
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		query += fmt.Sprintf(" AND user_id = $%d", len(args))
+	}
+	if filter.ClientIP != "" {
+		args = append(args, filter.ClientIP)
+		query += fmt.Sprintf(" AND client_ip = $%d", len(args))
+	}
+	if filter.UserAgent != "" {
+		args = append(args, "%"+filter.UserAgent+"%")
+		query += fmt.Sprintf(" AND user_agent ILIKE $%d", len(args))
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY updated_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar sesiones: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*domain.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la sesión: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al buscar sesiones: %w", err)
+	}
+	return sessions, nil
+}
+
 // DeleteSession elimina una sesión por ID
 func (r *sessionRepositorypg) DeleteSession(ctx context.Context, id string) error {
 	query := `DELETE FROM sessions WHERE id = $1`
@@ -96,6 +190,36 @@ func (r *sessionRepositorypg) DeleteSession(ctx context.Context, id string) erro
 	return nil
 }
 
+// DeleteSessionsByFamilyID elimina todos los refresh tokens de una familia
+func (r *sessionRepositorypg) DeleteSessionsByFamilyID(ctx context.Context, familyID string) error {
+	query := `DELETE FROM sessions WHERE family_id = $1`
+	res, err := r.db.ExecContext(ctx, query, familyID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar la familia de sesiones: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredSessions elimina las familias cuyo último refresh token ya
+// expiró. Hasta entonces se conservan los tokens rotados, que sirven para
+// detectar su reutilización.
+func (r *sessionRepositorypg) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		DELETE FROM sessions WHERE family_id IN (
+			SELECT family_id FROM sessions GROUP BY family_id HAVING MAX(expires_at) <= $1
+		)
+	`
+	res, err := r.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, fmt.Errorf("error al eliminar las sesiones expiradas: %w", err)
+	}
+	return res.RowsAffected()
+}
+
 // DeleteSessionsByUserID elimina todas las sesiones de un usuario
 func (r *sessionRepositorypg) DeleteSessionsByUserID(ctx context.Context, userID string) error {
 	query := `DELETE FROM sessions WHERE user_id = $1`
@@ -106,6 +230,16 @@ func (r *sessionRepositorypg) DeleteSessionsByUserID(ctx context.Context, userID
 	return nil
 }
 
+// DeleteOtherSessionsByUserID elimina todas las sesiones del usuario excepto la familia indicada
+func (r *sessionRepositorypg) DeleteOtherSessionsByUserID(ctx context.Context, userID, keepFamilyID string) error {
+	query := `DELETE FROM sessions WHERE user_id = $1 AND family_id <> $2`
+	_, err := r.db.ExecContext(ctx, query, userID, keepFamilyID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar las demás sesiones del usuario: %w", err)
+	}
+	return nil
+}
+
 // UpdateSession actualiza una sesión existente
 func (r *sessionRepositorypg) UpdateSession(ctx context.Context, session *domain.Session) error {
 	query := `
@@ -126,3 +260,58 @@ func (r *sessionRepositorypg) UpdateSession(ctx context.Context, session *domain
 	}
 	return nil
 }
+
+// SetFamilyBlocked bloquea o desbloquea todos los refresh tokens de una familia
+func (r *sessionRepositorypg) SetFamilyBlocked(ctx context.Context, familyID string, blocked bool, reason, blockedBy string) error {
+	var blockedAt *time.Time
+	if blocked {
+		now := time.Now()
+		blockedAt = &now
+	} else {
+		reason, blockedBy = "", ""
+	}
+
+	query := `
+		UPDATE sessions
+		SET is_blocked = $1, blocked_reason = $2, blocked_by = $3, blocked_at = $4, updated_at = NOW()
+		WHERE family_id = $5
+	`
+	res, err := r.db.ExecContext(ctx, query, blocked, reason, nullString(blockedBy), blockedAt, familyID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el bloqueo de la sesión: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrSessionNotFound
+	}
+	return nil
+}
+
+// RotateSession marca la sesión actual como rotada y guarda su reemplazo en una
+// sola transacción. Si otra petición ya rotó el token devuelve ErrRefreshTokenReused.
+func (r *sessionRepositorypg) RotateSession(ctx context.Context, current, next *domain.Session) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar la rotación de la sesión: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE sessions SET rotated_at = $1, updated_at = $1 WHERE id = $2 AND rotated_at IS NULL`
+	res, err := tx.ExecContext(ctx, query, current.RotatedAt, current.ID)
+	if err != nil {
+		return fmt.Errorf("error al rotar la sesión: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrRefreshTokenReused
+	}
+
+	if err := insertSession(ctx, tx, next); err != nil {
+		return fmt.Errorf("error al crear la sesión rotada: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar la rotación de la sesión: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+type signingKeyRepositoryPg struct {
+	db *sql.DB
+}
+
+// NewSigningKeyRepositoryPg crea una nueva instancia del repositorio de claves de firma
+func NewSigningKeyRepositoryPg(db *sql.DB) *signingKeyRepositoryPg {
+	return &signingKeyRepositoryPg{db: db}
+}
+
+// ListPublishedKeys lista las claves que aún verifican tokens, de la más antigua a la más nueva
+func (r *signingKeyRepositoryPg) ListPublishedKeys(ctx context.Context, now time.Time) ([]*domain.SigningKey, error) {
+	query := `
+		SELECT kid, algorithm, private_key, created_at, retired_at, published_until
+		FROM signing_keys
+		WHERE retired_at IS NULL OR published_until > $1
+		ORDER BY created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar las claves de firma: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*domain.SigningKey{}
+	for rows.Next() {
+		key := &domain.SigningKey{}
+		var retiredAt, publishedUntil sql.NullTime
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &key.CreatedAt, &retiredAt, &publishedUntil); err != nil {
+			return nil, fmt.Errorf("error al leer la clave de firma: %w", err)
+		}
+		if retiredAt.Valid {
+			key.RetiredAt = &retiredAt.Time
+		}
+		if publishedUntil.Valid {
+			key.PublishedUntil = &publishedUntil.Time
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al listar las claves de firma: %w", err)
+	}
+	return keys, nil
+}
+
+// CreateInitialKey guarda la primera clave activa
+func (r *signingKeyRepositoryPg) CreateInitialKey(ctx context.Context, key *domain.SigningKey) error {
+	query := `
+		INSERT INTO signing_keys (kid, algorithm, private_key, created_at)
+		SELECT $1, $2, $3, $4
+		WHERE NOT EXISTS (SELECT 1 FROM signing_keys WHERE retired_at IS NULL)
+		ON CONFLICT (kid) DO NOTHING
+	`
+	_, err := r.db.ExecContext(ctx, query, key.ID, key.Algorithm, key.PrivateKey, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error al guardar la clave de firma: %w", err)
+	}
+	return nil
+}
+
+// RotateKey retira la clave activa y guarda la siguiente en una transacción
+func (r *signingKeyRepositoryPg) RotateKey(ctx context.Context, currentID string, next *domain.SigningKey, retiredAt, publishedUntil time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar la rotación de claves: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE signing_keys SET retired_at = $1, published_until = $2 WHERE kid = $3 AND retired_at IS NULL`,
+		retiredAt, publishedUntil, currentID,
+	)
+	if err != nil {
+		return fmt.Errorf("error al retirar la clave de firma: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrSigningKeyRotated
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO signing_keys (kid, algorithm, private_key, created_at) VALUES ($1, $2, $3, $4)`,
+		next.ID, next.Algorithm, next.PrivateKey, next.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error al guardar la clave de firma: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar la rotación de claves: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredKeys elimina las claves retiradas que ya no verifican ningún token vigente
+func (r *signingKeyRepositoryPg) DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM signing_keys WHERE published_until <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("error al eliminar las claves de firma expiradas: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -5,14 +5,18 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/kevinhc2110/Auth_UCP/internal/domain"
 	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
-	"github.com/kevinhc2110/Auth_UCP/internal/usecases" 
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
 	"github.com/lib/pq"
 )
 
+const userColumns = `id, document_type, identification, name, lastname, email, password, role, active, mfa_enabled, created_at, updated_at, lastlogin_at,
+	email_verified_at, verification_sent_at`
+
 type UserRepositoryPg struct {
 	db *sql.DB
 }
@@ -21,32 +25,61 @@ func NewUserRepositoryPg(db *sql.DB) repositories.UserRepository {
 	return &UserRepositoryPg{db: db}
 }
 
+// scanUser lee una fila con las columnas de userColumns
+func scanUser(row interface{ Scan(dest ...any) error }) (*domain.User, error) {
+	var user domain.User
+	var lastLoginAt, emailVerifiedAt, verificationSentAt sql.NullTime
+	err := row.Scan(
+		&user.ID, &user.DocumentType, &user.Identification, &user.Name, &user.Lastname, &user.Email, &user.Password, &user.Role,
+		&user.Active, &user.MFAEnabled, &user.CreatedAt, &user.UpdatedAt, &lastLoginAt,
+		&emailVerifiedAt, &verificationSentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	user.LastLoginAt = lastLoginAt.Time
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if verificationSentAt.Valid {
+		user.VerificationSentAt = &verificationSentAt.Time
+	}
+	return &user, nil
+}
+
 func (r *UserRepositoryPg) Create(ctx context.Context, user *domain.User) error {
-	query := `INSERT INTO users (id, identification, name, lastname, email, password, role, active, created_at, updated_at, lastlogin_at)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	query := `INSERT INTO users (id, document_type, identification, name, lastname, email, password, role, active, created_at, updated_at, lastlogin_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
 	_, err := r.db.ExecContext(ctx, query,
-		user.ID, user.Identification, user.Name, user.Lastname, user.Email, user.Password, user.Role,
+		user.ID, user.DocumentType, user.Identification, user.Name, user.Lastname, user.Email, user.Password, user.Role,
 		user.Active, user.CreatedAt, user.UpdatedAt, user.LastLoginAt,
 	)
 
 	if err != nil {
-		if pgErr, ok := err.(*pq.Error); ok {
-			if pgErr.Code == "23505" { // Código de error para violación de restricción UNIQUE
-				return usecases.ErrEmailAlreadyExists
-			}
+		if uniqueErr := userUniqueViolation(err); uniqueErr != nil {
+			return uniqueErr
 		}
 		return fmt.Errorf("error al crear el usuario en la base de datos: %w", err)
 	}
 	return nil
 }
 
+// userUniqueViolation traduce la violación de una restricción UNIQUE de users
+// al error de la columna repetida, o devuelve nil si err es otro error
+func userUniqueViolation(err error) error {
+	pgErr, ok := err.(*pq.Error)
+	if !ok || pgErr.Code != "23505" { // Código de error para violación de restricción UNIQUE
+		return nil
+	}
+	if pgErr.Constraint == "users_document_key" {
+		return usecases.ErrIdentificationAlreadyExists
+	}
+	return usecases.ErrEmailAlreadyExists
+}
+
 func (r *UserRepositoryPg) FindByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
-	var user domain.User
-	query := `SELECT id, identification, name, lastname, email, password, role, active, created_at, updated_at, lastlogin_at FROM users WHERE id = $1`
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID, &user.Identification, &user.Name, &user.Lastname, &user.Email, &user.Password, &user.Role,
-		&user.Active, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, usecases.ErrUserNotFound
@@ -55,50 +88,49 @@ func (r *UserRepositoryPg) FindByID(ctx context.Context, id uuid.UUID) (*domain.
 		return nil, fmt.Errorf("error al buscar usuario por ID: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
-func (r *UserRepositoryPg) FindByIdentification(ctx context.Context, identification string) (*domain.User, error) {
-	var user domain.User
-	query := `SELECT id, identification, email, password, active, created_at, updated_at FROM users WHERE identification = $1`
-	err := r.db.QueryRowContext(ctx, query, identification).Scan(
-		&user.ID, &user.Identification, &user.Email, &user.Password,
-		&user.Active, &user.CreatedAt, &user.UpdatedAt,
-	)
+func (r *UserRepositoryPg) FindByIdentification(ctx context.Context, documentType, identification string) (*domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE document_type = $1 AND identification = $2`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, documentType, identification))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, usecases.ErrUserNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error al buscar usuario por identificación: %w", err)
 	}
-	return &user, nil
+	return user, nil
 }
 
 func (r *UserRepositoryPg) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
-	var user domain.User
-	query := `SELECT id, identification, email, password, active, created_at, updated_at FROM users WHERE email = $1`
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID, &user.Identification, &user.Email, &user.Password,
-		&user.Active, &user.CreatedAt, &user.UpdatedAt,
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, email))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, usecases.ErrUserNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error al buscar usuario por email: %w", err)
 	}
-	return &user, nil
+	return user, nil
 }
 
+// Update actualiza los datos del usuario. Si cambia el correo, el nuevo queda
+// sin verificar y se puede enviar de inmediato su enlace de verificación.
 func (r *UserRepositoryPg) Update(ctx context.Context, user *domain.User) error {
-	query := `UPDATE users 
-              SET identification = $1, email = $2, password = $3, active = $4, updated_at = $5 
-              WHERE id = $6`
-	
-	result, err := r.db.ExecContext(ctx, query, 
-		user.Identification, user.Email, user.Password, user.Active, user.UpdatedAt, user.ID)
+	query := `UPDATE users
+              SET document_type = $1, identification = $2, email = $3, password = $4, active = $5, updated_at = $6,
+                  email_verified_at = CASE WHEN email = $3 THEN email_verified_at END,
+                  verification_sent_at = CASE WHEN email = $3 THEN verification_sent_at END
+              WHERE id = $7`
+
+	result, err := r.db.ExecContext(ctx, query,
+		user.DocumentType, user.Identification, user.Email, user.Password, user.Active, user.UpdatedAt, user.ID)
 
 	if err != nil {
+		if uniqueErr := userUniqueViolation(err); uniqueErr != nil {
+			return uniqueErr
+		}
 		return fmt.Errorf("error al actualizar el usuario: %w", err)
 	}
 
@@ -110,6 +142,73 @@ func (r *UserRepositoryPg) Update(ctx context.Context, user *domain.User) error
 	return nil
 }
 
+// UpdateMFAEnabled activa o desactiva la exigencia de segundo factor del usuario
+func (r *UserRepositoryPg) UpdateMFAEnabled(ctx context.Context, id uuid.UUID, enabled bool) error {
+	query := `UPDATE users SET mfa_enabled = $1, updated_at = NOW() WHERE id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, enabled, id)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el segundo factor del usuario: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return usecases.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdatePassword reemplaza el hash de la contraseña del usuario
+func (r *UserRepositoryPg) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	query := `UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, passwordHash, id)
+	if err != nil {
+		return fmt.Errorf("error al actualizar la contraseña del usuario: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return usecases.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// MarkEmailVerified confirma el correo del usuario. Solo aplica si el correo no
+// cambió desde que se emitió el enlace.
+func (r *UserRepositoryPg) MarkEmailVerified(ctx context.Context, id uuid.UUID, email string, verifiedAt time.Time) error {
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, $1), updated_at = NOW() WHERE id = $2 AND email = $3`
+
+	result, err := r.db.ExecContext(ctx, query, verifiedAt, id, email)
+	if err != nil {
+		return fmt.Errorf("error al verificar el correo del usuario: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return usecases.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ReserveVerificationEmail registra un envío del enlace de verificación solo si
+// el anterior fue antes de notAfter. Devuelve false si aún no puede reenviarse.
+func (r *UserRepositoryPg) ReserveVerificationEmail(ctx context.Context, id uuid.UUID, sentAt, notAfter time.Time) (bool, error) {
+	query := `UPDATE users SET verification_sent_at = $1
+	          WHERE id = $2 AND (verification_sent_at IS NULL OR verification_sent_at < $3)`
+
+	result, err := r.db.ExecContext(ctx, query, sentAt, id, notAfter)
+	if err != nil {
+		return false, fmt.Errorf("error al registrar el envío de verificación: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
 func (r *UserRepositoryPg) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM users WHERE id = $1`
 
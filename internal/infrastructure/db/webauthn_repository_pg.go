@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+	"github.com/lib/pq"
+)
+
+const webauthnCredentialColumns = `id, user_id, credential_id, public_key, sign_count, transports, aaguid, nickname, created_at, last_used_at`
+
+type webauthnRepositoryPg struct {
+	db *sql.DB
+}
+
+// NewWebAuthnRepositoryPg crea una nueva instancia del repositorio de credenciales WebAuthn
+func NewWebAuthnRepositoryPg(db *sql.DB) *webauthnRepositoryPg {
+	return &webauthnRepositoryPg{db: db}
+}
+
+// scanWebAuthnCredential lee una fila con las columnas de webauthnCredentialColumns
+func scanWebAuthnCredential(row interface{ Scan(dest ...any) error }) (*domain.WebAuthnCredential, error) {
+	credential := &domain.WebAuthnCredential{}
+	var signCount int64
+	var lastUsedAt sql.NullTime
+	err := row.Scan(
+		&credential.ID, &credential.UserID, &credential.CredentialID, &credential.PublicKey, &signCount,
+		pq.Array(&credential.Transports), &credential.AAGUID, &credential.Nickname, &credential.CreatedAt, &lastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	credential.SignCount = uint32(signCount)
+	if lastUsedAt.Valid {
+		credential.LastUsedAt = &lastUsedAt.Time
+	}
+	return credential, nil
+}
+
+// CreateCredential guarda una credencial recién registrada
+func (r *webauthnRepositoryPg) CreateCredential(ctx context.Context, credential *domain.WebAuthnCredential) error {
+	query := `
+		INSERT INTO webauthn_credentials (id, user_id, credential_id, public_key, sign_count, transports, aaguid, nickname, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		credential.ID, credential.UserID, credential.CredentialID, credential.PublicKey, int64(credential.SignCount),
+		pq.Array(credential.Transports), credential.AAGUID, credential.Nickname, credential.CreatedAt,
+	)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			return usecases.ErrWebAuthnCredentialExists
+		}
+		return fmt.Errorf("error al guardar la credencial WebAuthn: %w", err)
+	}
+	return nil
+}
+
+// GetCredentialByCredentialID busca una credencial por el identificador que asignó el autenticador
+func (r *webauthnRepositoryPg) GetCredentialByCredentialID(ctx context.Context, credentialID string) (*domain.WebAuthnCredential, error) {
+	query := `SELECT ` + webauthnCredentialColumns + ` FROM webauthn_credentials WHERE credential_id = $1`
+	credential, err := scanWebAuthnCredential(r.db.QueryRowContext(ctx, query, credentialID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecases.ErrWebAuthnCredentialNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la credencial WebAuthn: %w", err)
+	}
+	return credential, nil
+}
+
+// ListCredentialsByUserID lista las credenciales registradas por un usuario
+func (r *webauthnRepositoryPg) ListCredentialsByUserID(ctx context.Context, userID string) ([]*domain.WebAuthnCredential, error) {
+	query := `SELECT ` + webauthnCredentialColumns + ` FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar las credenciales WebAuthn: %w", err)
+	}
+	defer rows.Close()
+
+	credentials := []*domain.WebAuthnCredential{}
+	for rows.Next() {
+		credential, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la credencial WebAuthn: %w", err)
+		}
+		credentials = append(credentials, credential)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error al listar las credenciales WebAuthn: %w", err)
+	}
+	return credentials, nil
+}
+
+// UpdateCredentialUsage guarda el nuevo contador de firmas tras una autenticación
+func (r *webauthnRepositoryPg) UpdateCredentialUsage(ctx context.Context, id string, signCount uint32, usedAt time.Time) error {
+	query := `UPDATE webauthn_credentials SET sign_count = $1, last_used_at = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, int64(signCount), usedAt, id)
+	if err != nil {
+		return fmt.Errorf("error al actualizar la credencial WebAuthn: %w", err)
+	}
+	return nil
+}
+
+// DeleteCredential elimina una credencial del usuario
+func (r *webauthnRepositoryPg) DeleteCredential(ctx context.Context, userID, id string) error {
+	query := `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`
+	res, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("error al eliminar la credencial WebAuthn: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrWebAuthnCredentialNotFound
+	}
+	return nil
+}
+
+// CreateChallenge guarda el reto de una ceremonia WebAuthn
+func (r *webauthnRepositoryPg) CreateChallenge(ctx context.Context, challenge *domain.WebAuthnChallenge) error {
+	query := `
+		INSERT INTO webauthn_challenges (id, user_id, ceremony, challenge, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		challenge.ID, nullString(challenge.UserID), challenge.Ceremony, challenge.Challenge,
+		challenge.ExpiresAt, challenge.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error al crear el reto WebAuthn: %w", err)
+	}
+	return nil
+}
+
+// GetChallenge obtiene un reto WebAuthn por su ID
+func (r *webauthnRepositoryPg) GetChallenge(ctx context.Context, id string) (*domain.WebAuthnChallenge, error) {
+	query := `SELECT id, user_id, ceremony, challenge, expires_at, created_at FROM webauthn_challenges WHERE id = $1`
+
+	challenge := &domain.WebAuthnChallenge{}
+	var userID sql.NullString
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&challenge.ID, &userID, &challenge.Ceremony, &challenge.Challenge, &challenge.ExpiresAt, &challenge.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecases.ErrWebAuthnChallenge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el reto WebAuthn: %w", err)
+	}
+	challenge.UserID = userID.String
+	return challenge, nil
+}
+
+// DeleteChallenge consume un reto WebAuthn. Devuelve ErrWebAuthnChallenge si ya fue usado.
+func (r *webauthnRepositoryPg) DeleteChallenge(ctx context.Context, id string) error {
+	query := `DELETE FROM webauthn_challenges WHERE id = $1`
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el reto WebAuthn: %w", err)
+	}
+	affected, _ := res.RowsAffected()
+	if affected == 0 {
+		return usecases.ErrWebAuthnChallenge
+	}
+	return nil
+}
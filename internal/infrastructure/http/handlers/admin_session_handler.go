@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+// AdminSessionHandler expone el control de sesiones para administradores
+type AdminSessionHandler struct {
+	sessionUseCase *usecases.SessionUseCase
+}
+
+// NewAdminSessionHandler crea una nueva instancia de AdminSessionHandler
+func NewAdminSessionHandler(sessionUseCase *usecases.SessionUseCase) *AdminSessionHandler {
+	return &AdminSessionHandler{sessionUseCase: sessionUseCase}
+}
+
+// principalFromContext devuelve el usuario o servicio autenticado por AuthMiddleware
+func principalFromContext(c *gin.Context) *domain.Principal {
+	principal, _ := c.MustGet("principal").(*domain.Principal)
+	return principal
+}
+
+// actorFromContext identifica al administrador que realiza la petición
+func actorFromContext(c *gin.Context) usecases.Actor {
+	return usecases.Actor{
+		UserID:    principalFromContext(c).UserID,
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}
+}
+
+// SearchSessions busca sesiones por usuario, IP o user agent
+func (h *AdminSessionHandler) SearchSessions(c *gin.Context) {
+	filter := domain.SessionFilter{
+		UserID:    c.Query("user_id"),
+		ClientIP:  c.Query("ip"),
+		UserAgent: c.Query("user_agent"),
+	}
+
+	if filter.UserID != "" {
+		if _, err := uuid.Parse(filter.UserID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Límite inválido"})
+			return
+		}
+		filter.Limit = value
+	}
+	if offset := c.Query("offset"); offset != "" {
+		value, err := strconv.Atoi(offset)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Desplazamiento inválido"})
+			return
+		}
+		filter.Offset = value
+	}
+
+	sessions, err := h.sessionUseCase.SearchSessions(c.Request.Context(), actorFromContext(c), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// BlockSession bloquea una sesión indicando el motivo
+func (h *AdminSessionHandler) BlockSession(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere el motivo del bloqueo"})
+		return
+	}
+
+	familyID, ok := familyIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.sessionUseCase.BlockSession(c.Request.Context(), actorFromContext(c), familyID, req.Reason)
+	if err != nil {
+		h.handleSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sesión bloqueada exitosamente"})
+}
+
+// UnblockSession desbloquea una sesión
+func (h *AdminSessionHandler) UnblockSession(c *gin.Context) {
+	familyID, ok := familyIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.sessionUseCase.UnblockSession(c.Request.Context(), actorFromContext(c), familyID); err != nil {
+		h.handleSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sesión desbloqueada exitosamente"})
+}
+
+// ForceLogoutUser cierra todas las sesiones de un usuario
+func (h *AdminSessionHandler) ForceLogoutUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.sessionUseCase.ForceLogoutUser(c.Request.Context(), actorFromContext(c), id.String()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Todas las sesiones del usuario fueron cerradas"})
+}
+
+// familyIDParam lee el family_id de la ruta. Si no es un UUID responde 400 y devuelve false.
+func familyIDParam(c *gin.Context) (string, bool) {
+	familyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de sesión inválido"})
+		return "", false
+	}
+	return familyID.String(), true
+}
+
+func (h *AdminSessionHandler) handleSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecases.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sesión no encontrada"})
+	case errors.Is(err, usecases.ErrBlockReasonMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"errors"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
 	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+	"github.com/kevinhc2110/Auth_UCP/pck/validation"
 )
 
 type AuthHandler struct {
@@ -18,11 +22,18 @@ func NewAuthHandler(authUseCase *usecases.AuthUseCase) *AuthHandler {
 	return &AuthHandler{authUseCase: authUseCase}
 }
 
-// Login maneja la autenticación del usuario y genera tokens
+// Login maneja la autenticación del usuario y genera tokens. El cliente elige
+// el método: "password" (por defecto), con el correo o con el documento de
+// identidad, o "email_otp", que sin código envía uno al correo y con código
+// completa el inicio de sesión.
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required"`
+		Method         string `json:"method"`
+		Email          string `json:"email"`
+		DocumentType   string `json:"document_type"`
+		Identification string `json:"identification"`
+		Password       string `json:"password"`
+		Code           string `json:"code"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -33,23 +44,162 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	userAgent := c.GetHeader("User-Agent")
 	clientIP := c.ClientIP()
 
-	session, accessToken, err := h.authUseCase.Authenticate(c.Request.Context(), req.Email, req.Password, userAgent, clientIP)
+	var result *usecases.AuthResult
+	var err error
+	switch req.Method {
+	case "", domain.LoginMethodPassword:
+		identifier := domain.LoginIdentifier{Email: req.Email, DocumentType: req.DocumentType, Identification: req.Identification}
+		if err := validation.ValidateLoginIdentifier(identifier); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Password == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+			return
+		}
+		result, err = h.authUseCase.Authenticate(c.Request.Context(), identifier, req.Password, userAgent, clientIP)
+	case domain.LoginMethodEmailOTP:
+		if validation.ValidateLoginIdentifier(domain.LoginIdentifier{Email: req.Email}) != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+			return
+		}
+		if req.Code == "" {
+			h.authUseCase.RequestEmailCode(c.Request.Context(), req.Email, userAgent, clientIP)
+			c.JSON(http.StatusAccepted, gin.H{
+				"code_sent": true,
+				"message":   "Si el correo está registrado, recibirás un código para iniciar sesión",
+			})
+			return
+		}
+		result, err = h.authUseCase.AuthenticateEmailCode(c.Request.Context(), req.Email, req.Code, userAgent, clientIP)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Método de inicio de sesión no soportado"})
+		return
+	}
 	if err != nil {
-		if errors.Is(err, usecases.ErrInvalidCredentials) {
+		var throttled *usecases.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, usecases.ErrInvalidCredentials) ||
+			errors.Is(err, usecases.ErrInvalidEmailOTP) ||
+			errors.Is(err, usecases.ErrTooManyEmailOTPAttempts) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, usecases.ErrEmailNotVerified) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "email_verification_required": true})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"access_token":  accessToken,
-		"refresh_token": session.RefreshToken,
-	})
+	writeAuthResult(c, result)
+}
+
+// LoginMFA completa el inicio de sesión con el segundo factor
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Method   string `json:"method"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	userAgent := c.GetHeader("User-Agent")
+	clientIP := c.ClientIP()
+
+	result, err := h.authUseCase.VerifyMFA(c.Request.Context(), req.MFAToken, req.Method, req.Code, userAgent, clientIP)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecases.ErrInvalidMFACode),
+			errors.Is(err, usecases.ErrInvalidMFAChallenge),
+			errors.Is(err, usecases.ErrTooManyMFAAttempts),
+			errors.Is(err, usecases.ErrTooManyEmailOTPAttempts):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, usecases.ErrUnsupportedMFA):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	writeAuthResult(c, result)
 }
 
-// RefreshToken renueva el token de acceso
+// SendMFAEmailCode envía por correo el código de respaldo de un reto MFA pendiente
+func (h *AuthHandler) SendMFAEmailCode(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	err := h.authUseCase.SendMFAEmailCode(c.Request.Context(), req.MFAToken, c.GetHeader("User-Agent"), c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, usecases.ErrInvalidMFAChallenge),
+			errors.Is(err, usecases.ErrTooManyMFAAttempts):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, usecases.ErrUnsupportedMFA):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, usecases.ErrEmailOTPThrottled):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Te enviamos un código a tu correo"})
+}
+
+// writeAuthResult responde con los tokens emitidos o con el reto MFA pendiente
+func writeAuthResult(c *gin.Context, result *usecases.AuthResult) {
+	if result.MFAToken != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    result.MFAToken,
+			"mfa_methods":  result.MFAMethods,
+		})
+		return
+	}
+
+	// El token de registro solo sirve para configurar el segundo factor; tras
+	// hacerlo el usuario inicia sesión de nuevo
+	if result.EnrollmentToken != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_enrollment_required": true,
+			"enrollment_token":        result.EnrollmentToken,
+		})
+		return
+	}
+
+	response := gin.H{
+		"access_token":  result.AccessToken,
+		"refresh_token": result.Session.RefreshToken,
+	}
+	if result.RecoveryCodesRemaining != nil {
+		response["recovery_codes_remaining"] = *result.RecoveryCodesRemaining
+		if result.RecoveryCodesLow {
+			response["warning"] = "Te quedan pocos códigos de recuperación, genera un nuevo juego"
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RefreshToken renueva el token de acceso y rota el refresh token
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req struct {
 		RefreshToken string `json:"refresh_token" binding:"required"`
@@ -60,21 +210,39 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	newToken, err := h.authUseCase.RefreshToken(c.Request.Context(), req.RefreshToken)
+	userAgent := c.GetHeader("User-Agent")
+	clientIP := c.ClientIP()
+
+	session, accessToken, err := h.authUseCase.RefreshToken(c.Request.Context(), req.RefreshToken, userAgent, clientIP)
 	if err != nil {
 		if errors.Is(err, usecases.ErrSessionExpired) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "El token ha expirado"})
 			return
 		}
-		if errors.Is(err, usecases.ErrInvalidToken) {
+		if errors.Is(err, usecases.ErrInvalidToken) || errors.Is(err, usecases.ErrInvalidSession) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
 			return
 		}
+		if errors.Is(err, usecases.ErrSessionBlocked) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "La sesión está bloqueada"})
+			return
+		}
+		if errors.Is(err, usecases.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reutilizado, inicia sesión nuevamente"})
+			return
+		}
+		if errors.Is(err, usecases.ErrMFAEnrollmentRequired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "mfa_enrollment_required": true})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"access_token": newToken})
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  accessToken,
+		"refresh_token": session.RefreshToken,
+	})
 }
 
 // Logout cierra la sesión del usuario
@@ -88,7 +256,9 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	if err := h.authUseCase.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+	// El token de acceso de la petición también se revoca
+	accessToken, _ := c.MustGet("tokenClaims").(*security.JWTClaims)
+	if err := h.authUseCase.Logout(c.Request.Context(), req.RefreshToken, accessToken); err != nil {
 		if errors.Is(err, usecases.ErrSessionNotFound) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sesión no encontrada"})
 			return
@@ -99,13 +269,3 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Sesión cerrada exitosamente"})
 }
-
-// Exponer clave publica
-func PublicKeyHandler(c *gin.Context) {
-	pubKey, err := os.ReadFile("public.pem")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.Data(http.StatusOK, "application/x-pem-file", pubKey)
-}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+// EmailVerificationHandler gestiona la confirmación del correo de las cuentas nuevas
+type EmailVerificationHandler struct {
+	verificationUseCase *usecases.EmailVerificationUseCase
+}
+
+// NewEmailVerificationHandler crea una nueva instancia de EmailVerificationHandler
+func NewEmailVerificationHandler(verificationUseCase *usecases.EmailVerificationUseCase) *EmailVerificationHandler {
+	return &EmailVerificationHandler{verificationUseCase: verificationUseCase}
+}
+
+// VerifyEmail confirma el correo con el token del enlace enviado
+func (h *EmailVerificationHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token requerido"})
+		return
+	}
+
+	if err := h.verificationUseCase.VerifyEmail(c.Request.Context(), token, c.GetHeader("User-Agent"), c.ClientIP()); err != nil {
+		if errors.Is(err, usecases.ErrInvalidVerificationLink) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Correo verificado. Ya puedes iniciar sesión"})
+}
+
+// ResendVerification solicita un nuevo enlace de verificación. Responde igual
+// exista o no la cuenta.
+func (h *EmailVerificationHandler) ResendVerification(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email inválido"})
+		return
+	}
+
+	h.verificationUseCase.ResendVerification(c.Request.Context(), req.Email)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Si la cuenta existe y no está verificada, recibirás un nuevo enlace",
+	})
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+// LoginLockoutHandler expone el desbloqueo de cuentas para administradores
+type LoginLockoutHandler struct {
+	lockoutUseCase *usecases.LoginLockoutUseCase
+}
+
+// NewLoginLockoutHandler crea una nueva instancia de LoginLockoutHandler
+func NewLoginLockoutHandler(lockoutUseCase *usecases.LoginLockoutUseCase) *LoginLockoutHandler {
+	return &LoginLockoutHandler{lockoutUseCase: lockoutUseCase}
+}
+
+// UnlockUser desbloquea una cuenta bloqueada por intentos fallidos
+func (h *LoginLockoutHandler) UnlockUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.lockoutUseCase.Unlock(c.Request.Context(), actorFromContext(c), id); err != nil {
+		if errors.Is(err, usecases.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cuenta desbloqueada"})
+}
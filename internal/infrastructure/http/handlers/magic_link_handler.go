@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+const (
+	magicLinkCookie     = "magic_link_nonce"
+	magicLinkCookiePath = "/api/login/magic-link"
+)
+
+// MagicLinkHandler gestiona el inicio de sesión con enlaces enviados por correo
+type MagicLinkHandler struct {
+	magicLinkUseCase *usecases.MagicLinkUseCase
+	authUseCase      *usecases.AuthUseCase
+	secureCookies    bool
+}
+
+// NewMagicLinkHandler crea una nueva instancia de MagicLinkHandler. Con
+// secureCookies la cookie del nonce solo viaja por HTTPS.
+func NewMagicLinkHandler(magicLinkUseCase *usecases.MagicLinkUseCase, authUseCase *usecases.AuthUseCase, secureCookies bool) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkUseCase: magicLinkUseCase,
+		authUseCase:      authUseCase,
+		secureCookies:    secureCookies,
+	}
+}
+
+// RequestLink envía el enlace de inicio de sesión y guarda en una cookie el
+// nonce que ata el enlace a este navegador. Responde igual exista o no el correo.
+func (h *MagicLinkHandler) RequestLink(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email inválido"})
+		return
+	}
+
+	nonce, err := h.magicLinkUseCase.RequestLink(c.Request.Context(), req.Email, c.GetHeader("User-Agent"), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(magicLinkCookie, nonce, int(usecases.MagicLinkDuration.Seconds()), magicLinkCookiePath, "", h.secureCookies, true)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Si el correo está registrado, recibirás un enlace para iniciar sesión. Ábrelo en este mismo navegador",
+	})
+}
+
+// VerifyLink completa el inicio de sesión con el token del enlace
+func (h *MagicLinkHandler) VerifyLink(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token requerido"})
+		return
+	}
+
+	nonce, _ := c.Cookie(magicLinkCookie)
+	result, err := h.authUseCase.AuthenticateMagicLink(c.Request.Context(), req.Token, nonce, c.GetHeader("User-Agent"), c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, usecases.ErrInvalidMagicLink):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, usecases.ErrMagicLinkOtherAgent):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// El nonce ya no sirve: el enlace quedó consumido
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(magicLinkCookie, "", -1, magicLinkCookiePath, "", h.secureCookies, true)
+
+	writeAuthResult(c, result)
+}
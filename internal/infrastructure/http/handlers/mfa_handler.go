@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+// MFAHandler gestiona el enrolamiento del segundo factor del usuario autenticado
+type MFAHandler struct {
+	mfaUseCase *usecases.MFAUseCase
+}
+
+// NewMFAHandler crea una nueva instancia de MFAHandler
+func NewMFAHandler(mfaUseCase *usecases.MFAUseCase) *MFAHandler {
+	return &MFAHandler{mfaUseCase: mfaUseCase}
+}
+
+// EnrollTOTP genera el secreto TOTP y el URI otpauth:// para el código QR
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	secret, uri, err := h.mfaUseCase.EnrollTOTP(c.Request.Context(), userID)
+	if err != nil {
+		h.handleMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	})
+}
+
+// ConfirmTOTP activa el segundo factor con el primer código de la aplicación autenticadora
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código requerido"})
+		return
+	}
+
+	recoveryCodes, err := h.mfaUseCase.ConfirmTOTP(c.Request.Context(), actorFromContext(c), userID, req.Code)
+	if err != nil {
+		h.handleMFAError(c, err)
+		return
+	}
+
+	// Los códigos solo se emiten si es el primer factor del usuario
+	if len(recoveryCodes) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "Segundo factor activado exitosamente"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Segundo factor activado exitosamente. Guarda los códigos de recuperación, no se volverán a mostrar",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableTOTP desactiva el segundo factor previa validación de un código
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código requerido"})
+		return
+	}
+
+	if err := h.mfaUseCase.DisableTOTP(c.Request.Context(), actorFromContext(c), userID, req.Code); err != nil {
+		h.handleMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Segundo factor desactivado"})
+}
+
+// RegenerateRecoveryCodes reemplaza los códigos de recuperación del usuario
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Contraseña requerida"})
+		return
+	}
+
+	recoveryCodes, err := h.mfaUseCase.RegenerateRecoveryCodes(c.Request.Context(), actorFromContext(c), userID, req.Password)
+	if err != nil {
+		h.handleMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Códigos de recuperación regenerados. Los anteriores dejaron de funcionar",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// RecoveryCodesStatus informa cuántos códigos de recuperación quedan sin usar
+func (h *MFAHandler) RecoveryCodesStatus(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	remaining, err := h.mfaUseCase.RecoveryCodesRemaining(c.Request.Context(), userID)
+	if err != nil {
+		h.handleMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"remaining": remaining})
+}
+
+func (h *MFAHandler) handleMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecases.ErrInvalidMFACode), errors.Is(err, usecases.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, usecases.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, usecases.ErrMFANotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecases.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
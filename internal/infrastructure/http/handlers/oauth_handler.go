@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+// OAuthHandler expone los endpoints del servidor de autorización OAuth 2.0
+type OAuthHandler struct {
+	oauthUseCase *usecases.OAuthUseCase
+	loginURL     string
+}
+
+// NewOAuthHandler crea una nueva instancia de OAuthHandler. loginURL es la
+// página del frontend donde el usuario inicia sesión y aprueba la petición.
+func NewOAuthHandler(oauthUseCase *usecases.OAuthUseCase, loginURL string) *OAuthHandler {
+	return &OAuthHandler{oauthUseCase: oauthUseCase, loginURL: loginURL}
+}
+
+// authorizationRequest son los parámetros de autorización, tanto en la query de
+// /authorize como en el cuerpo de la aprobación
+type authorizationRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Nonce               string `form:"nonce" json:"nonce"`
+}
+
+func (r authorizationRequest) toUseCase() usecases.AuthorizationRequest {
+	return usecases.AuthorizationRequest{
+		ResponseType:        r.ResponseType,
+		ClientID:            r.ClientID,
+		RedirectURI:         r.RedirectURI,
+		Scope:               r.Scope,
+		State:               r.State,
+		CodeChallenge:       r.CodeChallenge,
+		CodeChallengeMethod: r.CodeChallengeMethod,
+		Nonce:               r.Nonce,
+	}
+}
+
+// Authorize valida la petición de autorización y envía el navegador a la
+// página de inicio de sesión con los mismos parámetros
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var req authorizationRequest
+	_ = c.ShouldBindQuery(&req)
+
+	if _, _, err := h.oauthUseCase.ValidateAuthorizationRequest(c.Request.Context(), req.toUseCase()); err != nil {
+		h.authorizationError(c, req, err, func(target string) { c.Redirect(http.StatusFound, target) })
+		return
+	}
+
+	c.Redirect(http.StatusFound, usecases.OAuthRedirectURL(h.loginURL, c.Request.URL.Query()))
+}
+
+// AuthorizationInfo devuelve el cliente y los scopes de una petición de
+// autorización, para la pantalla de consentimiento
+func (h *OAuthHandler) AuthorizationInfo(c *gin.Context) {
+	var req authorizationRequest
+	_ = c.ShouldBindQuery(&req)
+
+	client, scopes, err := h.oauthUseCase.ValidateAuthorizationRequest(c.Request.Context(), req.toUseCase())
+	if err != nil {
+		h.authorizationError(c, req, err, func(target string) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "redirect_to": target})
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"client": gin.H{"client_id": client.ClientID, "name": client.Name},
+		"scopes": scopes,
+	})
+}
+
+// Approve registra la decisión del usuario autenticado y devuelve la URL de
+// retorno al cliente con el código de autorización o con access_denied
+func (h *OAuthHandler) Approve(c *gin.Context) {
+	var req struct {
+		authorizationRequest
+		Approve bool `json:"approve"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	respond := func(target string) { c.JSON(http.StatusOK, gin.H{"redirect_to": target}) }
+
+	if !req.Approve {
+		if _, _, err := h.oauthUseCase.ValidateAuthorizationRequest(c.Request.Context(), req.toUseCase()); err != nil {
+			h.authorizationError(c, req.authorizationRequest, err, respond)
+			return
+		}
+		h.authorizationError(c, req.authorizationRequest, usecases.ErrOAuthAccessDenied, respond)
+		return
+	}
+
+	target, err := h.oauthUseCase.Authorize(c.Request.Context(), actorFromContext(c), req.toUseCase())
+	if err != nil {
+		h.authorizationError(c, req.authorizationRequest, err, respond)
+		return
+	}
+	respond(target)
+}
+
+// authorizationError informa un error de autorización. Si la redirect_uri es de
+// confianza, el error se devuelve al cliente a través de ella (RFC 6749,
+// sección 4.1.2.1); si no, se responde directamente.
+func (h *OAuthHandler) authorizationError(c *gin.Context, req authorizationRequest, err error, redirect func(target string)) {
+	var oauthErr *usecases.OAuthError
+	switch {
+	case errors.As(err, &oauthErr):
+		redirect(usecases.OAuthRedirectURL(req.RedirectURI, url.Values{
+			"error":             {oauthErr.Code},
+			"error_description": {oauthErr.Description},
+			"state":             {req.State},
+		}))
+	case errors.Is(err, usecases.ErrOAuthClientNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_client", "error_description": err.Error()})
+	case errors.Is(err, usecases.ErrOAuthInvalidRedirectURI):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error", "error_description": err.Error()})
+	}
+}
+
+// Token emite un token de acceso a cambio de un código de autorización o, para
+// los servicios, de sus credenciales de cliente. El cliente se autentica con HTTP Basic o con client_id y client_secret en el formulario.
+func (h *OAuthHandler) Token(c *gin.Context) {
+	clientID, clientSecret, basicAuth := clientCredentials(c)
+	req := usecases.OAuthTokenRequest{
+		GrantType:    c.PostForm("grant_type"),
+		Scope:        c.PostForm("scope"),
+		Code:         c.PostForm("code"),
+		RedirectURI:  c.PostForm("redirect_uri"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		CodeVerifier: c.PostForm("code_verifier"),
+	}
+
+	// Las respuestas con tokens no deben guardarse en cachés
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	result, err := h.oauthUseCase.Token(c.Request.Context(), req)
+	if err != nil {
+		writeOAuthError(c, err, basicAuth)
+		return
+	}
+
+	response := gin.H{
+		"access_token": result.AccessToken,
+		"token_type":   result.TokenType,
+		"expires_in":   result.ExpiresIn,
+	}
+	if result.Scope != "" {
+		response["scope"] = result.Scope
+	}
+	if result.IDToken != "" {
+		response["id_token"] = result.IDToken
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Revoke revoca un token de acceso emitido para el cliente (RFC 7009). La
+// respuesta es 200 aunque el token no exista o ya no sea válido.
+func (h *OAuthHandler) Revoke(c *gin.Context) {
+	clientID, clientSecret, basicAuth := clientCredentials(c)
+	req := usecases.OAuthRevocationRequest{
+		Token:         c.PostForm("token"),
+		TokenTypeHint: c.PostForm("token_type_hint"),
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+	}
+
+	if err := h.oauthUseCase.Revoke(c.Request.Context(), req); err != nil {
+		writeOAuthError(c, err, basicAuth)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// Introspect informa si un token está activo y, en ese caso, a quién
+// pertenece (RFC 7662). Solo para clientes confidenciales.
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	clientID, clientSecret, basicAuth := clientCredentials(c)
+	req := usecases.OAuthIntrospectionRequest{
+		Token:         c.PostForm("token"),
+		TokenTypeHint: c.PostForm("token_type_hint"),
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	result, err := h.oauthUseCase.Introspect(c.Request.Context(), req)
+	if err != nil {
+		writeOAuthError(c, err, basicAuth)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// clientCredentials lee las credenciales del cliente de HTTP Basic o, si no
+// vienen ahí, de client_id y client_secret en el formulario
+func clientCredentials(c *gin.Context) (clientID, clientSecret string, basicAuth bool) {
+	clientID, clientSecret, basicAuth = c.Request.BasicAuth()
+	if !basicAuth {
+		return c.PostForm("client_id"), c.PostForm("client_secret"), false
+	}
+	// RFC 6749, sección 2.3.1: las credenciales van codificadas como formulario
+	clientID, _ = url.QueryUnescape(clientID)
+	clientSecret, _ = url.QueryUnescape(clientSecret)
+	return clientID, clientSecret, true
+}
+
+// UserInfo devuelve los claims del usuario dueño del token de acceso (OpenID
+// Connect Core, sección 5.3). Acepta GET y POST.
+func (h *OAuthHandler) UserInfo(c *gin.Context) {
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		c.Header("WWW-Authenticate", `Bearer realm="userinfo"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_request", "error_description": "Token requerido"})
+		return
+	}
+
+	info, err := h.oauthUseCase.UserInfo(c.Request.Context(), strings.TrimPrefix(authHeader, "Bearer "))
+	if err != nil {
+		var oauthErr *usecases.OAuthError
+		if !errors.As(err, &oauthErr) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error", "error_description": err.Error()})
+			return
+		}
+		status := http.StatusUnauthorized
+		if oauthErr.Code == usecases.ErrOAuthInsufficientScope.Code {
+			status = http.StatusForbidden
+		}
+		c.Header("WWW-Authenticate", `Bearer error="`+oauthErr.Code+`"`)
+		c.JSON(status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, info)
+}
+
+// Discovery publica el documento de descubrimiento de OpenID Connect
+func (h *OAuthHandler) Discovery(c *gin.Context) {
+	issuer := strings.TrimSuffix(h.oauthUseCase.Issuer(), "/")
+	c.JSON(http.StatusOK, gin.H{
+		"issuer":                                        issuer,
+		"authorization_endpoint":                        issuer + "/authorize",
+		"token_endpoint":                                issuer + "/token",
+		"userinfo_endpoint":                             issuer + "/userinfo",
+		"revocation_endpoint":                           issuer + "/revoke",
+		"introspection_endpoint":                        issuer + "/introspect",
+		"jwks_uri":                                      issuer + "/.well-known/jwks.json",
+		"response_types_supported":                      []string{usecases.OAuthResponseTypeCode},
+		"grant_types_supported":                         []string{usecases.OAuthGrantAuthorizationCode, usecases.OAuthGrantClientCredentials},
+		"subject_types_supported":                       []string{"public"},
+		"id_token_signing_alg_values_supported":         security.SupportedAlgorithms(),
+		"scopes_supported":                              domain.OIDCScopes,
+		"token_endpoint_auth_methods_supported":         []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":              []string{security.PKCEMethodS256},
+		"revocation_endpoint_auth_methods_supported":    []string{"client_secret_basic", "client_secret_post", "none"},
+		"introspection_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"claims_supported": []string{
+			"iss", "sub", "aud", "exp", "iat", "nonce",
+			"email", "email_verified", "name", "family_name", "role",
+		},
+	})
+}
+
+// JWKS publica las claves públicas con las que se verifican los tokens
+func (h *OAuthHandler) JWKS(c *gin.Context) {
+	// Los verificadores pueden cachear las claves unos minutos
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, security.PublicJWKS())
+}
+
+// writeOAuthError responde un error del endpoint /token (RFC 6749, sección 5.2)
+func writeOAuthError(c *gin.Context, err error, basicAuth bool) {
+	var oauthErr *usecases.OAuthError
+	if !errors.As(err, &oauthErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error", "error_description": err.Error()})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == usecases.ErrOAuthInvalidClient.Code {
+		status = http.StatusUnauthorized
+		if basicAuth {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+	}
+	c.JSON(status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+}
+
+// RegisterClient registra un cliente OAuth. El secreto de los clientes
+// confidenciales solo se muestra en esta respuesta.
+func (h *OAuthHandler) RegisterClient(c *gin.Context) {
+	var req struct {
+		Name         string   `json:"name" binding:"required"`
+		Type         string   `json:"type" binding:"required"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Scopes       []string `json:"scopes"`
+		GrantTypes   []string `json:"grant_types"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	client, secret, err := h.oauthUseCase.RegisterClient(c.Request.Context(), actorFromContext(c), usecases.OAuthClientInput{
+		Name:         req.Name,
+		Type:         req.Type,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		GrantTypes:   req.GrantTypes,
+	})
+	if err != nil {
+		if errors.Is(err, usecases.ErrInvalidOAuthClient) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"client": client}
+	if secret != "" {
+		response["client_secret"] = secret
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListClients lista los clientes OAuth registrados
+func (h *OAuthHandler) ListClients(c *gin.Context) {
+	clients, err := h.oauthUseCase.ListClients(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"clients": clients})
+}
+
+// DeleteClient elimina un cliente OAuth
+func (h *OAuthHandler) DeleteClient(c *gin.Context) {
+	if err := h.oauthUseCase.DeleteClient(c.Request.Context(), actorFromContext(c), c.Param("client_id")); err != nil {
+		if errors.Is(err, usecases.ErrOAuthClientNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cliente eliminado exitosamente"})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+// PasswordHandler gestiona la recuperación de contraseñas olvidadas
+type PasswordHandler struct {
+	resetUseCase *usecases.PasswordResetUseCase
+}
+
+// NewPasswordHandler crea una nueva instancia de PasswordHandler
+func NewPasswordHandler(resetUseCase *usecases.PasswordResetUseCase) *PasswordHandler {
+	return &PasswordHandler{resetUseCase: resetUseCase}
+}
+
+// ForgotPassword solicita el envío de un enlace de restablecimiento. Responde
+// igual exista o no el correo.
+func (h *PasswordHandler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email inválido"})
+		return
+	}
+
+	h.resetUseCase.RequestReset(c.Request.Context(), req.Email, c.GetHeader("User-Agent"), c.ClientIP())
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Si el correo está registrado, recibirás un enlace para restablecer tu contraseña",
+	})
+}
+
+// ResetPassword establece una nueva contraseña con el token recibido por correo
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	err := h.resetUseCase.ResetPassword(c.Request.Context(), req.Token, req.Password, c.GetHeader("User-Agent"), c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, usecases.ErrInvalidResetToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, usecases.ErrWeakPassword):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contraseña restablecida. Inicia sesión con tu nueva contraseña"})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+// SessionHandler permite al usuario gestionar sus propias sesiones
+type SessionHandler struct {
+	sessionUseCase *usecases.SessionUseCase
+}
+
+// NewSessionHandler crea una nueva instancia de SessionHandler
+func NewSessionHandler(sessionUseCase *usecases.SessionUseCase) *SessionHandler {
+	return &SessionHandler{sessionUseCase: sessionUseCase}
+}
+
+// sessionResponse es la vista pública de una sesión. El ID expuesto es el de la
+// familia, que se mantiene estable aunque el refresh token se rote.
+type sessionResponse struct {
+	ID        string    `json:"id"`
+	UserAgent string    `json:"user_agent"`
+	ClientIP  string    `json:"client_ip"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+}
+
+// ListSessions lista las sesiones activas del usuario autenticado
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+	currentSessionID := c.GetString("sessionID")
+
+	sessions, err := h.sessionUseCase.ListUserSessions(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, sessionResponse{
+			ID:        session.FamilyID,
+			UserAgent: session.UserAgent,
+			ClientIP:  session.ClientIP,
+			CreatedAt: session.CreatedAt,
+			UpdatedAt: session.UpdatedAt,
+			ExpiresAt: session.ExpiresAt,
+			Current:   session.FamilyID == currentSessionID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": response})
+}
+
+// RevokeSession cierra una sesión específica del usuario autenticado
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	if err := h.sessionUseCase.RevokeUserSession(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if errors.Is(err, usecases.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sesión no encontrada"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sesión cerrada exitosamente"})
+}
+
+// RevokeOtherSessions cierra todas las sesiones del usuario excepto la actual
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	err := h.sessionUseCase.RevokeOtherSessions(c.Request.Context(), userID, c.GetString("sessionID"))
+	if err != nil {
+		if errors.Is(err, usecases.ErrInvalidSession) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El token no está asociado a una sesión, inicia sesión nuevamente"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Las demás sesiones fueron cerradas"})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+// SigningKeyHandler expone la gestión de las claves de firma de tokens para administradores
+type SigningKeyHandler struct {
+	signingKeyUseCase *usecases.SigningKeyUseCase
+}
+
+// NewSigningKeyHandler crea una nueva instancia de SigningKeyHandler
+func NewSigningKeyHandler(signingKeyUseCase *usecases.SigningKeyUseCase) *SigningKeyHandler {
+	return &SigningKeyHandler{signingKeyUseCase: signingKeyUseCase}
+}
+
+// ListKeys lista la clave activa y las retiradas que siguen publicadas
+func (h *SigningKeyHandler) ListKeys(c *gin.Context) {
+	keys, err := h.signingKeyUseCase.ListKeys(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// RotateKeys genera una nueva clave activa sin invalidar los tokens vigentes
+func (h *SigningKeyHandler) RotateKeys(c *gin.Context) {
+	key, err := h.signingKeyUseCase.Rotate(c.Request.Context(), actorFromContext(c))
+	if err != nil {
+		if errors.Is(err, usecases.ErrSigningKeyRotated) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Clave de firma rotada exitosamente", "key": key})
+}
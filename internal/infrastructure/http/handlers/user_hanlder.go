@@ -30,7 +30,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	}
 
 	if err := h.userUseCase.CreateUser(c.Request.Context(), &user); err != nil {
-		if errors.Is(err, usecases.ErrEmailAlreadyExists) {
+		if errors.Is(err, usecases.ErrEmailAlreadyExists) || errors.Is(err, usecases.ErrIdentificationAlreadyExists) {
 			c.JSON(http.StatusConflict, gin.H{"error": "El usuario ya existe"})
 			return
 		}
@@ -41,11 +41,12 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "Usuario creado exitosamente", "user": user})
 }
 
-// GetUserByIdentification maneja la solicitud para obtener un usuario por identificación
+// GetUserByIdentification maneja la solicitud para obtener un usuario por tipo y número de documento
 func (h *UserHandler) GetUserByIdentification(c *gin.Context) {
+	documentType := c.Param("document_type")
 	identification := c.Param("identification")
 
-	user, err := h.userUseCase.GetUserByIdentification(c.Request.Context(), identification)
+	user, err := h.userUseCase.GetUserByIdentification(c.Request.Context(), documentType, identification)
 	if err != nil {
 		if errors.Is(err, usecases.ErrUserNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
@@ -96,8 +97,9 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	// Verificar si el usuario existe antes de actualizarlo
-	existingUser, err := h.userUseCase.GetUserByIdentification(c.Request.Context(), user.Identification)
+	// Verificar si el usuario existe antes de actualizarlo. Se busca por el ID
+	// del token: el documento del cuerpo es el nuevo y puede no traer el tipo
+	existingUser, err := h.userUseCase.GetUserByID(c.Request.Context(), user.ID)
 	if err != nil {
 		if errors.Is(err, usecases.ErrUserNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado, no se puede actualizar"})
@@ -111,6 +113,10 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	user.ID = existingUser.ID
 
 	if err := h.userUseCase.UpdateUser(c.Request.Context(), &user); err != nil {
+		if errors.Is(err, usecases.ErrEmailAlreadyExists) || errors.Is(err, usecases.ErrIdentificationAlreadyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security/webauthn"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
+)
+
+// WebAuthnHandler gestiona el registro de llaves de acceso y el inicio de sesión con ellas
+type WebAuthnHandler struct {
+	webauthnUseCase *usecases.WebAuthnUseCase
+	authUseCase     *usecases.AuthUseCase
+}
+
+// NewWebAuthnHandler crea una nueva instancia de WebAuthnHandler
+func NewWebAuthnHandler(webauthnUseCase *usecases.WebAuthnUseCase, authUseCase *usecases.AuthUseCase) *WebAuthnHandler {
+	return &WebAuthnHandler{webauthnUseCase: webauthnUseCase, authUseCase: authUseCase}
+}
+
+// publicKeyCredential es la credencial que devuelve el navegador, serializada
+// con PublicKeyCredential.toJSON() (campos binarios en base64url)
+type publicKeyCredential struct {
+	ID       string `json:"id" binding:"required"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type" binding:"required,eq=public-key"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+		AuthenticatorData string   `json:"authenticatorData"`
+		Signature         string   `json:"signature"`
+		UserHandle        string   `json:"userHandle"`
+	} `json:"response" binding:"required"`
+}
+
+// attestation decodifica la respuesta de navigator.credentials.create()
+func (p *publicKeyCredential) attestation() (webauthn.AttestationResponse, error) {
+	clientData, err := webauthn.DecodeBase64URL(p.Response.ClientDataJSON)
+	if err != nil {
+		return webauthn.AttestationResponse{}, err
+	}
+	object, err := webauthn.DecodeBase64URL(p.Response.AttestationObject)
+	if err != nil || len(object) == 0 {
+		return webauthn.AttestationResponse{}, errors.New("attestationObject inválido")
+	}
+	return webauthn.AttestationResponse{
+		ClientDataJSON:    clientData,
+		AttestationObject: object,
+		Transports:        p.Response.Transports,
+	}, nil
+}
+
+// assertion decodifica la respuesta de navigator.credentials.get()
+func (p *publicKeyCredential) assertion() (webauthn.AssertionResponse, error) {
+	fields := []string{p.ID, p.Response.ClientDataJSON, p.Response.AuthenticatorData, p.Response.Signature, p.Response.UserHandle}
+	decoded := make([][]byte, len(fields))
+	for i, field := range fields {
+		value, err := webauthn.DecodeBase64URL(field)
+		if err != nil {
+			return webauthn.AssertionResponse{}, err
+		}
+		decoded[i] = value
+	}
+	return webauthn.AssertionResponse{
+		CredentialID:      decoded[0],
+		ClientDataJSON:    decoded[1],
+		AuthenticatorData: decoded[2],
+		Signature:         decoded[3],
+		UserHandle:        decoded[4],
+	}, nil
+}
+
+// BeginRegistration devuelve las opciones para navigator.credentials.create()
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	challengeID, options, err := h.webauthnUseCase.BeginRegistration(c.Request.Context(), userID)
+	if err != nil {
+		h.handleWebAuthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"challenge_id": challengeID,
+		"public_key":   options,
+	})
+}
+
+// FinishRegistration verifica y guarda la credencial creada por el autenticador
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	var req struct {
+		ChallengeID string              `json:"challenge_id" binding:"required"`
+		Password    string              `json:"password" binding:"required"`
+		Nickname    string              `json:"nickname"`
+		Credential  publicKeyCredential `json:"credential" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	resp, err := req.Credential.attestation()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Credencial mal codificada"})
+		return
+	}
+
+	credential, recoveryCodes, err := h.webauthnUseCase.FinishRegistration(c.Request.Context(), actorFromContext(c), userID, req.ChallengeID, req.Password, req.Nickname, resp)
+	if err != nil {
+		h.handleWebAuthnError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"message":    "Llave de acceso registrada exitosamente",
+		"credential": credential,
+	}
+	// Los códigos solo se emiten si es el primer factor del usuario
+	if len(recoveryCodes) > 0 {
+		response["message"] = "Llave de acceso registrada exitosamente. Guarda los códigos de recuperación, no se volverán a mostrar"
+		response["recovery_codes"] = recoveryCodes
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListCredentials lista las llaves de acceso del usuario autenticado
+func (h *WebAuthnHandler) ListCredentials(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	credentials, err := h.webauthnUseCase.ListCredentials(c.Request.Context(), userID)
+	if err != nil {
+		h.handleWebAuthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"credentials": credentials})
+}
+
+// DeleteCredential elimina una llave de acceso del usuario autenticado, que
+// debe confirmar su contraseña actual
+func (h *WebAuthnHandler) DeleteCredential(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	if err := h.webauthnUseCase.DeleteCredential(c.Request.Context(), actorFromContext(c), userID, c.Param("id"), req.Password); err != nil {
+		h.handleWebAuthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Llave de acceso eliminada"})
+}
+
+// BeginLogin devuelve las opciones para navigator.credentials.get(). Con
+// mfa_token la llave se usa como segundo factor; sin él, como inicio de sesión
+// sin contraseña.
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	challengeID, options, err := h.webauthnUseCase.BeginLogin(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		h.handleWebAuthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"challenge_id": challengeID,
+		"public_key":   options,
+	})
+}
+
+// FinishLogin verifica la firma del autenticador y emite los tokens
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+	var req struct {
+		ChallengeID string              `json:"challenge_id" binding:"required"`
+		MFAToken    string              `json:"mfa_token"`
+		Credential  publicKeyCredential `json:"credential" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	resp, err := req.Credential.assertion()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Credencial mal codificada"})
+		return
+	}
+
+	userAgent := c.GetHeader("User-Agent")
+	clientIP := c.ClientIP()
+
+	result, err := h.authUseCase.AuthenticateWebAuthn(c.Request.Context(), req.ChallengeID, req.MFAToken, resp, userAgent, clientIP)
+	if err != nil {
+		h.handleWebAuthnError(c, err)
+		return
+	}
+
+	writeAuthResult(c, result)
+}
+
+func (h *WebAuthnHandler) handleWebAuthnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecases.ErrWebAuthnVerification),
+		errors.Is(err, usecases.ErrWebAuthnChallenge),
+		errors.Is(err, usecases.ErrUserVerificationRequired),
+		errors.Is(err, usecases.ErrInvalidCredentials),
+		errors.Is(err, usecases.ErrInvalidMFACode),
+		errors.Is(err, usecases.ErrInvalidMFAChallenge),
+		errors.Is(err, usecases.ErrTooManyMFAAttempts):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, usecases.ErrUnsupportedMFA):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecases.ErrWebAuthnCredentialExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, usecases.ErrWebAuthnCredentialNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usecases.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
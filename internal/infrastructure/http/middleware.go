@@ -5,11 +5,39 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/mailer"
 	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
 )
 
-// AuthMiddleware verifica el JWT antes de permitir acceso al handler
-func AuthMiddleware() gin.HandlerFunc {
+// LocaleMiddleware guarda en el contexto de la petición el idioma de
+// Accept-Language, que se usa para los correos enviados durante la petición
+func LocaleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if locale := mailer.ParseAcceptLanguage(c.GetHeader("Accept-Language")); locale != "" {
+			c.Request = c.Request.WithContext(mailer.WithLocale(c.Request.Context(), locale))
+		}
+		c.Next()
+	}
+}
+
+// AuthMiddleware verifica el JWT antes de permitir acceso al handler y
+// rechaza los tokens revocados. Deja en el contexto el principal de la
+// petición, que puede ser un usuario o un servicio (client_credentials).
+// Los tokens de registro del segundo factor no se aceptan.
+func AuthMiddleware(revocations *usecases.TokenRevocationUseCase) gin.HandlerFunc {
+	return authenticate(revocations, false)
+}
+
+// MFAEnrollmentMiddleware es AuthMiddleware para las rutas con las que se
+// configura el segundo factor, que aceptan además los tokens de registro
+// entregados a los usuarios cuyo rol lo exige
+func MFAEnrollmentMiddleware(revocations *usecases.TokenRevocationUseCase) gin.HandlerFunc {
+	return authenticate(revocations, true)
+}
+
+func authenticate(revocations *usecases.TokenRevocationUseCase, allowEnrollment bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -38,6 +66,40 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Los tokens revocados (logout, /revoke, o sesión cerrada o bloqueada) dejan
+		// de valer antes de expirar
+		if revocations.IsRevoked(claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token revocado"})
+			c.Abort()
+			return
+		}
+
+		// Los tokens emitidos para aplicaciones OAuth no dan acceso a esta API
+		if len(claims.Audience) > 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
+			c.Abort()
+			return
+		}
+
+		// Quien aún debe configurar el segundo factor solo accede a las rutas para hacerlo
+		if claims.HasScope(domain.ScopeMFAEnrollment) && !allowEnrollment {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Debes configurar un segundo factor para continuar", "mfa_enrollment_required": true})
+			c.Abort()
+			return
+		}
+
+		// Los servicios se identifican por su client_id y no tienen usuario ni rol
+		if claims.ClientID != "" {
+			c.Set("principal", &domain.Principal{
+				Type:     domain.PrincipalService,
+				ClientID: claims.ClientID,
+				Scopes:   strings.Fields(claims.Scope),
+			})
+			c.Set("tokenClaims", claims)
+			c.Next()
+			return
+		}
+
 		// Validar el rol
 		if claims.Role == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Rol no válido"})
@@ -48,8 +110,48 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Guardar los claims en el contexto para usarlos en el handler
 		c.Set("userID", claims.UserID)
 		c.Set("role", claims.Role)
+		c.Set("sessionID", claims.SessionID)
+		c.Set("tokenClaims", claims)
+		c.Set("principal", &domain.Principal{
+			Type:      domain.PrincipalUser,
+			UserID:    claims.UserID,
+			Role:      claims.Role,
+			SessionID: claims.SessionID,
+			Scopes:    strings.Fields(claims.Scope),
+		})
+
+		c.Next()
+	}
+}
+
 
+// RequireUser restringe el acceso a los usuarios, rechazando a los servicios.
+// Debe usarse después de AuthMiddleware.
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := c.MustGet("principal").(*domain.Principal)
+		if !ok || !principal.IsUser() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Esta acción requiere un usuario"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
 
+// RequireRole restringe el acceso a los usuarios autenticados con alguno de los roles indicados.
+// Debe usarse después de AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "No tienes permisos para esta acción"})
+		c.Abort()
+	}
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/ratelimit"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+)
+
+// RateLimitKey extrae de la petición el valor por el que se limita, o "" si
+// la petición no lo trae
+type RateLimitKey struct {
+	Name  string
+	Value func(c *gin.Context) string
+}
+
+var (
+	KeyByIP       = RateLimitKey{Name: "ip", Value: func(c *gin.Context) string { return c.ClientIP() }}
+	KeyByAccount  = RateLimitKey{Name: "account", Value: accountFromBody}
+	KeyByMFAToken = RateLimitKey{Name: "mfa", Value: mfaTokenFromBody}
+	KeyByClientID = RateLimitKey{Name: "client", Value: clientIDFromRequest}
+)
+
+// RateLimitRule es el límite de una ruta para un tipo de clave
+type RateLimitRule struct {
+	Key   RateLimitKey
+	Limit ratelimit.Limit
+}
+
+// RateLimit limita las peticiones a una ruta según cada regla por separado:
+// basta con que una se agote para rechazar la petición con 429. Informa el
+// límite más cercano a agotarse en las cabeceras RateLimit-*. Si el almacén
+// falla, la petición se deja pasar para no tumbar el login.
+func RateLimit(store ratelimit.Store, route string, rules ...RateLimitRule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tightest *ratelimit.Result
+		var tightestRule RateLimitRule
+		for _, rule := range rules {
+			value := rule.Key.Value(c)
+			if value == "" {
+				continue
+			}
+
+			key := route + ":" + rule.Key.Name + ":" + value
+			result, err := store.Take(c.Request.Context(), key, rule.Limit)
+			if err != nil {
+				log.Printf("Error aplicando el límite de peticiones %s: %v", key, err)
+				continue
+			}
+			if tightest == nil || tighter(result, *tightest) {
+				tightest, tightestRule = &result, rule
+			}
+		}
+		if tightest == nil {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", tightestRule.Limit.Burst, int(tightestRule.Limit.Period.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(tightest.ResetAfter))
+
+		if !tightest.Allowed {
+			c.Header("Retry-After", ceilSeconds(tightest.RetryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Demasiadas peticiones, inténtalo de nuevo más tarde"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// tighter indica si a está más cerca de agotarse que b
+func tighter(a, b ratelimit.Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// peekJSONBody decodifica el cuerpo JSON en payload y lo deja intacto para el handler
+func peekJSONBody(c *gin.Context, payload any) bool {
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return false
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(body, payload) == nil
+}
+
+// accountFromBody lee del cuerpo JSON el correo o el documento con el que se
+// inicia sesión
+func accountFromBody(c *gin.Context) string {
+	var payload struct {
+		Email          string `json:"email"`
+		DocumentType   string `json:"document_type"`
+		Identification string `json:"identification"`
+	}
+	if !peekJSONBody(c, &payload) || (payload.Email == "" && payload.Identification == "") {
+		return ""
+	}
+	identifier := domain.LoginIdentifier{Email: payload.Email, DocumentType: payload.DocumentType, Identification: payload.Identification}
+	return identifier.Key()
+}
+
+// mfaTokenFromBody identifica el reto MFA del cuerpo JSON por el hash de su
+// token, para no guardar el token en las claves de los límites
+func mfaTokenFromBody(c *gin.Context) string {
+	var payload struct {
+		MFAToken string `json:"mfa_token"`
+	}
+	if !peekJSONBody(c, &payload) || payload.MFAToken == "" {
+		return ""
+	}
+	return security.HashToken(payload.MFAToken)
+}
+
+// clientIDFromRequest identifica al cliente OAuth: el servicio autenticado por
+// AuthMiddleware, o las credenciales de cliente de la petición
+func clientIDFromRequest(c *gin.Context) string {
+	if value, ok := c.Get("principal"); ok {
+		if principal, ok := value.(*domain.Principal); ok && principal.IsService() {
+			return principal.ClientID
+		}
+	}
+	if clientID, _, ok := c.Request.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		return clientID
+	}
+	return c.PostForm("client_id")
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/ratelimit"
+)
+
+// keyRecorder es un almacén que guarda las claves consultadas y deja pasar todo
+type keyRecorder struct {
+	keys []string
+}
+
+func (s *keyRecorder) Take(_ context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	s.keys = append(s.keys, key)
+	return ratelimit.Result{Allowed: true, Limit: limit.Burst, Remaining: limit.Burst - 1}, nil
+}
+
+// rateLimitedRouter crea un router con una ruta limitada por IP
+func rateLimitedRouter(t *testing.T, trustedProxies []string, store ratelimit.Store, limit ratelimit.Limit) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router, err := newRouter(trustedProxies)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	router.POST("/login", RateLimit(store, "login", RateLimitRule{Key: KeyByIP, Limit: limit}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func sendFrom(router *gin.Engine, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.Header.Set("X-Real-IP", forwardedFor)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestKeyByIPIgnoresSpoofedForwardedFor(t *testing.T) {
+	store := &keyRecorder{}
+	router := rateLimitedRouter(t, nil, store, ratelimit.PerMinute(10))
+
+	for _, forwardedFor := range []string{"", "198.51.100.1", "198.51.100.2, 10.0.0.1"} {
+		sendFrom(router, "203.0.113.5:40000", forwardedFor)
+	}
+
+	for _, key := range store.keys {
+		if key != "login:ip:203.0.113.5" {
+			t.Fatalf("se esperaba la IP de la conexión en la clave, se obtuvo %s", key)
+		}
+	}
+	if len(store.keys) != 3 {
+		t.Fatalf("se esperaban 3 claves, se obtuvieron %d", len(store.keys))
+	}
+}
+
+func TestKeyByIPSpoofedForwardedForStillLimited(t *testing.T) {
+	router := rateLimitedRouter(t, nil, ratelimit.NewMemoryStore(), ratelimit.PerMinute(1))
+
+	if code := sendFrom(router, "203.0.113.5:40000", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("se esperaba 200, se obtuvo %d", code)
+	}
+	if code := sendFrom(router, "203.0.113.5:40001", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Fatalf("cambiar X-Forwarded-For saltó el límite: se obtuvo %d", code)
+	}
+}
+
+func TestKeyByIPTrustedProxy(t *testing.T) {
+	store := &keyRecorder{}
+	router := rateLimitedRouter(t, []string{"10.0.0.0/8"}, store, ratelimit.PerMinute(10))
+
+	sendFrom(router, "10.0.0.2:40000", "198.51.100.1")
+	sendFrom(router, "203.0.113.5:40000", "198.51.100.1")
+
+	want := []string{"login:ip:198.51.100.1", "login:ip:203.0.113.5"}
+	if len(store.keys) != len(want) {
+		t.Fatalf("se esperaban %v, se obtuvo %v", want, store.keys)
+	}
+	for i := range want {
+		if store.keys[i] != want[i] {
+			t.Fatalf("se esperaban %v, se obtuvo %v", want, store.keys)
+		}
+	}
+}
+
+func TestNewRouterRejectsInvalidProxy(t *testing.T) {
+	if _, err := newRouter([]string{"no-es-una-ip"}); err == nil {
+		t.Fatal("se esperaba un error")
+	}
+}
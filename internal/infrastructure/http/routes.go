@@ -2,29 +2,161 @@ package http
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
 	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/http/handlers"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/ratelimit"
+	"github.com/kevinhc2110/Auth_UCP/internal/usecases"
 )
 
+// Handlers agrupa los manejadores que expone la API
+type Handlers struct {
+	Auth         *handlers.AuthHandler
+	User         *handlers.UserHandler
+	Session      *handlers.SessionHandler
+	AdminSession *handlers.AdminSessionHandler
+	MFA          *handlers.MFAHandler
+	WebAuthn     *handlers.WebAuthnHandler
+	Password     *handlers.PasswordHandler
+	Verification *handlers.EmailVerificationHandler
+	MagicLink    *handlers.MagicLinkHandler
+	OAuth        *handlers.OAuthHandler
+	SigningKey   *handlers.SigningKeyHandler
+	LoginLockout *handlers.LoginLockoutHandler
+
+	// TokenRevocation es la lista de tokens revocados que consulta AuthMiddleware
+	TokenRevocation *usecases.TokenRevocationUseCase
+	// RateLimits guarda los cubos de los límites de peticiones
+	RateLimits ratelimit.Store
+}
+
 // SetupRoutes define las rutas de la API
-func SetupRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, userHandler *handlers.UserHandler) {
+func SetupRoutes(router *gin.Engine, h Handlers) {
+	// Endpoints del servidor de autorización OAuth 2.0 (RFC 6749) y de OpenID Connect
+	router.GET("/authorize", h.OAuth.Authorize)
+	router.POST("/token", RateLimit(h.RateLimits, "oauth_token",
+		RateLimitRule{Key: KeyByIP, Limit: ratelimit.PerMinute(60)},
+		RateLimitRule{Key: KeyByClientID, Limit: ratelimit.PerMinute(60)},
+	), h.OAuth.Token)
+	router.POST("/revoke", RateLimit(h.RateLimits, "oauth_revoke",
+		RateLimitRule{Key: KeyByIP, Limit: ratelimit.PerMinute(60)},
+		RateLimitRule{Key: KeyByClientID, Limit: ratelimit.PerMinute(60)},
+	), h.OAuth.Revoke)
+	// Los servidores de recursos introspectan en cada petición que reciben
+	router.POST("/introspect", RateLimit(h.RateLimits, "oauth_introspect",
+		RateLimitRule{Key: KeyByClientID, Limit: ratelimit.PerMinute(600)},
+	), h.OAuth.Introspect)
+	router.GET("/userinfo", h.OAuth.UserInfo)
+	router.POST("/userinfo", h.OAuth.UserInfo)
+	router.GET("/.well-known/openid-configuration", h.OAuth.Discovery)
+	router.GET("/.well-known/jwks.json", h.OAuth.JWKS)
+
 	api := router.Group("/api")
+	api.Use(LocaleMiddleware())
 
 	{
 		// Rutas de autenticación y usuarios
-		api.POST("/login", authHandler.Login)
-		api.POST("/register", userHandler.CreateUser)
+		api.POST("/login", RateLimit(h.RateLimits, "login",
+			RateLimitRule{Key: KeyByIP, Limit: ratelimit.PerMinute(20)},
+			RateLimitRule{Key: KeyByAccount, Limit: ratelimit.PerMinute(10)},
+		), h.Auth.Login)
+		api.POST("/login/mfa", RateLimit(h.RateLimits, "login_mfa",
+			RateLimitRule{Key: KeyByIP, Limit: ratelimit.PerMinute(20)},
+			RateLimitRule{Key: KeyByMFAToken, Limit: ratelimit.PerMinute(5)},
+		), h.Auth.LoginMFA)
+		api.POST("/login/mfa/email", RateLimit(h.RateLimits, "login_mfa_email",
+			RateLimitRule{Key: KeyByIP, Limit: ratelimit.PerMinute(10)},
+			RateLimitRule{Key: KeyByMFAToken, Limit: ratelimit.PerMinute(3)},
+		), h.Auth.SendMFAEmailCode)
+		// El código por correo se pide y se usa por /login, que ya está limitado
+		api.POST("/login/magic-link", RateLimit(h.RateLimits, "login_magic_link",
+			RateLimitRule{Key: KeyByIP, Limit: ratelimit.PerMinute(10)},
+			RateLimitRule{Key: KeyByAccount, Limit: ratelimit.PerHour(10)},
+		), h.MagicLink.RequestLink)
+		api.POST("/login/magic-link/verify", h.MagicLink.VerifyLink)
+		api.POST("/register", RateLimit(h.RateLimits, "register",
+			RateLimitRule{Key: KeyByIP, Limit: ratelimit.PerHour(10)},
+		), h.User.CreateUser)
+		// El refresh token es la credencial: se usa cuando el token de acceso ya expiró
+		api.POST("/refresh", RateLimit(h.RateLimits, "refresh",
+			RateLimitRule{Key: KeyByIP, Limit: ratelimit.PerMinute(30)},
+		), h.Auth.RefreshToken)
+
+		// Recuperación de contraseña
+		api.POST("/password/forgot", RateLimit(h.RateLimits, "password_forgot",
+			RateLimitRule{Key: KeyByIP, Limit: ratelimit.PerHour(20)},
+			RateLimitRule{Key: KeyByAccount, Limit: ratelimit.PerHour(5)},
+		), h.Password.ForgotPassword)
+		api.POST("/password/reset", h.Password.ResetPassword)
+
+		// Verificación del correo
+		api.GET("/email/verify", h.Verification.VerifyEmail)
+		api.POST("/email/verify/resend", RateLimit(h.RateLimits, "email_verify_resend",
+			RateLimitRule{Key: KeyByIP, Limit: ratelimit.PerHour(20)},
+			RateLimitRule{Key: KeyByAccount, Limit: ratelimit.PerHour(5)},
+		), h.Verification.ResendVerification)
+
+		// Inicio de sesión con llave de acceso, sin contraseña o como segundo factor
+		api.POST("/webauthn/login/begin", h.WebAuthn.BeginLogin)
+		api.POST("/webauthn/login/finish", h.WebAuthn.FinishLogin)
 
 	}
 
 	// Rutas protegidas
 	protected := api.Group("/")
-	protected.Use(AuthMiddleware()) // 🔐 Middleware aplicado
+	protected.Use(AuthMiddleware(h.TokenRevocation), RequireUser()) // 🔐 Middleware aplicado
 
 	{
-		protected.PUT("/users", userHandler.UpdateUser)
-		protected.DELETE("/users/:id", userHandler.DeleteUser)
-		protected.POST("/refresh", authHandler.RefreshToken)
-		protected.POST("/logout", authHandler.Logout)
-		protected.GET("/public-key", handlers.PublicKeyHandler)
+		protected.PUT("/users", h.User.UpdateUser)
+		protected.DELETE("/users/:id", h.User.DeleteUser)
+		protected.POST("/logout", h.Auth.Logout)
+
+		// Gestión de las sesiones del propio usuario
+		protected.GET("/sessions", h.Session.ListSessions)
+		protected.DELETE("/sessions", h.Session.RevokeOtherSessions)
+		protected.DELETE("/sessions/:id", h.Session.RevokeSession)
+
+		// Segundo factor de autenticación
+		protected.POST("/mfa/totp/disable", h.MFA.DisableTOTP)
+		protected.GET("/mfa/recovery-codes", h.MFA.RecoveryCodesStatus)
+		protected.POST("/mfa/recovery-codes", h.MFA.RegenerateRecoveryCodes)
+
+		// Llaves de acceso y llaves de seguridad
+		protected.GET("/webauthn/credentials", h.WebAuthn.ListCredentials)
+		protected.DELETE("/webauthn/credentials/:id", h.WebAuthn.DeleteCredential)
+
+		// Consentimiento de las peticiones de autorización OAuth
+		protected.GET("/oauth/authorize", h.OAuth.AuthorizationInfo)
+		protected.POST("/oauth/authorize", h.OAuth.Approve)
+	}
+
+	// Registro del segundo factor, también accesible con el token que reciben los
+	// usuarios cuyo rol lo exige y aún no lo configuraron
+	enrollment := api.Group("/")
+	enrollment.Use(MFAEnrollmentMiddleware(h.TokenRevocation), RequireUser())
+
+	{
+		enrollment.POST("/mfa/totp/enroll", h.MFA.EnrollTOTP)
+		enrollment.POST("/mfa/totp/confirm", h.MFA.ConfirmTOTP)
+		enrollment.POST("/webauthn/register/begin", h.WebAuthn.BeginRegistration)
+		enrollment.POST("/webauthn/register/finish", h.WebAuthn.FinishRegistration)
+	}
+
+	// Rutas exclusivas para administradores
+	admin := api.Group("/admin")
+	admin.Use(AuthMiddleware(h.TokenRevocation), RequireRole(string(domain.RoleAdmin)))
+
+	{
+		admin.GET("/sessions", h.AdminSession.SearchSessions)
+		admin.POST("/sessions/:id/block", h.AdminSession.BlockSession)
+		admin.POST("/sessions/:id/unblock", h.AdminSession.UnblockSession)
+		admin.DELETE("/users/:id/sessions", h.AdminSession.ForceLogoutUser)
+		admin.POST("/users/:id/unlock", h.LoginLockout.UnlockUser)
+
+		admin.GET("/oauth/clients", h.OAuth.ListClients)
+		admin.POST("/oauth/clients", h.OAuth.RegisterClient)
+		admin.DELETE("/oauth/clients/:client_id", h.OAuth.DeleteClient)
+
+		admin.GET("/keys", h.SigningKey.ListKeys)
+		admin.POST("/keys/rotate", h.SigningKey.RotateKeys)
 	}
 }
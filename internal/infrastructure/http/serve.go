@@ -1,10 +1,10 @@
 package http
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/gin-gonic/gin"
-	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/http/handlers"
 )
 
 // Server representa el servidor HTTP
@@ -12,14 +12,30 @@ type Server struct {
 	router *gin.Engine
 }
 
-// NewServer inicializa un nuevo servidor con los handlers correspondientes
-func NewServer(authHandler *handlers.AuthHandler, userHandler *handlers.UserHandler) *Server {
-	router := gin.Default()
+// NewServer inicializa un nuevo servidor con los handlers correspondientes.
+// Solo se acepta X-Forwarded-For de las peticiones que llegan desde
+// trustedProxies; con nil, c.ClientIP() es siempre la IP de la conexión.
+func NewServer(h Handlers, trustedProxies []string) (*Server, error) {
+	router, err := newRouter(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
 
 	// Registrar rutas con los handlers
-	SetupRoutes(router, authHandler, userHandler)
+	SetupRoutes(router, h)
+
+	return &Server{router: router}, nil
+}
 
-	return &Server{router: router}
+// newRouter crea el motor de gin confiando solo en los proxies indicados. Por
+// omisión gin confía en cualquiera, y la IP de los límites de peticiones y del
+// bloqueo por IP saldría de una cabecera que el cliente controla.
+func newRouter(trustedProxies []string) (*gin.Engine, error) {
+	router := gin.Default()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("error al configurar los proxies de confianza: %w", err)
+	}
+	return router, nil
 }
 
 // Run inicia el servidor en el puerto especificado
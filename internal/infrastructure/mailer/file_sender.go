@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileSender guarda cada correo como un archivo .eml en un directorio
+type FileSender struct {
+	dir string
+}
+
+// NewFileSender crea un FileSender, creando el directorio si no existe
+func NewFileSender(dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("error al crear el directorio de correos: %w", err)
+	}
+	return &FileSender{dir: dir}, nil
+}
+
+// Send escribe el correo en un archivo nuevo
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405"), uuid.New().String())
+	if err := os.WriteFile(filepath.Join(s.dir, name), buildMessage("", msg), 0o640); err != nil {
+		return fmt.Errorf("error al guardar el correo: %w", err)
+	}
+	return nil
+}
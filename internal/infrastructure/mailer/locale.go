@@ -0,0 +1,28 @@
+package mailer
+
+import (
+	"context"
+	"strings"
+)
+
+type localeKey struct{}
+
+// WithLocale guarda en el contexto el idioma preferido para los correos
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext devuelve el idioma guardado con WithLocale, o "" si no hay
+func LocaleFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// ParseAcceptLanguage extrae el idioma principal de una cabecera
+// Accept-Language, por ejemplo "en" de "en-US,en;q=0.9,es;q=0.8"
+func ParseAcceptLanguage(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	tag, _, _ := strings.Cut(first, ";")
+	primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	return strings.ToLower(primary)
+}
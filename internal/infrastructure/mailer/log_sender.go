@@ -0,0 +1,21 @@
+package mailer
+
+import (
+	"context"
+	"log"
+)
+
+// LogSender escribe en el log que se envió un correo en lugar de enviarlo. El
+// cuerpo no se escribe porque lleva enlaces y códigos de acceso.
+type LogSender struct{}
+
+// NewLogSender crea un nuevo LogSender
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+// Send escribe el destinatario, el asunto y la plantilla del correo en el log
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("📧 Correo para %s | %s | plantilla %s", msg.To, msg.Subject, msg.Template)
+	return nil
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaildirSender entrega los correos en un buzón Maildir local, legible por
+// clientes como mutt o por herramientas de pruebas
+type MaildirSender struct {
+	dir string
+}
+
+// NewMaildirSender crea un MaildirSender con los subdirectorios tmp, new y cur
+func NewMaildirSender(dir string) (*MaildirSender, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o750); err != nil {
+			return nil, fmt.Errorf("error al crear el buzón maildir: %w", err)
+		}
+	}
+	return &MaildirSender{dir: dir}, nil
+}
+
+// Send escribe el correo en tmp y lo mueve a new, como exige el formato Maildir
+func (s *MaildirSender) Send(ctx context.Context, msg Message) error {
+	hostname, _ := os.Hostname()
+	name := fmt.Sprintf("%d.%s.%s", time.Now().UnixNano(), uuid.New().String(), hostname)
+
+	tmpPath := filepath.Join(s.dir, "tmp", name)
+	if err := os.WriteFile(tmpPath, buildMessage("", msg), 0o640); err != nil {
+		return fmt.Errorf("error al guardar el correo: %w", err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(s.dir, "new", name)); err != nil {
+		return fmt.Errorf("error al entregar el correo: %w", err)
+	}
+	return nil
+}
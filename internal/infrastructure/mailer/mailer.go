@@ -0,0 +1,44 @@
+// Package mailer define el envío de correos con implementaciones
+// intercambiables (SMTP, archivos, maildir, memoria o log) y el renderizado de
+// las plantillas localizadas de cada notificación.
+package mailer
+
+import (
+	"context"
+)
+
+// Message es un correo listo para enviar
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Template es la plantilla de la que salió el correo
+	Template Template
+}
+
+// Sender entrega un correo a su destinatario
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Mailer renderiza una plantilla en el idioma del contexto y la envía
+type Mailer struct {
+	sender   Sender
+	renderer *Renderer
+}
+
+// New crea un Mailer que envía con sender las plantillas de renderer
+func New(sender Sender, renderer *Renderer) *Mailer {
+	return &Mailer{sender: sender, renderer: renderer}
+}
+
+// SendTemplate renderiza la plantilla con el idioma de ctx y envía el correo
+func (m *Mailer) SendTemplate(ctx context.Context, to string, name Template, data Data) error {
+	msg, err := m.renderer.Render(LocaleFromContext(ctx), name, data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return m.sender.Send(ctx, msg)
+}
@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSendTemplate(t *testing.T) {
+	renderer := newTestRenderer(t)
+	sender := NewMemorySender()
+	m := New(sender, renderer)
+
+	data := Data{Name: "Ana", Link: "https://example.com/login?token=abc", ExpiresIn: 15 * time.Minute}
+	ctx := WithLocale(context.Background(), "en")
+	if err := m.SendTemplate(ctx, "ana@example.com", TemplateMagicLink, data); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	messages := sender.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("se esperaba un correo, se obtuvieron %d", len(messages))
+	}
+	want, err := renderer.Render("en", TemplateMagicLink, data)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	want.To = "ana@example.com"
+	if messages[0] != want {
+		t.Fatalf("se esperaba %+v, se obtuvo %+v", want, messages[0])
+	}
+
+	sender.Reset()
+	if len(sender.Messages()) != 0 {
+		t.Fatal("Reset no descartó los correos")
+	}
+}
+
+func TestSendTemplateUnknownTemplate(t *testing.T) {
+	sender := NewMemorySender()
+	m := New(sender, newTestRenderer(t))
+
+	if err := m.SendTemplate(context.Background(), "ana@example.com", Template("no_existe"), Data{}); err == nil {
+		t.Fatal("se esperaba un error")
+	}
+	if len(sender.Messages()) != 0 {
+		t.Fatal("se envió un correo sin plantilla")
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := map[string]string{
+		"en-US,en;q=0.9,es;q=0.8": "en",
+		"es":                      "es",
+		"ES-co":                   "es",
+		" fr;q=0.5":               "fr",
+		"":                        "",
+	}
+	for header, want := range tests {
+		if got := ParseAcceptLanguage(header); got != want {
+			t.Fatalf("%q: se esperaba %q, se obtuvo %q", header, want, got)
+		}
+	}
+}
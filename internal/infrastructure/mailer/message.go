@@ -0,0 +1,71 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// buildMessage arma el correo en formato RFC 5322. Si tiene versión HTML se
+// envía como multipart/alternative junto al texto plano.
+func buildMessage(from string, msg Message) []byte {
+	var b bytes.Buffer
+	if from != "" {
+		writeHeader(&b, "From", from)
+	}
+	writeHeader(&b, "To", msg.To)
+	writeHeader(&b, "Subject", mime.QEncoding.Encode("utf-8", stripLineBreaks(msg.Subject)))
+	writeHeader(&b, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&b, "MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		writeHeader(&b, "Content-Type", "text/plain; charset=UTF-8")
+		writeHeader(&b, "Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		writeQuotedPrintable(&b, msg.Text)
+		return b.Bytes()
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	writeHeader(&b, "Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	b.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		w, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		writeQuotedPrintable(w, part.content)
+	}
+	parts.Close()
+
+	b.Write(body.Bytes())
+	return b.Bytes()
+}
+
+// writeHeader escribe una cabecera descartando saltos de línea, que permitirían
+// inyectar cabeceras adicionales
+func writeHeader(b *bytes.Buffer, key, value string) {
+	fmt.Fprintf(b, "%s: %s\r\n", key, stripLineBreaks(value))
+}
+
+// stripLineBreaks elimina los saltos de línea de un valor de cabecera. El asunto
+// se limpia antes de codificarlo, porque la codificación los conservaría.
+func stripLineBreaks(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, content string) {
+	qp := quotedprintable.NewWriter(w)
+	qp.Write([]byte(content))
+	qp.Close()
+}
@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"testing"
+)
+
+func TestBuildMessageStripsHeaderInjection(t *testing.T) {
+	raw := buildMessage("no-reply@example.com", Message{
+		To:      "ana@example.com\r\nBcc: atacante@example.com",
+		Subject: "Hola\r\nBcc: atacante@example.com",
+		Text:    "cuerpo",
+	})
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("correo mal formado: %v", err)
+	}
+	if bcc := msg.Header.Get("Bcc"); bcc != "" {
+		t.Fatalf("se inyectó la cabecera Bcc: %q", bcc)
+	}
+	if to := msg.Header.Get("To"); to != "ana@example.comBcc: atacante@example.com" {
+		t.Fatalf("destinatario inesperado: %q", to)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("asunto mal codificado: %v", err)
+	}
+	if subject != "HolaBcc: atacante@example.com" {
+		t.Fatalf("asunto inesperado: %q", subject)
+	}
+}
+
+func TestBuildMessagePlainText(t *testing.T) {
+	raw := buildMessage("", Message{To: "ana@example.com", Subject: "Código de acceso", Text: "Tu código es 123456"})
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("correo mal formado: %v", err)
+	}
+	if from := msg.Header.Get("From"); from != "" {
+		t.Fatalf("no se esperaba remitente, se obtuvo %q", from)
+	}
+	if contentType := msg.Header.Get("Content-Type"); contentType != "text/plain; charset=UTF-8" {
+		t.Fatalf("Content-Type inesperado: %q", contentType)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Código de acceso" {
+		t.Fatalf("asunto inesperado: %q, %v", subject, err)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil || string(body) != "Tu código es 123456" {
+		t.Fatalf("cuerpo inesperado: %q, %v", body, err)
+	}
+}
+
+func TestBuildMessageMultipart(t *testing.T) {
+	raw := buildMessage("no-reply@example.com", Message{
+		To:      "ana@example.com",
+		Subject: "Verifica tu correo",
+		Text:    "Abre el enlace",
+		HTML:    "<p>Abre el <a href=\"https://example.com\">enlace</a></p>",
+	})
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("correo mal formado: %v", err)
+	}
+	if from := msg.Header.Get("From"); from != "no-reply@example.com" {
+		t.Fatalf("remitente inesperado: %q", from)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type inesperado: %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+
+	want := []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", "Abre el enlace"},
+		{"text/html; charset=UTF-8", "<p>Abre el <a href=\"https://example.com\">enlace</a></p>"},
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for i, w := range want {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			t.Fatalf("parte %d: %v", i, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != w.contentType {
+			t.Fatalf("parte %d: Content-Type %q, se esperaba %q", i, got, w.contentType)
+		}
+		if got := part.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+			t.Fatalf("parte %d: codificación %q", i, got)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil || string(body) != w.content {
+			t.Fatalf("parte %d: contenido %q, %v", i, body, err)
+		}
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Fatalf("se esperaban solo dos partes: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+)
+
+// SMTPConfig son los datos de conexión al servidor SMTP
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPSender envía los correos por SMTP. Usa STARTTLS si el servidor lo ofrece.
+type SMTPSender struct {
+	config SMTPConfig
+}
+
+// NewSMTPSender crea un SMTPSender
+func NewSMTPSender(config SMTPConfig) *SMTPSender {
+	return &SMTPSender{config: config}
+}
+
+// Send entrega el correo al servidor SMTP
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	}
+
+	addr := net.JoinHostPort(s.config.Host, s.config.Port)
+	if err := smtp.SendMail(addr, auth, s.config.From, []string{msg.To}, buildMessage(s.config.From, msg)); err != nil {
+		return fmt.Errorf("error al enviar el correo por SMTP: %w", err)
+	}
+	return nil
+}
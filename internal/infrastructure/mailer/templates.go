@@ -0,0 +1,119 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Template identifica una notificación
+type Template string
+
+const (
+	TemplateWelcome        Template = "welcome"
+	TemplateVerifyEmail    Template = "verify_email"
+	TemplateResetPassword  Template = "reset_password"
+	TemplateNewDeviceAlert Template = "new_device_alert"
+	TemplateMagicLink      Template = "magic_link"
+	TemplateEmailOTP       Template = "email_otp"
+)
+
+var (
+	templateNames = []Template{TemplateWelcome, TemplateVerifyEmail, TemplateResetPassword, TemplateNewDeviceAlert, TemplateMagicLink, TemplateEmailOTP}
+	locales       = []string{"es", "en"}
+)
+
+// Data son los valores disponibles en las plantillas. Cada plantilla usa solo
+// los que necesita.
+type Data struct {
+	AppName   string
+	Name      string
+	Link      string
+	Code      string
+	ExpiresIn time.Duration
+	UserAgent string
+	ClientIP  string
+	Time      time.Time
+}
+
+// Minutes y Hours facilitan mostrar la vigencia del enlace en las plantillas
+func (d Data) Minutes() int { return int(d.ExpiresIn.Minutes()) }
+func (d Data) Hours() int   { return int(d.ExpiresIn.Hours()) }
+
+type localizedTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Renderer genera el asunto, el texto y el HTML de cada notificación. Cada
+// plantilla de texto define los bloques "subject" y "text"; la HTML define
+// "content", que se inserta en layout.html.
+type Renderer struct {
+	appName       string
+	defaultLocale string
+	templates     map[string]localizedTemplate
+}
+
+// NewRenderer carga las plantillas embebidas. defaultLocale se usa cuando el
+// idioma pedido no está disponible.
+func NewRenderer(appName, defaultLocale string) (*Renderer, error) {
+	r := &Renderer{appName: appName, defaultLocale: defaultLocale, templates: map[string]localizedTemplate{}}
+
+	for _, locale := range locales {
+		for _, name := range templateNames {
+			base := fmt.Sprintf("templates/%s/%s", locale, name)
+			text, err := texttemplate.ParseFS(templateFS, base+".txt")
+			if err != nil {
+				return nil, fmt.Errorf("error al cargar la plantilla %s: %w", base, err)
+			}
+			html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", base+".html")
+			if err != nil {
+				return nil, fmt.Errorf("error al cargar la plantilla %s: %w", base, err)
+			}
+			r.templates[locale+"/"+string(name)] = localizedTemplate{text: text, html: html}
+		}
+	}
+
+	if _, ok := r.templates[defaultLocale+"/"+string(TemplateWelcome)]; !ok {
+		return nil, fmt.Errorf("idioma por defecto no soportado: %s", defaultLocale)
+	}
+	return r, nil
+}
+
+// Render genera el correo de la plantilla en el idioma pedido
+func (r *Renderer) Render(locale string, name Template, data Data) (Message, error) {
+	tmpl, ok := r.templates[locale+"/"+string(name)]
+	if !ok {
+		tmpl, ok = r.templates[r.defaultLocale+"/"+string(name)]
+		if !ok {
+			return Message{}, fmt.Errorf("plantilla desconocida: %s", name)
+		}
+	}
+	if data.AppName == "" {
+		data.AppName = r.appName
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("error al renderizar %s: %w", name, err)
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return Message{}, fmt.Errorf("error al renderizar %s: %w", name, err)
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("error al renderizar %s: %w", name, err)
+	}
+
+	return Message{
+		Subject:  subject.String(),
+		Text:     text.String(),
+		HTML:     html.String(),
+		Template: name,
+	}, nil
+}
@@ -0,0 +1,92 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRenderer(t *testing.T) *Renderer {
+	t.Helper()
+	renderer, err := NewRenderer("Auth UCP", "es")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	return renderer
+}
+
+func TestRenderLocales(t *testing.T) {
+	renderer := newTestRenderer(t)
+	data := Data{Name: "Ana", Link: "https://example.com/verify?token=abc", ExpiresIn: 24 * time.Hour}
+
+	tests := []struct {
+		locale  string
+		subject string
+		text    string
+	}{
+		{locale: "es", subject: "Verifica tu correo", text: "en las próximas 24 horas"},
+		{locale: "en", subject: "Verify your email", text: "24 hours"},
+		{locale: "fr", subject: "Verifica tu correo", text: "en las próximas 24 horas"},
+		{locale: "", subject: "Verifica tu correo", text: "en las próximas 24 horas"},
+	}
+
+	for _, tt := range tests {
+		t.Run("idioma "+tt.locale, func(t *testing.T) {
+			msg, err := renderer.Render(tt.locale, TemplateVerifyEmail, data)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if msg.Subject != tt.subject {
+				t.Fatalf("se esperaba el asunto %q, se obtuvo %q", tt.subject, msg.Subject)
+			}
+			if !strings.Contains(msg.Text, tt.text) || !strings.Contains(msg.Text, data.Link) {
+				t.Fatalf("texto inesperado: %q", msg.Text)
+			}
+			if !strings.Contains(msg.HTML, "<h2 style=\"margin-top: 0;\">Auth UCP</h2>") {
+				t.Fatalf("el HTML no usa el layout con el nombre de la aplicación: %q", msg.HTML)
+			}
+			if msg.Template != TemplateVerifyEmail {
+				t.Fatalf("se esperaba la plantilla %s, se obtuvo %s", TemplateVerifyEmail, msg.Template)
+			}
+		})
+	}
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	renderer := newTestRenderer(t)
+	msg, err := renderer.Render("es", TemplateWelcome, Data{Name: "<script>alert(1)</script>"})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if strings.Contains(msg.HTML, "<script>") {
+		t.Fatalf("el HTML no escapa los datos: %q", msg.HTML)
+	}
+}
+
+func TestRenderEveryTemplate(t *testing.T) {
+	renderer := newTestRenderer(t)
+	for _, locale := range locales {
+		for _, name := range templateNames {
+			msg, err := renderer.Render(locale, name, Data{Name: "Ana", Link: "https://example.com", Code: "123456", ExpiresIn: time.Hour, Time: time.Now()})
+			if err != nil {
+				t.Fatalf("%s/%s: error inesperado: %v", locale, name, err)
+			}
+			if msg.Subject == "" || msg.Text == "" || msg.HTML == "" {
+				t.Fatalf("%s/%s: correo incompleto: %+v", locale, name, msg)
+			}
+		}
+	}
+}
+
+func TestRenderUnknownTemplate(t *testing.T) {
+	renderer := newTestRenderer(t)
+	if _, err := renderer.Render("en", Template("no_existe"), Data{}); err == nil {
+		t.Fatal("se esperaba un error")
+	}
+}
+
+func TestNewRendererUnsupportedDefaultLocale(t *testing.T) {
+	if _, err := NewRenderer("Auth UCP", "fr"); err == nil {
+		t.Fatal("se esperaba un error")
+	}
+}
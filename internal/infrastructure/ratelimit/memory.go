@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval es cada cuánto MemoryStore descarta los cubos que ya se rellenaron
+const sweepInterval = time.Minute
+
+// MemoryStore guarda los cubos en memoria. Los límites son por instancia.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	fullAt time.Time
+}
+
+// NewMemoryStore crea un almacén de cubos en memoria
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}, lastSweep: time.Now()}
+}
+
+// Take gasta una ficha del cubo de key
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.buckets[key]
+	if !ok {
+		state = &memoryBucket{}
+		s.buckets[key] = state
+	}
+	result := take(&state.bucket, limit, now)
+	state.fullAt = now.Add(result.ResetAfter)
+
+	// Un cubo lleno equivale a uno sin estado, así que se puede descartar
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, b := range s.buckets {
+			if !b.fullAt.After(now) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	return result, nil
+}
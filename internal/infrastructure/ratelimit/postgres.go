@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PostgresStore guarda los cubos en Postgres, de modo que todas las instancias
+// comparten los mismos límites. Cada ficha se gasta en una transacción que
+// bloquea la fila del cubo.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore crea un almacén de cubos en la tabla rate_limit_buckets
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Take gasta una ficha del cubo de key
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (result Result, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("error al iniciar la transacción del límite de peticiones: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// El cubo nuevo empieza lleno. Si dos peticiones lo crean a la vez, la
+	// segunda espera a la primera y continúa desde el estado que dejó.
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (bucket_key, tokens, updated_at, full_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (bucket_key) DO NOTHING
+	`, key, float64(limit.Burst), now)
+	if err != nil {
+		return Result{}, fmt.Errorf("error al crear el límite de peticiones: %w", err)
+	}
+
+	var state bucket
+	err = tx.QueryRowContext(ctx,
+		`SELECT tokens, updated_at FROM rate_limit_buckets WHERE bucket_key = $1 FOR UPDATE`, key,
+	).Scan(&state.tokens, &state.updatedAt)
+	if err != nil {
+		return Result{}, fmt.Errorf("error al leer el límite de peticiones: %w", err)
+	}
+
+	result = take(&state, limit, now)
+	_, err = tx.ExecContext(ctx,
+		`UPDATE rate_limit_buckets SET tokens = $1, updated_at = $2, full_at = $3 WHERE bucket_key = $4`,
+		state.tokens, state.updatedAt, now.Add(result.ResetAfter), key,
+	)
+	if err != nil {
+		return Result{}, fmt.Errorf("error al guardar el límite de peticiones: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Result{}, fmt.Errorf("error al guardar el límite de peticiones: %w", err)
+	}
+	return result, nil
+}
+
+// StartCleanup elimina cada interval los cubos que ya se rellenaron, que
+// equivalen a no tener estado
+func (s *PostgresStore) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE full_at <= $1`, time.Now()); err != nil {
+					log.Printf("Error eliminando los límites de peticiones vencidos: %v", err)
+				}
+			}
+		}
+	}()
+}
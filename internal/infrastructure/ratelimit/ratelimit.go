@@ -0,0 +1,81 @@
+// Package ratelimit limita la frecuencia de peticiones con el algoritmo token
+// bucket. Cada clave (una IP, un correo, un client_id) tiene un cubo de Burst
+// fichas que se rellena a ritmo constante durante Period; cada petición gasta
+// una ficha y, si no queda ninguna, se rechaza hasta que se rellene.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit define el tamaño del cubo y cuánto tarda en rellenarse por completo
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// PerMinute permite n peticiones por minuto, con ráfagas de hasta n
+func PerMinute(n int) Limit {
+	return Limit{Burst: n, Period: time.Minute}
+}
+
+// PerHour permite n peticiones por hora, con ráfagas de hasta n
+func PerHour(n int) Limit {
+	return Limit{Burst: n, Period: time.Hour}
+}
+
+// rate devuelve cuántas fichas se recuperan por segundo
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result es el resultado de gastar una ficha
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter es cuánto falta para la próxima ficha si se rechazó la petición
+	RetryAfter time.Duration
+	// ResetAfter es cuánto falta para que el cubo vuelva a estar lleno
+	ResetAfter time.Duration
+}
+
+// Store guarda el estado de los cubos. MemoryStore sirve para una sola
+// instancia; PostgresStore comparte los límites entre varias.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket es el estado de un cubo: las fichas disponibles en updatedAt
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take rellena el cubo según el tiempo transcurrido e intenta gastar una ficha.
+// Un cubo sin estado previo empieza lleno.
+func take(state *bucket, limit Limit, now time.Time) Result {
+	if state.updatedAt.IsZero() {
+		state.tokens = float64(limit.Burst)
+	} else if elapsed := now.Sub(state.updatedAt).Seconds(); elapsed > 0 {
+		state.tokens = math.Min(float64(limit.Burst), state.tokens+elapsed*limit.rate())
+	}
+	state.updatedAt = now
+
+	result := Result{Limit: limit.Burst}
+	if state.tokens >= 1 {
+		state.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - state.tokens) / limit.rate())
+	}
+	result.Remaining = int(state.tokens)
+	result.ResetAfter = secondsToDuration((float64(limit.Burst) - state.tokens) / limit.rate())
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testLimit recupera media ficha por segundo, un valor exacto en coma flotante
+var testLimit = Limit{Burst: 4, Period: 8 * time.Second}
+
+func TestTakeRefill(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	state := &bucket{}
+
+	type step struct {
+		name       string
+		at         time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		resetAfter time.Duration
+	}
+	steps := []step{
+		{name: "cubo nuevo empieza lleno", at: 0, allowed: true, remaining: 3, resetAfter: 2 * time.Second},
+		{name: "segunda ficha", at: 0, allowed: true, remaining: 2, resetAfter: 4 * time.Second},
+		{name: "tercera ficha", at: 0, allowed: true, remaining: 1, resetAfter: 6 * time.Second},
+		{name: "última ficha", at: 0, allowed: true, remaining: 0, resetAfter: 8 * time.Second},
+		{name: "cubo vacío", at: 0, allowed: false, remaining: 0, retryAfter: 2 * time.Second, resetAfter: 8 * time.Second},
+		{name: "media ficha recuperada", at: time.Second, allowed: false, remaining: 0, retryAfter: time.Second, resetAfter: 7 * time.Second},
+		{name: "ficha recuperada", at: 2 * time.Second, allowed: true, remaining: 0, resetAfter: 8 * time.Second},
+		{name: "reloj hacia atrás no recupera", at: time.Second, allowed: false, remaining: 0, retryAfter: 2 * time.Second, resetAfter: 8 * time.Second},
+		{name: "el relleno no pasa del máximo", at: time.Hour, allowed: true, remaining: 3, resetAfter: 2 * time.Second},
+	}
+
+	for _, s := range steps {
+		got := take(state, testLimit, start.Add(s.at))
+		want := Result{
+			Allowed:    s.allowed,
+			Limit:      testLimit.Burst,
+			Remaining:  s.remaining,
+			RetryAfter: s.retryAfter,
+			ResetAfter: s.resetAfter,
+		}
+		if got != want {
+			t.Fatalf("%s: se esperaba %+v, se obtuvo %+v", s.name, want, got)
+		}
+	}
+}
+
+func TestLimitRate(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit Limit
+		want  float64
+	}{
+		{name: "por minuto", limit: PerMinute(60), want: 1},
+		{name: "por hora", limit: PerHour(3600), want: 1},
+		{name: "fracción", limit: testLimit, want: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limit.rate(); got != tt.want {
+				t.Fatalf("se esperaba %v, se obtuvo %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreSeparatesKeys(t *testing.T) {
+	store := NewMemoryStore()
+	limit := PerHour(2)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if result, err := store.Take(ctx, "ip:1", limit); err != nil || !result.Allowed {
+			t.Fatalf("petición %d rechazada: %+v, %v", i+1, result, err)
+		}
+	}
+	result, err := store.Take(ctx, "ip:1", limit)
+	if err != nil || result.Allowed {
+		t.Fatalf("se esperaba el rechazo al agotar el cubo: %+v, %v", result, err)
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > 30*time.Minute {
+		t.Fatalf("espera fuera de rango: %v", result.RetryAfter)
+	}
+
+	if result, err := store.Take(ctx, "ip:2", limit); err != nil || !result.Allowed {
+		t.Fatalf("otra clave rechazada: %+v, %v", result, err)
+	}
+}
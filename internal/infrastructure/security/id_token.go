@@ -0,0 +1,41 @@
+package security
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// UserClaims son los claims estándar de OpenID Connect sobre el usuario. Cada
+// uno se incluye solo si el scope que lo cubre fue concedido.
+type UserClaims struct {
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+	FamilyName    string `json:"family_name,omitempty"`
+	Role          string `json:"role,omitempty"`
+}
+
+// IDTokenClaims son los claims del id_token de OpenID Connect
+type IDTokenClaims struct {
+	Nonce string `json:"nonce,omitempty"`
+	UserClaims
+	jwt.RegisteredClaims
+}
+
+// GenerateIDToken genera el id_token que recibe una aplicación cliente (aud)
+// tras autenticar al usuario (sub)
+func GenerateIDToken(issuer, subject, audience, nonce string, user UserClaims, duration time.Duration) (string, error) {
+	now := time.Now()
+	return sign(IDTokenClaims{
+		Nonce:      nonce,
+		UserClaims: user,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+}
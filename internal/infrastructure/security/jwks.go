@@ -0,0 +1,108 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+)
+
+// JWK es una clave pública en formato JSON Web Key (RFC 7517). Según el tipo
+// lleva n y e (RSA), crv, x e y (EC) o crv y x (OKP, RFC 8037).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet es el documento publicado en jwks_uri
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS publica las claves con las que se verifican los tokens del
+// servicio: la activa y las retiradas cuyos tokens aún no expiran
+func PublicJWKS() JWKSet {
+	keys := PublishedKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	set := JWKSet{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		members, err := jwkMembers(key.publicKey)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, JWK{
+			Kty: members["kty"],
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Algorithm,
+			Crv: members["crv"],
+			N:   members["n"],
+			E:   members["e"],
+			X:   members["x"],
+			Y:   members["y"],
+		})
+	}
+	return set
+}
+
+// Thumbprint calcula el identificador de una clave pública según RFC 7638: el
+// SHA-256 de sus miembros obligatorios serializados en orden lexicográfico
+func Thumbprint(key crypto.PublicKey) (string, error) {
+	members, err := jwkMembers(key)
+	if err != nil {
+		return "", err
+	}
+	// json.Marshal de un map ordena las claves, como exige el RFC
+	canonical, _ := json.Marshal(members)
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// jwkMembers devuelve los miembros obligatorios del JWK de una clave pública,
+// que son los que entran en su thumbprint
+func jwkMembers(key crypto.PublicKey) (map[string]string, error) {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"n":   encode(key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		// Forma no comprimida 0x04 || x || y, con coordenadas de 32 bytes en P-256
+		ecdhKey, err := key.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		point := ecdhKey.Bytes()
+		size := (len(point) - 1) / 2
+		return map[string]string{
+			"kty": "EC",
+			"crv": key.Curve.Params().Name,
+			"x":   encode(point[1 : 1+size]),
+			"y":   encode(point[1+size:]),
+		}, nil
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   encode(key),
+		}, nil
+	}
+	return nil, errors.New("tipo de clave pública no soportado")
+}
@@ -2,50 +2,165 @@ package security
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
-// Claims personalizados para el token
+// Claims personalizados para el token. RegisteredClaims.ID es el jti, que
+// identifica al token para poder revocarlo.
 type JWTClaims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    string `json:"user_id,omitempty"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	// ClientID identifica al servicio en los tokens emitidos con client_credentials,
+	// que no tienen UserID
+	ClientID string `json:"client_id,omitempty"`
+	// DocumentType es el tipo de documento de identidad del usuario
+	DocumentType string `json:"document_type,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken genera un JWT para un usuario
-func GenerateToken(userID, role string, duration time.Duration) (string, error) {
-	if privateKey == nil {
-		return "", errors.New("clave privada no cargada")
+// HasScope indica si el token concede el scope
+func (c *JWTClaims) HasScope(scope string) bool {
+	for _, granted := range strings.Fields(c.Scope) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenOption agrega claims opcionales a un token
+type TokenOption func(*JWTClaims)
+
+// WithSessionID asocia el token a la sesión (familia de refresh tokens) que lo originó
+func WithSessionID(sessionID string) TokenOption {
+	return func(c *JWTClaims) {
+		c.SessionID = sessionID
+	}
+}
+
+// WithDocumentType agrega el tipo de documento de identidad del usuario
+func WithDocumentType(documentType string) TokenOption {
+	return func(c *JWTClaims) {
+		c.DocumentType = documentType
+	}
+}
+
+// WithIssuer identifica al emisor del token, necesario en los tokens OIDC
+func WithIssuer(issuer string) TokenOption {
+	return func(c *JWTClaims) {
+		c.Issuer = issuer
+	}
+}
+
+// WithAudience restringe el token a los destinatarios indicados, como el
+// client_id de una aplicación OAuth
+func WithAudience(audience ...string) TokenOption {
+	return func(c *JWTClaims) {
+		c.Audience = audience
+	}
+}
+
+// WithScope agrega los scopes concedidos, separados por espacios (RFC 8693)
+func WithScope(scope string) TokenOption {
+	return func(c *JWTClaims) {
+		c.Scope = scope
 	}
+}
 
+// GenerateToken genera un JWT para un usuario
+func GenerateToken(userID, role string, duration time.Duration, opts ...TokenOption) (string, error) {
 	claims := JWTClaims{
 		UserID: userID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+	for _, opt := range opts {
+		opt(&claims)
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(privateKey)
+	return sign(claims)
 }
 
-// ValidateToken verifica la validez de un JWT
-func ValidateToken(tokenString string) (*JWTClaims, error) {
-	if publicKey == nil {
-		return nil, errors.New("clave pública no cargada")
+// GenerateClientToken genera un JWT para un servicio autenticado con sus
+// credenciales de cliente. El sujeto del token es el propio cliente.
+func GenerateClientToken(clientID string, duration time.Duration, opts ...TokenOption) (string, error) {
+	claims := JWTClaims{
+		ClientID: clientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Subject:   clientID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	for _, opt := range opts {
+		opt(&claims)
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, errors.New("método de firma no válido")
+	return sign(claims)
+}
+
+// sign firma los claims con la clave activa del keyring, usando su algoritmo
+func sign(claims jwt.Claims) (string, error) {
+	key := ActiveKey()
+	if key == nil {
+		return "", errors.New("clave privada no cargada")
+	}
+	method, ok := signingMethods[key.Algorithm]
+	if !ok {
+		return "", errors.New("algoritmo de firma no soportado")
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.privateKey)
+}
+
+// verificationKey devuelve la clave publicada que corresponde al kid del token.
+// Los tokens sin kid, emitidos antes de publicar el JWKS, se prueban contra
+// todas las claves publicadas de su algoritmo. El alg del token debe coincidir
+// con el de la clave, para que no se pueda verificar con otro algoritmo.
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	alg := token.Method.Alg()
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		set := jwt.VerificationKeySet{}
+		for _, key := range PublishedKeys() {
+			if key.Algorithm == alg {
+				set.Keys = append(set.Keys, key.publicKey)
+			}
+		}
+		if len(set.Keys) == 0 {
+			return nil, errors.New("clave pública no cargada")
 		}
-		return publicKey, nil
-	})
+		return set, nil
+	}
+
+	key, ok := publishedKey(kid)
+	if !ok && tryReload() {
+		key, ok = publishedKey(kid)
+	}
+	if !ok {
+		return nil, errors.New("clave de firma desconocida")
+	}
+	if key.Algorithm != alg {
+		return nil, errors.New("método de firma no válido")
+	}
+	return key.publicKey, nil
+}
+
+// ValidateToken verifica la validez de un JWT
+func ValidateToken(tokenString string) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, verificationKey, jwt.WithValidMethods(SupportedAlgorithms()))
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,64 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// sealedKeyPrefix distingue una clave privada cifrada de una guardada en PEM
+const sealedKeyPrefix = "sealed:v1:"
+
+// SealPrivateKey cifra con AES-256-GCM una clave privada en PEM para guardarla.
+// Sin secreto la clave se guarda tal cual.
+func SealPrivateKey(secret, privatePEM []byte) (string, error) {
+	if len(secret) == 0 {
+		return string(privatePEM), nil
+	}
+	aead, err := keyCipher(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, privatePEM, nil)
+	return sealedKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenPrivateKey recupera la clave privada en PEM guardada con SealPrivateKey
+func OpenPrivateKey(secret []byte, stored string) ([]byte, error) {
+	if !strings.HasPrefix(stored, sealedKeyPrefix) {
+		return []byte(stored), nil
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("la clave de firma está cifrada y no se configuró el secreto")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, sealedKeyPrefix))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := keyCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("clave de firma cifrada inválida")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+func keyCipher(secret []byte) (cipher.AEAD, error) {
+	key := sha256.Sum256(secret)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -1,43 +1,268 @@
 package security
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const rsaKeyBits = 2048
+
+// signingMethods son los algoritmos con los que se pueden firmar tokens: RSA,
+// ECDSA P-256 y Ed25519, estos dos con claves y firmas mucho más pequeñas
+var signingMethods = map[string]jwt.SigningMethod{
+	jwt.SigningMethodRS256.Alg(): jwt.SigningMethodRS256,
+	jwt.SigningMethodES256.Alg(): jwt.SigningMethodES256,
+	jwt.SigningMethodEdDSA.Alg(): jwt.SigningMethodEdDSA,
+}
+
+// SupportedAlgorithms lista los algoritmos de firma soportados
+func SupportedAlgorithms() []string {
+	return []string{
+		jwt.SigningMethodRS256.Alg(),
+		jwt.SigningMethodES256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+	}
+}
+
+// SigningKey es un par de claves con el que se firman o verifican tokens
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+// Keyring guarda la clave activa, con la que se firman los tokens nuevos, y
+// las retiradas que siguen publicadas para verificar los tokens que firmaron
+type Keyring struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// keyring es el juego de claves que usan GenerateToken, ValidateToken y PublicJWKS
+var keyring = &Keyring{keys: map[string]*SigningKey{}}
+
+// reloadInterval limita las recargas por kid desconocido, para que tokens con
+// kid inventados no golpeen la base de datos
+const reloadInterval = 10 * time.Second
+
 var (
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
+	reloadMu   sync.Mutex
+	reloadKeys func() error
+	lastReload time.Time
 )
 
-// Cargar claves RSA desde archivos
-func LoadKeys() error {
-	privKeyBytes, err := os.ReadFile("internal/infrastructure/security/private.pem")
+// SetKeyReloader registra la función que recarga las claves cuando llega un
+// token firmado con una clave que esta instancia aún no conoce, como la que
+// acaba de generar otra instancia al rotar
+func SetKeyReloader(reload func() error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadKeys = reload
+}
+
+// tryReload recarga las claves si no se hizo en el último reloadInterval
+func tryReload() bool {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	if reloadKeys == nil || time.Since(lastReload) < reloadInterval {
+		return false
+	}
+	lastReload = time.Now()
+	return reloadKeys() == nil
+}
+
+// InstallKeys reemplaza las claves en uso. active firma los tokens nuevos y
+// published son todas las claves aceptadas al verificar, incluida la activa.
+func InstallKeys(active *SigningKey, published []*SigningKey) {
+	keys := make(map[string]*SigningKey, len(published)+1)
+	for _, key := range published {
+		keys[key.ID] = key
+	}
+	keys[active.ID] = active
+
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+	keyring.active = active
+	keyring.keys = keys
+}
+
+// ActiveKey devuelve la clave con la que se firman los tokens nuevos
+func ActiveKey() *SigningKey {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	return keyring.active
+}
+
+// PublishedKeys devuelve todas las claves aceptadas al verificar
+func PublishedKeys() []*SigningKey {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	keys := make([]*SigningKey, 0, len(keyring.keys))
+	for _, key := range keyring.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// publishedKey busca una clave publicada por su kid
+func publishedKey(kid string) (*SigningKey, bool) {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	key, ok := keyring.keys[kid]
+	return key, ok
+}
+
+// GenerateSigningKey genera un nuevo par de claves para el algoritmo indicado
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case jwt.SigningMethodES256.Alg():
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("algoritmo de firma no soportado: %s", algorithm)
+	}
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return newSigningKey(private)
+}
+
+// ParseSigningKey carga un par de claves desde la clave privada en PEM, ya sea
+// PKCS #8, PKCS #1 (RSA) o SEC 1 (EC). El algoritmo se deduce del tipo de clave.
+func ParseSigningKey(privatePEM []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(privatePEM)
+	if block == nil {
+		return nil, errors.New("la clave privada no está en formato PEM")
 	}
 
-	privateKey, err = jwt.ParseRSAPrivateKeyFromPEM(privKeyBytes)
+	var private any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("tipo de clave privada PEM no soportado: %s", block.Type)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	pubKeyBytes, err := os.ReadFile("internal/infrastructure/security/public.pem")
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("tipo de clave privada no soportado")
+	}
+	return newSigningKey(signer)
+}
+
+func newSigningKey(private crypto.Signer) (*SigningKey, error) {
+	algorithm, err := keyAlgorithm(private)
+	if err != nil {
+		return nil, err
+	}
+	id, err := Thumbprint(private.Public())
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return &SigningKey{
+		ID:         id,
+		Algorithm:  algorithm,
+		privateKey: private,
+		publicKey:  private.Public(),
+	}, nil
+}
+
+// keyAlgorithm devuelve el algoritmo con el que firma cada tipo de clave
+func keyAlgorithm(private crypto.Signer) (string, error) {
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256.Alg(), nil
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return "", errors.New("solo se soportan claves ECDSA con la curva P-256")
+		}
+		return jwt.SigningMethodES256.Alg(), nil
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA.Alg(), nil
+	}
+	return "", errors.New("tipo de clave privada no soportado")
+}
 
-	publicKey, err = jwt.ParseRSAPublicKeyFromPEM(pubKeyBytes)
-	return err
+// PrivateKeyPEM serializa la clave privada en PEM (PKCS #8) para guardarla
+func (k *SigningKey) PrivateKeyPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
 }
 
-// Obtener claves
-func PrivateKey() *rsa.PrivateKey {
-	return privateKey
+// LoadKeys carga el par de claves de los archivos PEM del repositorio, que
+// se importan como clave inicial cuando aún no hay claves guardadas
+func LoadKeys() (*SigningKey, error) {
+	privKeyBytes, err := os.ReadFile("internal/infrastructure/security/private.pem")
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseSigningKey(privKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKeyBytes, err := os.ReadFile("internal/infrastructure/security/public.pem")
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := parsePublicKey(pubKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !publicKey.Equal(key.publicKey) {
+		return nil, errors.New("public.pem no corresponde a private.pem")
+	}
+	return key, nil
 }
 
-func PublicKey() *rsa.PublicKey {
-	return publicKey
+// parsePublicKey carga una clave pública PKIX, o PKCS #1 si es RSA
+func parsePublicKey(publicPEM []byte) (interface{ Equal(crypto.PublicKey) bool }, error) {
+	block, _ := pem.Decode(publicPEM)
+	if block == nil {
+		return nil, errors.New("la clave pública no está en formato PEM")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := public.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return nil, errors.New("tipo de clave pública no soportado")
+	}
+	return key, nil
 }
@@ -0,0 +1,62 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// GenerateOpaqueToken genera un token aleatorio de un solo uso apto para URLs
+func GenerateOpaqueToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// HashToken calcula el hash con el que se almacena un token opaco
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateRecoveryCode genera un código de recuperación legible con el formato xxxxx-xxxxx
+func GenerateRecoveryCode() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// recoveryCodePrefixLength es la cantidad de caracteres del código que se
+// guardan en claro. Bastan para distinguir los códigos de un mismo usuario y
+// dejan 40 bits secretos.
+const recoveryCodePrefixLength = 2
+
+// RecoveryCodePrefix devuelve la parte no secreta del código con la que se
+// elige el hash a verificar, para no verificar todos en cada intento
+func RecoveryCodePrefix(code string) string {
+	if len(code) < recoveryCodePrefixLength {
+		return code
+	}
+	return code[:recoveryCodePrefixLength]
+}
+
+// GenerateNumericCode genera un código de verificación de digits dígitos,
+// conservando los ceros a la izquierda
+func GenerateNumericCode(digits int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
@@ -1,19 +1,194 @@
 package security
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
-// HashPassword cifra una contraseña
-func HashPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidPasswordHash = errors.New("hash de contraseña con formato inválido")
+
+// PasswordHasher cifra y verifica contraseñas con un algoritmo concreto. Cada
+// hash lleva el algoritmo y sus parámetros, de modo que se pueden verificar
+// los hashes antiguos mientras los nuevos usan otros.
+type PasswordHasher interface {
+	// Hash cifra una contraseña
+	Hash(password string) (string, error)
+	// Matches indica si el hash fue generado por este algoritmo
+	Matches(hash string) bool
+	// Verify compara una contraseña con un hash de este algoritmo
+	Verify(hash, password string) bool
+	// NeedsRehash indica si el hash usa parámetros distintos de los actuales
+	NeedsRehash(hash string) bool
+}
+
+// Argon2idParams son los parámetros de argon2id. Memory va en KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams sigue la recomendación mínima de OWASP para argon2id
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Argon2idHasher genera hashes argon2id en formato PHC:
+// $argon2id$v=19$m=19456,t=2,p=1$<sal>$<hash>
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher crea un hasher argon2id con los parámetros indicados
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Matches(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (h *Argon2idHasher) Verify(hash, password string) bool {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(candidate, key) == 1
+}
+
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, _, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return params != h.params
+}
+
+// decodeArgon2id lee los parámetros, la sal y la clave de un hash PHC argon2id
+func decodeArgon2id(hash string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// BcryptHasher verifica los hashes bcrypt generados antes de adoptar argon2id.
+// bcrypt ignora los bytes de la contraseña a partir del 72.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher crea un hasher bcrypt con el costo indicado
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
 	if err != nil {
 		return "", err
 	}
 	return string(hashed), nil
 }
 
-// ComparePassword compara una contraseña con su hash
+func (h *BcryptHasher) Matches(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (h *BcryptHasher) Verify(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost
+}
+
+var (
+	// passwordHasher genera los hashes nuevos
+	passwordHasher PasswordHasher = NewArgon2idHasher(DefaultArgon2idParams)
+	// legacyHashers verifican los hashes de algoritmos anteriores
+	legacyHashers = []PasswordHasher{NewBcryptHasher(bcrypt.DefaultCost)}
+)
+
+// SetPasswordHasher cambia el hasher de los hashes nuevos. Debe llamarse al
+// arrancar, antes de atender peticiones.
+func SetPasswordHasher(hasher PasswordHasher) {
+	passwordHasher = hasher
+}
+
+// HashPassword cifra una contraseña con el hasher actual
+func HashPassword(password string) (string, error) {
+	return passwordHasher.Hash(password)
+}
+
+// ComparePassword compara una contraseña con su hash, sea del algoritmo actual o de uno anterior
 func ComparePassword(hashedPassword, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	return err == nil
+	hasher := hasherFor(hashedPassword)
+	return hasher != nil && hasher.Verify(hashedPassword, password)
+}
+
+// NeedsRehash indica si el hash debe regenerarse con el algoritmo y los parámetros actuales
+func NeedsRehash(hashedPassword string) bool {
+	return !passwordHasher.Matches(hashedPassword) || passwordHasher.NeedsRehash(hashedPassword)
 }
 
+// hasherFor devuelve el hasher que generó el hash, o nil si ninguno lo reconoce
+func hasherFor(hashedPassword string) PasswordHasher {
+	if passwordHasher.Matches(hashedPassword) {
+		return passwordHasher
+	}
+	for _, hasher := range legacyHashers {
+		if hasher.Matches(hashedPassword) {
+			return hasher
+		}
+	}
+	return nil
+}
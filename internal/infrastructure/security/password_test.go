@@ -0,0 +1,182 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams son parámetros pequeños para que las pruebas sean rápidas
+var testArgon2idParams = Argon2idParams{
+	Memory:      64,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// useTestHasher cambia el hasher global durante la prueba y lo restaura al terminar
+func useTestHasher(t *testing.T, hasher PasswordHasher) {
+	t.Helper()
+	previous := passwordHasher
+	SetPasswordHasher(hasher)
+	t.Cleanup(func() { SetPasswordHasher(previous) })
+}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+
+	hash, err := hasher.Hash("Contraseña1!")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("formato PHC inesperado: %s", hash)
+	}
+	if !hasher.Matches(hash) {
+		t.Fatal("el hasher no reconoce su propio hash")
+	}
+	if !hasher.Verify(hash, "Contraseña1!") {
+		t.Fatal("contraseña correcta rechazada")
+	}
+	if hasher.Verify(hash, "Contraseña2!") {
+		t.Fatal("contraseña incorrecta aceptada")
+	}
+	if hasher.NeedsRehash(hash) {
+		t.Fatal("hash con los parámetros actuales marcado para regenerar")
+	}
+
+	other, err := hasher.Hash("Contraseña1!")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if other == hash {
+		t.Fatal("dos hashes de la misma contraseña comparten sal")
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	hash, err := NewArgon2idHasher(testArgon2idParams).Hash("Contraseña1!")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	stronger := testArgon2idParams
+	stronger.Iterations = 2
+	longerKey := testArgon2idParams
+	longerKey.KeyLength = 64
+
+	tests := []struct {
+		name   string
+		params Argon2idParams
+		want   bool
+	}{
+		{name: "mismos parámetros", params: testArgon2idParams, want: false},
+		{name: "más iteraciones", params: stronger, want: true},
+		{name: "clave más larga", params: longerKey, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewArgon2idHasher(tt.params).NeedsRehash(hash); got != tt.want {
+				t.Fatalf("se esperaba %v, se obtuvo %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestArgon2idRejectsMalformed(t *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+	hash, err := hasher.Hash("Contraseña1!")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{name: "vacío", hash: ""},
+		{name: "otra versión", hash: strings.Replace(hash, "v=19", "v=16", 1)},
+		{name: "parámetros ilegibles", hash: strings.Replace(hash, "m=64", "m=x", 1)},
+		{name: "sin clave", hash: hash[:strings.LastIndex(hash, "$")+1]},
+		{name: "sal no base64", hash: strings.Replace(hash, "p=1$", "p=1$***", 1)},
+		{name: "segmentos de más", hash: hash + "$extra"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if hasher.Verify(tt.hash, "Contraseña1!") {
+				t.Fatal("hash malformado aceptado")
+			}
+			if !hasher.NeedsRehash(tt.hash) {
+				t.Fatal("hash malformado no marcado para regenerar")
+			}
+		})
+	}
+}
+
+func TestPasswordBcryptFallback(t *testing.T) {
+	useTestHasher(t, NewArgon2idHasher(testArgon2idParams))
+
+	legacy, err := NewBcryptHasher(bcrypt.MinCost).Hash("Contraseña1!")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	if !ComparePassword(legacy, "Contraseña1!") {
+		t.Fatal("hash bcrypt con la contraseña correcta rechazado")
+	}
+	if ComparePassword(legacy, "Contraseña2!") {
+		t.Fatal("hash bcrypt con la contraseña incorrecta aceptado")
+	}
+	if !NeedsRehash(legacy) {
+		t.Fatal("hash bcrypt no marcado para regenerar con argon2id")
+	}
+
+	rehashed, err := HashPassword("Contraseña1!")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if !strings.HasPrefix(rehashed, "$argon2id$") {
+		t.Fatalf("se esperaba un hash argon2id, se obtuvo %s", rehashed)
+	}
+	if !ComparePassword(rehashed, "Contraseña1!") {
+		t.Fatal("hash regenerado rechazado")
+	}
+	if NeedsRehash(rehashed) {
+		t.Fatal("hash regenerado marcado para regenerar")
+	}
+}
+
+func TestPasswordParamsUpgrade(t *testing.T) {
+	useTestHasher(t, NewArgon2idHasher(testArgon2idParams))
+	hash, err := HashPassword("Contraseña1!")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	stronger := testArgon2idParams
+	stronger.Memory = 128
+	useTestHasher(t, NewArgon2idHasher(stronger))
+
+	// El hash anterior se sigue verificando con sus propios parámetros
+	if !ComparePassword(hash, "Contraseña1!") {
+		t.Fatal("hash con parámetros anteriores rechazado")
+	}
+	if !NeedsRehash(hash) {
+		t.Fatal("hash con parámetros anteriores no marcado para regenerar")
+	}
+}
+
+func TestComparePasswordUnknownFormat(t *testing.T) {
+	for _, hash := range []string{"", "Contraseña1!", "$1$sal$hash", "$argon2i$v=19$m=64,t=1,p=1$c2Fs$aGFzaA"} {
+		if ComparePassword(hash, "Contraseña1!") {
+			t.Fatalf("hash %q aceptado", hash)
+		}
+		if !NeedsRehash(hash) {
+			t.Fatalf("hash %q no marcado para regenerar", hash)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package security
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// PKCEMethodS256 es el único método de PKCE aceptado (RFC 7636); "plain" no
+// protege frente a quien intercepta la petición de autorización
+const PKCEMethodS256 = "S256"
+
+// VerifyPKCE comprueba que el code_verifier corresponda al code_challenge S256
+func VerifyPKCE(verifier, challenge string) bool {
+	// RFC 7636, sección 4.1: entre 43 y 128 caracteres
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	for _, r := range verifier {
+		if !isUnreserved(r) {
+			return false
+		}
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func isUnreserved(r rune) bool {
+	return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' ||
+		r == '-' || r == '.' || r == '_' || r == '~'
+}
@@ -0,0 +1,97 @@
+package security
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedLinkRoundTrip(t *testing.T) {
+	secret := []byte("secreto-de-prueba")
+	now := time.Unix(1700000000, 0)
+
+	token, err := SignLink(secret, "email_verification", "usuario-1", now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	subject, err := VerifyLink(secret, "email_verification", token, now)
+	if err != nil {
+		t.Fatalf("enlace válido rechazado: %v", err)
+	}
+	if subject != "usuario-1" {
+		t.Fatalf("se esperaba usuario-1, se obtuvo %s", subject)
+	}
+}
+
+func TestSignedLinkExpiry(t *testing.T) {
+	secret := []byte("secreto-de-prueba")
+	expiresAt := time.Unix(1700000000, 0)
+
+	token, err := SignLink(secret, "email_verification", "usuario-1", expiresAt)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		now   time.Time
+		valid bool
+	}{
+		{name: "antes de expirar", now: expiresAt.Add(-time.Second), valid: true},
+		{name: "en el segundo de expiración", now: expiresAt, valid: true},
+		{name: "un segundo después", now: expiresAt.Add(time.Second), valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifyLink(secret, "email_verification", token, tt.now)
+			if tt.valid && err != nil {
+				t.Fatalf("enlace válido rechazado: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidSignedLink) {
+				t.Fatalf("se esperaba ErrInvalidSignedLink, se obtuvo %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyLinkRejects(t *testing.T) {
+	secret := []byte("secreto-de-prueba")
+	now := time.Unix(1700000000, 0)
+
+	token, err := SignLink(secret, "email_verification", "usuario-1", now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	other, err := SignLink(secret, "email_verification", "usuario-2", now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+	otherPayload, _, _ := strings.Cut(other, ".")
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		purpose string
+		token   string
+	}{
+		{name: "otro propósito", secret: secret, purpose: "magic_link", token: token},
+		{name: "otro secreto", secret: []byte("otro-secreto"), purpose: "email_verification", token: token},
+		{name: "contenido cambiado", secret: secret, purpose: "email_verification", token: otherPayload + "." + signature},
+		{name: "firma truncada", secret: secret, purpose: "email_verification", token: payload + "." + signature[:len(signature)-2]},
+		{name: "firma no base64", secret: secret, purpose: "email_verification", token: payload + ".***"},
+		{name: "sin separador", secret: secret, purpose: "email_verification", token: payload + signature},
+		{name: "vacío", secret: secret, purpose: "email_verification", token: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := VerifyLink(tt.secret, tt.purpose, tt.token, now); !errors.Is(err, ErrInvalidSignedLink) {
+				t.Fatalf("se esperaba ErrInvalidSignedLink, se obtuvo %v", err)
+			}
+		})
+	}
+}
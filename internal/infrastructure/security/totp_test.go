@@ -0,0 +1,118 @@
+package security
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret es la clave SHA1 de los vectores de prueba del RFC 6238
+// ("12345678901234567890") codificada en base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTPRFC6238Vectors(t *testing.T) {
+	// El RFC publica códigos de 8 dígitos; los de 6 son sus últimos 6 dígitos
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			at := time.Unix(tt.unix, 0)
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, at)
+			if !ok {
+				t.Fatalf("código %s rechazado en %d", tt.code, tt.unix)
+			}
+			if want := tt.unix / totpPeriod; step != want {
+				t.Fatalf("se esperaba el paso %d, se obtuvo %d", want, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	key := []byte("12345678901234567890")
+	at := time.Unix(1234567890, 0)
+	current := at.Unix() / totpPeriod
+
+	tests := []struct {
+		name   string
+		offset int64
+		valid  bool
+	}{
+		{name: "paso anterior", offset: -1, valid: true},
+		{name: "paso siguiente", offset: 1, valid: true},
+		{name: "dos pasos antes", offset: -2, valid: false},
+		{name: "dos pasos después", offset: 2, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, totpCode(key, current+tt.offset), at)
+			if ok != tt.valid {
+				t.Fatalf("se esperaba %v, se obtuvo %v", tt.valid, ok)
+			}
+			if ok && step != current+tt.offset {
+				t.Fatalf("se esperaba el paso %d, se obtuvo %d", current+tt.offset, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejects(t *testing.T) {
+	at := time.Unix(59, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{name: "código incorrecto", secret: rfc6238Secret, code: "287083"},
+		{name: "código corto", secret: rfc6238Secret, code: "28708"},
+		{name: "código de 8 dígitos", secret: rfc6238Secret, code: "94287082"},
+		{name: "secreto inválido", secret: "no-es-base32!", code: "287082"},
+		{name: "otro secreto", secret: "JBSWY3DPEHPK3PXP", code: "287082"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, at); ok {
+				t.Fatal("se esperaba que el código fuera rechazado")
+			}
+		})
+	}
+}
+
+func TestValidateTOTPLowercaseSecret(t *testing.T) {
+	if _, ok := ValidateTOTP(strings.ToLower(rfc6238Secret), "287082", time.Unix(59, 0)); !ok {
+		t.Fatal("secreto en minúsculas rechazado")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secreto no es base32: %v", err)
+	}
+	if len(key) != totpSecretSize {
+		t.Fatalf("se esperaban %d bytes, se obtuvieron %d", totpSecretSize, len(key))
+	}
+
+	at := time.Now()
+	code := totpCode(key, at.Unix()/totpPeriod)
+	if _, ok := ValidateTOTP(secret, code, at); !ok {
+		t.Fatal("código del secreto generado rechazado")
+	}
+}
@@ -0,0 +1,76 @@
+package webauthn
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want any
+		rest []byte
+	}{
+		{name: "entero pequeño", data: []byte{0x0a}, want: int64(10)},
+		{name: "entero de un byte", data: []byte{0x18, 0xff}, want: int64(255)},
+		{name: "entero de dos bytes", data: []byte{0x19, 0x01, 0x00}, want: int64(256)},
+		{name: "entero negativo", data: []byte{0x26}, want: int64(-7)},
+		{name: "entero negativo de dos bytes", data: []byte{0x39, 0x01, 0x00}, want: int64(-257)},
+		{name: "cadena de bytes", data: []byte{0x43, 1, 2, 3}, want: []byte{1, 2, 3}},
+		{name: "cadena de texto", data: []byte{0x64, 'n', 'o', 'n', 'e'}, want: "none"},
+		{name: "arreglo", data: []byte{0x82, 0x01, 0x20}, want: []any{int64(1), int64(-1)}},
+		{name: "mapa", data: []byte{0xa2, 0x01, 0x02, 0x61, 'a', 0xf5}, want: map[any]any{int64(1): int64(2), "a": true}},
+		{name: "etiqueta", data: []byte{0xc2, 0x41, 0x01}, want: []byte{1}},
+		{name: "falso", data: []byte{0xf4}, want: false},
+		{name: "nulo", data: []byte{0xf6}, want: nil},
+		{name: "bytes restantes", data: []byte{0x01, 0x02}, want: int64(1), rest: []byte{0x02}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCBOR(tt.data)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("se esperaba %#v, se obtuvo %#v", tt.want, got)
+			}
+			if !bytes.Equal(rest, tt.rest) {
+				t.Fatalf("bytes restantes %x, se esperaba %x", rest, tt.rest)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORRejectsMalformed(t *testing.T) {
+	nested := bytes.Repeat([]byte{0x81}, maxCBORDepth+2)
+	nested = append(nested, 0x00)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "vacío", data: nil},
+		{name: "argumento truncado", data: []byte{0x19, 0x01}},
+		{name: "cadena truncada", data: []byte{0x45, 1, 2}},
+		{name: "arreglo truncado", data: []byte{0x83, 0x01, 0x02}},
+		{name: "mapa sin valor", data: []byte{0xa1, 0x01}},
+		{name: "clave de mapa no soportada", data: []byte{0xa1, 0x41, 0x01, 0x01}},
+		{name: "longitud indefinida", data: []byte{0x5f, 0x41, 0x01, 0xff}},
+		{name: "longitud mayor que los datos", data: []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "entero fuera de rango", data: []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "valor simple no soportado", data: []byte{0xf0}},
+		{name: "anidamiento excesivo", data: nested},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.data); !errors.Is(err, errInvalidCBOR) {
+				t.Fatalf("se esperaba errInvalidCBOR, se obtuvo %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package webauthn
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseCOSEKeyRejects(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgES256)
+	valid := authenticator.coseKey(t)
+
+	x := bytes.Repeat([]byte{0x01}, 32)
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{name: "truncada", raw: valid[:len(valid)-1]},
+		{name: "con bytes sobrantes", raw: append(append([]byte{}, valid...), 0x00)},
+		{name: "no es un mapa", raw: cborBytes(x)},
+		{name: "algoritmo no soportado", raw: cborMap(map[any][]byte{
+			int64(coseKeyType):   cborInt(coseKeyTypeEC2),
+			int64(coseAlgorithm): cborInt(-35), // ES384
+			int64(coseCurve):     cborInt(2),
+			int64(coseX):         cborBytes(x),
+			int64(coseY):         cborBytes(x),
+		})},
+		{name: "punto fuera de la curva", raw: cborMap(map[any][]byte{
+			int64(coseKeyType):   cborInt(coseKeyTypeEC2),
+			int64(coseAlgorithm): cborInt(AlgES256),
+			int64(coseCurve):     cborInt(coseCurveP256),
+			int64(coseX):         cborBytes(x),
+			int64(coseY):         cborBytes(x),
+		})},
+		{name: "módulo RSA corto", raw: cborMap(map[any][]byte{
+			int64(coseKeyType):   cborInt(coseKeyTypeRSA),
+			int64(coseAlgorithm): cborInt(AlgRS256),
+			int64(coseRSAN):      cborBytes(bytes.Repeat([]byte{0xff}, 128)),
+			int64(coseRSAE):      cborBytes([]byte{0x01, 0x00, 0x01}),
+		})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parseCOSEKey(tt.raw); err == nil {
+				t.Fatal("se esperaba un error")
+			}
+		})
+	}
+}
+
+func TestVerifyCOSESignature(t *testing.T) {
+	for _, alg := range []int64{AlgES256, AlgRS256} {
+		authenticator := newSoftAuthenticator(t, alg)
+		key := authenticator.coseKey(t)
+		data := []byte("datos firmados")
+		signature := authenticator.sign(t, data)
+
+		if err := verifyCOSESignature(key, data, signature); err != nil {
+			t.Fatalf("alg %d: firma válida rechazada: %v", alg, err)
+		}
+		if err := verifyCOSESignature(key, []byte("otros datos"), signature); err == nil {
+			t.Fatalf("alg %d: firma de otros datos aceptada", alg)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// Package webauthn implementa la verificación de las ceremonias de registro y
+// autenticación de WebAuthn (Nivel 2) necesarias para llaves de acceso y llaves
+// de seguridad. Las declaraciones de atestación no se verifican: el servicio
+// solicita atestación "none" y no confía en el modelo del autenticador.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrVerification        = errors.New("verificación WebAuthn fallida")
+	ErrClonedAuthenticator = errors.New("el contador de firmas no avanzó, posible autenticador clonado")
+	ErrUserNotVerified     = errors.New("el autenticador no verificó al usuario")
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	flagUserPresent   = 0x01
+	flagUserVerified  = 0x04
+	flagAttestedData  = 0x40
+	flagExtensionData = 0x80
+
+	challengeSize = 32
+)
+
+// Valores de userVerification y residentKey definidos por la especificación
+const (
+	Required    = "required"
+	Preferred   = "preferred"
+	Discouraged = "discouraged"
+)
+
+// Config identifica al Relying Party frente a los autenticadores
+type Config struct {
+	RPID    string
+	RPName  string
+	Origins []string
+}
+
+// AttestationResponse es la respuesta del navegador a navigator.credentials.create()
+type AttestationResponse struct {
+	ClientDataJSON    []byte
+	AttestationObject []byte
+	Transports        []string
+}
+
+// AssertionResponse es la respuesta del navegador a navigator.credentials.get()
+type AssertionResponse struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+// Credential es una credencial verificada durante el registro
+type Credential struct {
+	ID           []byte
+	PublicKey    []byte // Clave pública COSE
+	Algorithm    int64
+	SignCount    uint32
+	AAGUID       []byte
+	UserVerified bool
+}
+
+// Assertion es el resultado de verificar una autenticación
+type Assertion struct {
+	SignCount    uint32
+	UserVerified bool
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// NewChallenge genera un reto aleatorio codificado en base64url
+func NewChallenge() (string, error) {
+	b := make([]byte, challengeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return EncodeBase64URL(b), nil
+}
+
+// EncodeBase64URL codifica en base64url sin relleno, como lo hace WebAuthn
+func EncodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeBase64URL decodifica base64url con o sin relleno
+func DecodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// VerifyRegistration valida la respuesta de registro frente al reto emitido y
+// devuelve la credencial que debe guardarse
+func (c Config) VerifyRegistration(challenge string, resp AttestationResponse) (*Credential, error) {
+	if err := c.verifyClientData(resp.ClientDataJSON, ceremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, rest, err := decodeCBOR(resp.AttestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: objeto de atestación inválido", ErrVerification)
+	}
+	object, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: objeto de atestación inválido", ErrVerification)
+	}
+	rawAuthData, ok := object["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: faltan los datos del autenticador", ErrVerification)
+	}
+
+	data, err := c.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if data.flags&flagAttestedData == 0 {
+		return nil, fmt.Errorf("%w: la respuesta no incluye la credencial", ErrVerification)
+	}
+
+	_, alg, err := parseCOSEKey(data.publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVerification, err)
+	}
+
+	return &Credential{
+		ID:           data.credentialID,
+		PublicKey:    data.publicKey,
+		Algorithm:    alg,
+		SignCount:    data.signCount,
+		AAGUID:       data.aaguid,
+		UserVerified: data.flags&flagUserVerified != 0,
+	}, nil
+}
+
+// VerifyAssertion valida la firma de una autenticación con la clave pública
+// registrada y comprueba que el contador de firmas avance. Con userVerification
+// Required rechaza las respuestas en las que el autenticador no verificó al usuario.
+func (c Config) VerifyAssertion(challenge, userVerification string, publicKey []byte, storedSignCount uint32, resp AssertionResponse) (*Assertion, error) {
+	if err := c.verifyClientData(resp.ClientDataJSON, ceremonyGet, challenge); err != nil {
+		return nil, err
+	}
+
+	data, err := c.parseAuthenticatorData(resp.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(resp.ClientDataJSON)
+	signed := append(append([]byte{}, resp.AuthenticatorData...), clientDataHash[:]...)
+	if err := verifyCOSESignature(publicKey, signed, resp.Signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVerification, err)
+	}
+	if userVerification == Required && data.flags&flagUserVerified == 0 {
+		return nil, ErrUserNotVerified
+	}
+
+	// Los autenticadores que no llevan contador siempre envían cero
+	if (data.signCount != 0 || storedSignCount != 0) && data.signCount <= storedSignCount {
+		return nil, ErrClonedAuthenticator
+	}
+
+	return &Assertion{
+		SignCount:    data.signCount,
+		UserVerified: data.flags&flagUserVerified != 0,
+	}, nil
+}
+
+// verifyClientData comprueba el tipo de ceremonia, el reto y el origen
+func (c Config) verifyClientData(raw []byte, ceremony, challenge string) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("%w: clientDataJSON inválido", ErrVerification)
+	}
+	if data.Type != ceremony {
+		return fmt.Errorf("%w: tipo de ceremonia inesperado", ErrVerification)
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimRight(data.Challenge, "=")), []byte(challenge)) != 1 {
+		return fmt.Errorf("%w: el reto no coincide", ErrVerification)
+	}
+	if data.CrossOrigin || !c.allowedOrigin(data.Origin) {
+		return fmt.Errorf("%w: origen no permitido", ErrVerification)
+	}
+	return nil
+}
+
+func (c Config) allowedOrigin(origin string) bool {
+	for _, allowed := range c.Origins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAuthenticatorData decodifica los datos del autenticador y valida el
+// hash del RP ID y la presencia del usuario
+func (c Config) parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, fmt.Errorf("%w: datos del autenticador incompletos", ErrVerification)
+	}
+
+	data := &authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(data.rpIDHash, rpIDHash[:]) {
+		return nil, fmt.Errorf("%w: RP ID no coincide", ErrVerification)
+	}
+	if data.flags&flagUserPresent == 0 {
+		return nil, fmt.Errorf("%w: el usuario no confirmó su presencia", ErrVerification)
+	}
+
+	rest := raw[37:]
+	if data.flags&flagAttestedData != 0 {
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: credencial incompleta", ErrVerification)
+		}
+		data.aaguid = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return nil, fmt.Errorf("%w: credencial incompleta", ErrVerification)
+		}
+		data.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, remaining, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: clave pública inválida", ErrVerification)
+		}
+		data.publicKey = rest[:len(rest)-len(remaining)]
+		rest = remaining
+	}
+	if data.flags&flagExtensionData != 0 {
+		_, remaining, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extensiones inválidas", ErrVerification)
+		}
+		rest = remaining
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: datos del autenticador con bytes sobrantes", ErrVerification)
+	}
+
+	return data, nil
+}
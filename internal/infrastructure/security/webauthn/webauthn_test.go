@@ -0,0 +1,405 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+)
+
+const (
+	testRPID   = "auth.example.com"
+	testOrigin = "https://auth.example.com"
+)
+
+var testConfig = Config{RPID: testRPID, RPName: "Auth", Origins: []string{testOrigin}}
+
+// softAuthenticator es un autenticador por software que firma con ES256 o RS256
+type softAuthenticator struct {
+	alg          int64
+	ecKey        *ecdsa.PrivateKey
+	rsaKey       *rsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, alg int64) *softAuthenticator {
+	t.Helper()
+	a := &softAuthenticator{alg: alg, credentialID: make([]byte, 16)}
+	if _, err := rand.Read(a.credentialID); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	switch alg {
+	case AlgES256:
+		a.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgRS256:
+		a.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		t.Fatalf("algoritmo no soportado por el autenticador de prueba: %d", alg)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// coseKey codifica la clave pública del autenticador en formato COSE
+func (a *softAuthenticator) coseKey(t *testing.T) []byte {
+	t.Helper()
+	if a.ecKey != nil {
+		pub, err := a.ecKey.PublicKey.ECDH()
+		if err != nil {
+			t.Fatal(err)
+		}
+		point := pub.Bytes()
+		return cborMap(map[any][]byte{
+			int64(coseKeyType):   cborInt(coseKeyTypeEC2),
+			int64(coseAlgorithm): cborInt(AlgES256),
+			int64(coseCurve):     cborInt(coseCurveP256),
+			int64(coseX):         cborBytes(point[1:33]),
+			int64(coseY):         cborBytes(point[33:]),
+		})
+	}
+	return cborMap(map[any][]byte{
+		int64(coseKeyType):   cborInt(coseKeyTypeRSA),
+		int64(coseAlgorithm): cborInt(AlgRS256),
+		int64(coseRSAN):      cborBytes(a.rsaKey.N.Bytes()),
+		int64(coseRSAE):      cborBytes(big32(a.rsaKey.E)),
+	})
+}
+
+// authData arma los datos del autenticador; con attested incluye la credencial
+func (a *softAuthenticator) authData(t *testing.T, rpID string, flags byte, attested bool) []byte {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	if attested {
+		flags |= flagAttestedData
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey(t)...)
+	}
+	return data
+}
+
+func (a *softAuthenticator) sign(t *testing.T, data []byte) []byte {
+	t.Helper()
+	digest := sha256.Sum256(data)
+	var signature []byte
+	var err error
+	if a.ecKey != nil {
+		signature, err = ecdsa.SignASN1(rand.Reader, a.ecKey, digest[:])
+	} else {
+		signature, err = rsa.SignPKCS1v15(rand.Reader, a.rsaKey, crypto.SHA256, digest[:])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signature
+}
+
+// ceremony describe lo que el navegador y el autenticador envían en una respuesta
+type ceremony struct {
+	clientType  string
+	challenge   string
+	origin      string
+	crossOrigin bool
+	rpID        string
+	flags       byte
+}
+
+func (c ceremony) clientDataJSON(t *testing.T) []byte {
+	t.Helper()
+	raw, err := json.Marshal(clientData{Type: c.clientType, Challenge: c.challenge, Origin: c.origin, CrossOrigin: c.crossOrigin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func (a *softAuthenticator) register(t *testing.T, c ceremony) AttestationResponse {
+	t.Helper()
+	attestationObject := cborMap(map[any][]byte{
+		"fmt":      cborText("none"),
+		"attStmt":  cborMap(nil),
+		"authData": cborBytes(a.authData(t, c.rpID, c.flags, true)),
+	})
+	return AttestationResponse{ClientDataJSON: c.clientDataJSON(t), AttestationObject: attestationObject}
+}
+
+func (a *softAuthenticator) assert(t *testing.T, c ceremony) AssertionResponse {
+	t.Helper()
+	a.signCount++
+	authData := a.authData(t, c.rpID, c.flags, false)
+	clientDataJSON := c.clientDataJSON(t)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	return AssertionResponse{
+		CredentialID:      a.credentialID,
+		ClientDataJSON:    clientDataJSON,
+		AuthenticatorData: authData,
+		Signature:         a.sign(t, append(append([]byte{}, authData...), clientDataHash[:]...)),
+	}
+}
+
+func newCeremony(t *testing.T, clientType string) ceremony {
+	t.Helper()
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ceremony{
+		clientType: clientType,
+		challenge:  challenge,
+		origin:     testOrigin,
+		rpID:       testRPID,
+		flags:      flagUserPresent | flagUserVerified,
+	}
+}
+
+func TestCeremonies(t *testing.T) {
+	for _, alg := range []int64{AlgES256, AlgRS256} {
+		authenticator := newSoftAuthenticator(t, alg)
+
+		registration := newCeremony(t, ceremonyCreate)
+		credential, err := testConfig.VerifyRegistration(registration.challenge, authenticator.register(t, registration))
+		if err != nil {
+			t.Fatalf("alg %d: registro: %v", alg, err)
+		}
+		if credential.Algorithm != alg || !bytes.Equal(credential.ID, authenticator.credentialID) || !credential.UserVerified {
+			t.Fatalf("alg %d: credencial inesperada: %+v", alg, credential)
+		}
+
+		login := newCeremony(t, ceremonyGet)
+		assertion, err := testConfig.VerifyAssertion(login.challenge, Required, credential.PublicKey, credential.SignCount, authenticator.assert(t, login))
+		if err != nil {
+			t.Fatalf("alg %d: autenticación: %v", alg, err)
+		}
+		if assertion.SignCount != authenticator.signCount || !assertion.UserVerified {
+			t.Fatalf("alg %d: resultado inesperado: %+v", alg, assertion)
+		}
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgES256)
+	otherChallenge, _ := NewChallenge()
+
+	tests := []struct {
+		name   string
+		modify func(c *ceremony)
+		raw    func(resp *AttestationResponse)
+	}{
+		{name: "reto distinto", modify: func(c *ceremony) { c.challenge = otherChallenge }},
+		{name: "origen distinto", modify: func(c *ceremony) { c.origin = "https://evil.example.com" }},
+		{name: "origen cruzado", modify: func(c *ceremony) { c.crossOrigin = true }},
+		{name: "tipo de ceremonia", modify: func(c *ceremony) { c.clientType = ceremonyGet }},
+		{name: "rpIdHash distinto", modify: func(c *ceremony) { c.rpID = "evil.example.com" }},
+		{name: "sin presencia del usuario", modify: func(c *ceremony) { c.flags = flagUserVerified }},
+		{name: "clientDataJSON inválido", raw: func(resp *AttestationResponse) { resp.ClientDataJSON = []byte("{") }},
+		{name: "objeto de atestación truncado", raw: func(resp *AttestationResponse) {
+			resp.AttestationObject = resp.AttestationObject[:len(resp.AttestationObject)-10]
+		}},
+		{name: "objeto de atestación con bytes sobrantes", raw: func(resp *AttestationResponse) {
+			resp.AttestationObject = append(resp.AttestationObject, 0x00)
+		}},
+		{name: "objeto de atestación que no es un mapa", raw: func(resp *AttestationResponse) {
+			resp.AttestationObject = cborBytes([]byte("authData"))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCeremony(t, ceremonyCreate)
+			challenge := c.challenge
+			if tt.modify != nil {
+				tt.modify(&c)
+			}
+			resp := authenticator.register(t, c)
+			if tt.raw != nil {
+				tt.raw(&resp)
+			}
+			if _, err := testConfig.VerifyRegistration(challenge, resp); !errors.Is(err, ErrVerification) {
+				t.Fatalf("se esperaba ErrVerification, se obtuvo %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgES256)
+	registration := newCeremony(t, ceremonyCreate)
+	credential, err := testConfig.VerifyRegistration(registration.challenge, authenticator.register(t, registration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newSoftAuthenticator(t, AlgES256)
+	otherChallenge, _ := NewChallenge()
+
+	tests := []struct {
+		name             string
+		userVerification string
+		modify           func(c *ceremony)
+		raw              func(resp *AssertionResponse)
+		storedSignCount  uint32
+		want             error
+	}{
+		{name: "reto distinto", modify: func(c *ceremony) { c.challenge = otherChallenge }, want: ErrVerification},
+		{name: "origen distinto", modify: func(c *ceremony) { c.origin = "https://evil.example.com" }, want: ErrVerification},
+		{name: "tipo de ceremonia", modify: func(c *ceremony) { c.clientType = ceremonyCreate }, want: ErrVerification},
+		{name: "rpIdHash distinto", modify: func(c *ceremony) { c.rpID = "evil.example.com" }, want: ErrVerification},
+		{name: "sin presencia del usuario", modify: func(c *ceremony) { c.flags = flagUserVerified }, want: ErrVerification},
+		{
+			name:             "sin verificación del usuario en el inicio sin contraseña",
+			userVerification: Required,
+			modify:           func(c *ceremony) { c.flags = flagUserPresent },
+			want:             ErrUserNotVerified,
+		},
+		{name: "firma alterada", raw: func(resp *AssertionResponse) { resp.Signature[len(resp.Signature)-1] ^= 0xff }, want: ErrVerification},
+		{name: "firma de otra llave", raw: func(resp *AssertionResponse) {
+			clientDataHash := sha256.Sum256(resp.ClientDataJSON)
+			resp.Signature = other.sign(t, append(append([]byte{}, resp.AuthenticatorData...), clientDataHash[:]...))
+		}, want: ErrVerification},
+		{name: "datos del autenticador truncados", raw: func(resp *AssertionResponse) {
+			resp.AuthenticatorData = resp.AuthenticatorData[:36]
+		}, want: ErrVerification},
+		{name: "extensiones mal codificadas", raw: func(resp *AssertionResponse) {
+			resp.AuthenticatorData[32] |= flagExtensionData
+			resp.AuthenticatorData = append(resp.AuthenticatorData, 0x5f)
+		}, want: ErrVerification},
+		{name: "contador de firmas que retrocede", storedSignCount: 1 << 20, want: ErrClonedAuthenticator},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCeremony(t, ceremonyGet)
+			challenge := c.challenge
+			if tt.modify != nil {
+				tt.modify(&c)
+			}
+			resp := authenticator.assert(t, c)
+			if tt.raw != nil {
+				tt.raw(&resp)
+			}
+			userVerification := tt.userVerification
+			if userVerification == "" {
+				userVerification = Preferred
+			}
+			if _, err := testConfig.VerifyAssertion(challenge, userVerification, credential.PublicKey, tt.storedSignCount, resp); !errors.Is(err, tt.want) {
+				t.Fatalf("se esperaba %v, se obtuvo %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionAllowsMissingUVAsSecondFactor(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgRS256)
+	registration := newCeremony(t, ceremonyCreate)
+	credential, err := testConfig.VerifyRegistration(registration.challenge, authenticator.register(t, registration))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := newCeremony(t, ceremonyGet)
+	login.flags = flagUserPresent
+	assertion, err := testConfig.VerifyAssertion(login.challenge, Preferred, credential.PublicKey, credential.SignCount, authenticator.assert(t, login))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assertion.UserVerified {
+		t.Fatal("la respuesta no debería marcar al usuario como verificado")
+	}
+}
+
+func TestVerifyAssertionAcceptsAuthenticatorsWithoutCounter(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgES256)
+	registration := newCeremony(t, ceremonyCreate)
+	credential, err := testConfig.VerifyRegistration(registration.challenge, authenticator.register(t, registration))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		login := newCeremony(t, ceremonyGet)
+		authenticator.signCount = ^uint32(0) // assert lo incrementa a cero
+		if _, err := testConfig.VerifyAssertion(login.challenge, Required, credential.PublicKey, 0, authenticator.assert(t, login)); err != nil {
+			t.Fatalf("intento %d: %v", i, err)
+		}
+	}
+}
+
+// Codificación CBOR mínima para armar las respuestas del autenticador
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+}
+
+func cborInt(n int64) []byte {
+	if n < 0 {
+		return cborHead(1, uint64(-1-n))
+	}
+	return cborHead(0, uint64(n))
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, uint64(len(b))), b...)
+}
+
+func cborText(s string) []byte {
+	return append(cborHead(3, uint64(len(s))), s...)
+}
+
+// cborMap codifica un mapa cuyos valores ya están codificados, con las claves
+// en un orden estable
+func cborMap(items map[any][]byte) []byte {
+	keys := make([][]byte, 0, len(items))
+	values := make(map[string][]byte, len(items))
+	for key, value := range items {
+		var encoded []byte
+		switch k := key.(type) {
+		case int64:
+			encoded = cborInt(k)
+		case string:
+			encoded = cborText(k)
+		}
+		keys = append(keys, encoded)
+		values[string(encoded)] = value
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+
+	out := cborHead(5, uint64(len(items)))
+	for _, key := range keys {
+		out = append(out, key...)
+		out = append(out, values[string(key)]...)
+	}
+	return out
+}
+
+func big32(n int) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(n))
+	return bytes.TrimLeft(b, "\x00")
+}
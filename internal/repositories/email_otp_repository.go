@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type EmailOTPRepository interface {
+	// SaveOTP reemplaza el código vigente del usuario para el propósito solo si
+	// el anterior se envió antes de notAfter. Devuelve false si aún no puede reenviarse.
+	SaveOTP(ctx context.Context, otp *domain.EmailOTP, notAfter time.Time) (bool, error)
+	GetOTP(ctx context.Context, userID, purpose string) (*domain.EmailOTP, error)
+	// ReserveOTPAttempt suma un intento al código solo si aún no llegó a
+	// maxAttempts. Devuelve false si ya no quedan intentos o no hay código.
+	ReserveOTPAttempt(ctx context.Context, userID, purpose string, maxAttempts int) (bool, error)
+	DeleteOTP(ctx context.Context, userID, purpose string) error
+}
@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type KnownDeviceRepository interface {
+	// TouchDevice registra el uso del dispositivo y devuelve true si era nuevo
+	TouchDevice(ctx context.Context, device *domain.KnownDevice) (bool, error)
+	CountDevices(ctx context.Context, userID string) (int, error)
+}
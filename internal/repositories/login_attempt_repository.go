@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type LoginAttemptRepository interface {
+	RecordLoginAttempt(ctx context.Context, attempt *domain.LoginAttempt) error
+	// ReserveLoginAttempt aplica LoginThrottle.Reserve de forma atómica y
+	// devuelve el contador resultante, o el actual si hay que esperar
+	ReserveLoginAttempt(ctx context.Context, kind, key string, now, resetBefore time.Time, delays []time.Duration, lockDuration time.Duration) (*domain.LoginThrottle, bool, error)
+	// ReleaseLoginAttempt descuenta un intento reservado que no resultó fallido
+	ReleaseLoginAttempt(ctx context.Context, kind, key string) error
+	DeleteLoginThrottle(ctx context.Context, kind, key string) error
+}
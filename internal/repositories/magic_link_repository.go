@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type MagicLinkRepository interface {
+	CreateMagicLink(ctx context.Context, link *domain.MagicLink) error
+	GetMagicLink(ctx context.Context, id string) (*domain.MagicLink, error)
+	MarkMagicLinkUsed(ctx context.Context, id string) error
+}
@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type MFARepository interface {
+	SaveTOTPFactor(ctx context.Context, factor *domain.TOTPFactor) error
+	GetTOTPFactor(ctx context.Context, userID string) (*domain.TOTPFactor, error)
+	ConfirmTOTPFactor(ctx context.Context, userID string, step int64) error
+	UpdateTOTPLastStep(ctx context.Context, userID string, step int64) error
+	DeleteTOTPFactor(ctx context.Context, userID string) error
+
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codes []*domain.RecoveryCode) error
+	ListUnusedRecoveryCodes(ctx context.Context, userID string) ([]*domain.RecoveryCode, error)
+	MarkRecoveryCodeUsed(ctx context.Context, id string) error
+	DeleteRecoveryCodes(ctx context.Context, userID string) error
+
+	CreateChallenge(ctx context.Context, challenge *domain.MFAChallenge) error
+	GetChallengeByTokenHash(ctx context.Context, tokenHash string) (*domain.MFAChallenge, error)
+	// ReserveChallengeAttempt suma un intento al reto solo si aún no llegó a
+	// maxAttempts. Devuelve false si ya no quedan intentos o el reto no existe.
+	ReserveChallengeAttempt(ctx context.Context, id string, maxAttempts int) (bool, error)
+	DeleteChallenge(ctx context.Context, id string) error
+}
@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client *domain.OAuthClient) error
+	GetClient(ctx context.Context, clientID string) (*domain.OAuthClient, error)
+	ListClients(ctx context.Context) ([]*domain.OAuthClient, error)
+	DeleteClient(ctx context.Context, clientID string) error
+
+	CreateAuthorizationCode(ctx context.Context, code *domain.OAuthAuthorizationCode) error
+	// ConsumeAuthorizationCode elimina y devuelve el código, de modo que solo
+	// puede canjearse una vez
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*domain.OAuthAuthorizationCode, error)
+}
@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type PasswordResetRepository interface {
+	CreateToken(ctx context.Context, token *domain.PasswordResetToken) error
+	GetTokenByHash(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error)
+	MarkTokenUsed(ctx context.Context, id string) error
+	DeleteTokensByUserID(ctx context.Context, userID string) error
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type RevokedTokenRepository interface {
+	// RevokeToken guarda el token revocado; revocarlo de nuevo no es un error
+	RevokeToken(ctx context.Context, token *domain.RevokedToken) error
+	// ListRevokedTokens lista los tokens revocados desde since que aún no expiran
+	ListRevokedTokens(ctx context.Context, since, now time.Time) ([]*domain.RevokedToken, error)
+	DeleteExpiredRevokedTokens(ctx context.Context, now time.Time) (int64, error)
+	// RevokeSessions guarda la revocación; si ya existía, conserva la más reciente
+	RevokeSessions(ctx context.Context, revocation *domain.SessionRevocation) error
+	// ListSessionRevocations lista las revocaciones de sesiones desde since que aún no expiran
+	ListSessionRevocations(ctx context.Context, since, now time.Time) ([]*domain.SessionRevocation, error)
+	DeleteExpiredSessionRevocations(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,11 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type SecurityEventRepository interface {
+	CreateEvent(ctx context.Context, event *domain.SecurityEvent) error
+}
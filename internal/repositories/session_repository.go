@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/kevinhc2110/Auth_UCP/internal/domain"
 )
@@ -10,7 +11,15 @@ type SessionRepository interface {
 	CreateSession(ctx context.Context, session *domain.Session) error
 	GetSessionByID(ctx context.Context, id string) (*domain.Session, error)
 	GetSessionByToken(ctx context.Context, refreshToken string) (*domain.Session, error)
+	ListActiveSessionsByUserID(ctx context.Context, userID string) ([]*domain.Session, error)
+	SearchSessions(ctx context.Context, filter domain.SessionFilter) ([]*domain.Session, error)
 	UpdateSession(ctx context.Context, session *domain.Session) error
+	RotateSession(ctx context.Context, current, next *domain.Session) error
+	SetFamilyBlocked(ctx context.Context, familyID string, blocked bool, reason, blockedBy string) error
 	DeleteSession(ctx context.Context, id string) error
+	DeleteSessionsByFamilyID(ctx context.Context, familyID string) error
 	DeleteSessionsByUserID(ctx context.Context, userID string) error
-}
\ No newline at end of file
+	DeleteOtherSessionsByUserID(ctx context.Context, userID, keepFamilyID string) error
+	// DeleteExpiredSessions elimina las familias cuyo último refresh token expiró antes de now
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type SigningKeyRepository interface {
+	// ListPublishedKeys lista la clave activa y las retiradas aún publicadas
+	ListPublishedKeys(ctx context.Context, now time.Time) ([]*domain.SigningKey, error)
+	// CreateInitialKey guarda la clave solo si no hay otra activa, para que
+	// varias instancias que arrancan a la vez no creen claves distintas
+	CreateInitialKey(ctx context.Context, key *domain.SigningKey) error
+	// RotateKey retira la clave activa y guarda la siguiente. Falla con
+	// ErrSigningKeyRotated si otra instancia ya la rotó.
+	RotateKey(ctx context.Context, currentID string, next *domain.SigningKey, retiredAt, publishedUntil time.Time) error
+	DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error)
+}
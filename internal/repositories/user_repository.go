@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/kevinhc2110/Auth_UCP/internal/domain"
@@ -10,8 +11,12 @@ import (
 type UserRepository interface {
 	Create(ctx context.Context, user *domain.User) error
 	FindByID(ctx context.Context, ID uuid.UUID) (*domain.User, error)
-	FindByIdentification(ctx context.Context, identification string) (*domain.User, error)
+	FindByIdentification(ctx context.Context, documentType, identification string) (*domain.User, error)
 	FindByEmail(ctx context.Context, email string) (*domain.User, error)
 	Update(ctx context.Context, user *domain.User) error
+	UpdateMFAEnabled(ctx context.Context, id uuid.UUID, enabled bool) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, email string, verifiedAt time.Time) error
+	ReserveVerificationEmail(ctx context.Context, id uuid.UUID, sentAt, notAfter time.Time) (bool, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+type WebAuthnRepository interface {
+	CreateCredential(ctx context.Context, credential *domain.WebAuthnCredential) error
+	GetCredentialByCredentialID(ctx context.Context, credentialID string) (*domain.WebAuthnCredential, error)
+	ListCredentialsByUserID(ctx context.Context, userID string) ([]*domain.WebAuthnCredential, error)
+	UpdateCredentialUsage(ctx context.Context, id string, signCount uint32, usedAt time.Time) error
+	DeleteCredential(ctx context.Context, userID, id string) error
+
+	CreateChallenge(ctx context.Context, challenge *domain.WebAuthnChallenge) error
+	GetChallenge(ctx context.Context, id string) (*domain.WebAuthnChallenge, error)
+	DeleteChallenge(ctx context.Context, id string) error
+}
@@ -3,105 +3,382 @@ package usecases
 import (
 	"context"
 	"errors"
+	"log"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/kevinhc2110/Auth_UCP/internal/domain"
 	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security/webauthn"
 	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
 )
 
+const (
+	accessTokenDuration  = 12 * time.Hour
+	refreshTokenDuration = 24 * time.Hour
+	// enrollmentTokenDuration limita el token con el que un usuario obligado a
+	// usar segundo factor lo configura antes de poder entrar
+	enrollmentTokenDuration = 15 * time.Minute
+)
+
+// dummyPasswordHash se compara cuando la cuenta no existe, para que la
+// respuesta tarde lo mismo que con una contraseña incorrecta
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := security.HashPassword("contraseña-de-relleno")
+	return hash
+})
+
+// AuthResult es el resultado de un inicio de sesión. Si el usuario tiene un
+// segundo factor activo, en lugar de tokens se entrega un reto MFA. Si su rol
+// exige segundo factor y aún no lo configuró, solo se entrega un token de
+// registro del segundo factor, sin sesión ni refresh token.
+type AuthResult struct {
+	Session         *domain.Session
+	AccessToken     string
+	MFAToken        string
+	MFAMethods      []string
+	EnrollmentToken string
+	// RecoveryCodesRemaining se informa cuando el login usó un código de recuperación
+	RecoveryCodesRemaining *int
+	RecoveryCodesLow       bool
+}
 
 type AuthUseCase struct {
 	userRepo    repositories.UserRepository
 	sessionRepo repositories.SessionRepository
+	sessions    *SessionUseCase
+	mfa         *MFAUseCase
+	webauthn    *WebAuthnUseCase
+	magicLinks  *MagicLinkUseCase
+	emailOTP    *EmailOTPUseCase
+	alerts      *LoginAlertUseCase
+	lockout     *LoginLockoutUseCase
+	revocations *TokenRevocationUseCase
+	events      *SecurityEventUseCase
 }
 
 // NewAuthUseCase crea una nueva instancia del caso de uso de autenticación
-func NewAuthUseCase(userRepo repositories.UserRepository, sessionRepo repositories.SessionRepository) *AuthUseCase {
+func NewAuthUseCase(userRepo repositories.UserRepository, sessionRepo repositories.SessionRepository, mfa *MFAUseCase, webAuthn *WebAuthnUseCase, magicLinks *MagicLinkUseCase, emailOTP *EmailOTPUseCase, alerts *LoginAlertUseCase, lockout *LoginLockoutUseCase, revocations *TokenRevocationUseCase, events *SecurityEventUseCase) *AuthUseCase {
 	return &AuthUseCase{
 		userRepo:    userRepo,
 		sessionRepo: sessionRepo,
+		sessions:    NewSessionUseCase(sessionRepo, revocations, events),
+		mfa:         mfa,
+		webauthn:    webAuthn,
+		magicLinks:  magicLinks,
+		emailOTP:    emailOTP,
+		alerts:      alerts,
+		lockout:     lockout,
+		revocations: revocations,
+		events:      events,
 	}
 }
 
-// Authenticate valida las credenciales del usuario y genera tokens
-func (uc *AuthUseCase) Authenticate(ctx context.Context, email, password, userAgent, clientIP string) (*domain.Session, string, error) {
-	// Buscar usuario por email
-	user, err := uc.userRepo.FindByEmail(ctx, email)
+// Authenticate valida las credenciales del usuario, identificado por correo o
+// por documento, y genera tokens, o un reto MFA si el usuario tiene activo un
+// segundo factor. Los fallos se cuentan por cuenta y por IP, con demoras
+// crecientes y un bloqueo temporal, igual con ambos identificadores.
+func (uc *AuthUseCase) Authenticate(ctx context.Context, identifier domain.LoginIdentifier, password, userAgent, clientIP string) (*AuthResult, error) {
+	// Buscar usuario por correo o por documento
+	user, err := uc.findLoginUser(ctx, identifier)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			return nil, err
+		}
+		user = nil
+	}
+
+	// El intento se cuenta antes de mirar la contraseña, y la espera se exige
+	// aunque sea la correcta
+	reservation, err := uc.lockout.Reserve(ctx, identifier, user, clientIP)
 	if err != nil {
-		return nil, "", ErrInvalidCredentials
+		return nil, err
+	}
+
+	if user == nil {
+		security.ComparePassword(dummyPasswordHash(), password)
+		if err := uc.lockout.RecordFailure(ctx, reservation, userAgent); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
 	}
 
 	// Verificar contraseña
 	if !security.ComparePassword(user.Password, password) {
-		return nil, "", ErrInvalidCredentials
+		if err := uc.lockout.RecordFailure(ctx, reservation, userAgent); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
 	}
+	if err := uc.lockout.RecordSuccess(ctx, reservation, userAgent); err != nil {
+		return nil, err
+	}
+	uc.upgradePasswordHash(ctx, user, password)
 
-	// Generar token JWT
-	accessToken, err := security.GenerateToken(user.ID.String(), user.Role, 12*time.Hour)
+	// Solo se informa tras validar la contraseña para no revelar qué cuentas existen
+	if !user.EmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+
+	return uc.completeLogin(ctx, user, domain.LoginMethodPassword, userAgent, clientIP)
+}
+
+// upgradePasswordHash vuelve a cifrar la contraseña, ya verificada, si su hash
+// usa un algoritmo o parámetros anteriores. Si falla, el inicio de sesión sigue
+// y se reintenta en el próximo.
+func (uc *AuthUseCase) upgradePasswordHash(ctx context.Context, user *domain.User, password string) {
+	if !security.NeedsRehash(user.Password) {
+		return
+	}
+	hashed, err := security.HashPassword(password)
 	if err != nil {
-		return nil, "", errors.New("error generating access token")
+		log.Printf("Error recifrando la contraseña del usuario %s: %v", user.ID, err)
+		return
+	}
+	if err := uc.userRepo.UpdatePassword(ctx, user.ID, hashed); err != nil {
+		log.Printf("Error actualizando el hash de la contraseña del usuario %s: %v", user.ID, err)
+		return
 	}
+	user.Password = hashed
+}
 
-	// Generar refresh token
-	refreshToken := security.GenerateRefreshToken()
+// findLoginUser busca la cuenta por el correo o por el documento de identidad.
+// Ambos siguen la misma política de bloqueo, sea cual sea la cuenta encontrada,
+// para que el documento no sirva para averiguar el rol de su titular.
+func (uc *AuthUseCase) findLoginUser(ctx context.Context, identifier domain.LoginIdentifier) (*domain.User, error) {
+	if identifier.Email != "" {
+		return uc.userRepo.FindByEmail(ctx, identifier.Email)
+	}
+	return uc.userRepo.FindByIdentification(ctx, identifier.DocumentType, domain.NormalizeIdentification(identifier.Identification))
+}
 
-	// Crear sesión
-	session := &domain.Session{
-		ID:           user.ID.String(),
-		UserID:       user.ID.String(),
-		RefreshToken: refreshToken,
-		UserAgent:    userAgent,
-		ClientIP:     clientIP,
-		IsBlocked:    false,
-		ExpiresAt:    time.Now().Add(12 * time.Hour),
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+// completeLogin termina un inicio de sesión cuyo primer factor ya se validó con
+// firstFactor: emite los tokens o un reto MFA si el usuario tiene activo un
+// segundo factor
+func (uc *AuthUseCase) completeLogin(ctx context.Context, user *domain.User, firstFactor, userAgent, clientIP string) (*AuthResult, error) {
+	if user.MFAEnabled {
+		mfaToken, err := uc.mfa.CreateChallenge(ctx, user, firstFactor, userAgent, clientIP)
+		if err != nil {
+			return nil, err
+		}
+		return &AuthResult{MFAToken: mfaToken, MFAMethods: uc.mfa.Methods(ctx, user, firstFactor)}, nil
+	}
+
+	// Los roles que exigen segundo factor no reciben acceso completo hasta configurarlo
+	if user.RequiresMFA() {
+		enrollmentToken, err := security.GenerateToken(user.ID.String(), user.Role, enrollmentTokenDuration,
+			security.WithScope(domain.ScopeMFAEnrollment), security.WithDocumentType(user.DocumentType))
+		if err != nil {
+			return nil, errors.New("error generating enrollment token")
+		}
+		return &AuthResult{EnrollmentToken: enrollmentToken}, nil
+	}
+
+	session, accessToken, err := uc.issueSession(ctx, user, userAgent, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResult{Session: session, AccessToken: accessToken}, nil
+}
+
+// AuthenticateMagicLink completa un inicio de sesión con un enlace enviado por
+// correo, que reemplaza a la contraseña como primer factor
+func (uc *AuthUseCase) AuthenticateMagicLink(ctx context.Context, token, nonce, userAgent, clientIP string) (*AuthResult, error) {
+	user, err := uc.magicLinks.ConsumeLink(ctx, token, nonce, userAgent, clientIP)
+	if err != nil {
+		return nil, err
 	}
+	return uc.completeLogin(ctx, user, domain.LoginMethodMagicLink, userAgent, clientIP)
+}
+
+// RequestEmailCode envía en segundo plano un código de inicio de sesión al correo
+func (uc *AuthUseCase) RequestEmailCode(ctx context.Context, email, userAgent, clientIP string) {
+	uc.emailOTP.RequestLoginCode(ctx, email, userAgent, clientIP)
+}
 
-	// Guardar sesión en el repositorio
-	err = uc.sessionRepo.CreateSession(ctx, session)
+// AuthenticateEmailCode completa un inicio de sesión con un código enviado por
+// correo, que reemplaza a la contraseña como primer factor
+func (uc *AuthUseCase) AuthenticateEmailCode(ctx context.Context, email, code, userAgent, clientIP string) (*AuthResult, error) {
+	user, err := uc.emailOTP.ConsumeLoginCode(ctx, email, code, userAgent, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	return uc.completeLogin(ctx, user, domain.LoginMethodEmailOTP, userAgent, clientIP)
+}
+
+// SendMFAEmailCode envía el código de respaldo por correo para un reto MFA pendiente
+func (uc *AuthUseCase) SendMFAEmailCode(ctx context.Context, mfaToken, userAgent, clientIP string) error {
+	return uc.mfa.SendEmailCode(ctx, mfaToken, userAgent, clientIP)
+}
+
+// VerifyMFA completa un inicio de sesión pendiente de segundo factor
+func (uc *AuthUseCase) VerifyMFA(ctx context.Context, mfaToken, method, code, userAgent, clientIP string) (*AuthResult, error) {
+	mfaResult, err := uc.mfa.ResolveChallenge(ctx, mfaToken, method, code, userAgent, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	session, accessToken, err := uc.issueSession(ctx, mfaResult.User, userAgent, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResult{
+		Session:                session,
+		AccessToken:            accessToken,
+		RecoveryCodesRemaining: mfaResult.RecoveryCodesRemaining,
+		RecoveryCodesLow:       mfaResult.RecoveryCodesLow(),
+	}, nil
+}
+
+// AuthenticateWebAuthn completa un inicio de sesión con llave de acceso, ya sea
+// sin contraseña o como segundo factor de un reto MFA pendiente
+func (uc *AuthUseCase) AuthenticateWebAuthn(ctx context.Context, challengeID, mfaToken string, resp webauthn.AssertionResponse, userAgent, clientIP string) (*AuthResult, error) {
+	user, err := uc.webauthn.FinishLogin(ctx, challengeID, mfaToken, resp, userAgent, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	session, accessToken, err := uc.issueSession(ctx, user, userAgent, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResult{Session: session, AccessToken: accessToken}, nil
+}
+
+// issueSession crea la sesión del dispositivo y el token de acceso asociado
+func (uc *AuthUseCase) issueSession(ctx context.Context, user *domain.User, userAgent, clientIP string) (*domain.Session, string, error) {
+	// Cada login abre su propia sesión (y familia de refresh tokens), de modo que
+	// el usuario puede mantener sesiones simultáneas en varios dispositivos
+	session, err := uc.sessions.CreateSession(ctx, user.ID.String(), userAgent, clientIP, security.GenerateRefreshToken(), refreshTokenDuration)
 	if err != nil {
 		return nil, "", errors.New("error saving session")
 	}
 
+	// Generar token JWT
+	accessToken, err := security.GenerateToken(user.ID.String(), user.Role, accessTokenDuration,
+		security.WithSessionID(session.FamilyID), security.WithDocumentType(user.DocumentType))
+	if err != nil {
+		return nil, "", errors.New("error generating access token")
+	}
+
+	uc.alerts.NotifyLogin(ctx, user, userAgent, clientIP)
+
 	return session, accessToken, nil
 }
 
-// RefreshToken permite renovar el token de acceso con un refresh token
-func (uc *AuthUseCase) RefreshToken(ctx context.Context, refreshToken string) (string, error) {
+// RefreshToken rota el refresh token: invalida el presentado y emite uno nuevo
+// de la misma familia junto con un nuevo token de acceso. Presentar un token ya
+// rotado revoca toda la familia.
+func (uc *AuthUseCase) RefreshToken(ctx context.Context, refreshToken, userAgent, clientIP string) (*domain.Session, string, error) {
 	session, err := uc.sessionRepo.GetSessionByToken(ctx, refreshToken)
 	if err != nil {
-		return "", ErrSessionNotFound
+		if errors.Is(err, ErrSessionBlocked) || errors.Is(err, ErrSessionExpired) {
+			return nil, "", err
+		}
+		return nil, "", ErrInvalidToken
+	}
+
+	// Un token ya rotado solo puede venir de una copia robada o filtrada
+	if session.RotatedAt != nil {
+		uc.revokeFamily(ctx, session, userAgent, clientIP)
+		return nil, "", ErrRefreshTokenReused
 	}
 
 	// Validar sesión
 	if session.IsBlocked {
-		return "", errors.New("session is blocked")
+		return nil, "", ErrSessionBlocked
 	}
 	if time.Now().After(session.ExpiresAt) {
-		return "", ErrSessionExpired
+		return nil, "", ErrSessionExpired
+	}
+
+	userID, err := uuid.Parse(session.UserID)
+	if err != nil {
+		return nil, "", ErrInvalidSession
+	}
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, "", ErrInvalidSession
+	}
+	// Las sesiones abiertas antes de exigir el segundo factor no se renuevan
+	if user.RequiresMFA() && !user.MFAEnabled {
+		return nil, "", ErrMFAEnrollmentRequired
 	}
 
 	// Generar nuevo token de acceso
-	accessToken, err := security.GenerateToken(session.UserID, "user", 12*time.Hour)
+	accessToken, err := security.GenerateToken(user.ID.String(), user.Role, accessTokenDuration,
+		security.WithSessionID(session.FamilyID), security.WithDocumentType(user.DocumentType))
 	if err != nil {
-		return "", errors.New("error generating new access token")
+		return nil, "", errors.New("error generating new access token")
 	}
 
-	// Actualizar la sesión con una nueva fecha de expiración
-	session.ExpiresAt = time.Now().Add(24 * time.Hour)
-	session.UpdatedAt = time.Now()
+	now := time.Now()
+	session.RotatedAt = &now
+	next := &domain.Session{
+		ID:           uuid.New().String(),
+		FamilyID:     session.FamilyID,
+		UserID:       session.UserID,
+		RefreshToken: security.GenerateRefreshToken(),
+		UserAgent:    userAgent,
+		ClientIP:     clientIP,
+		IsBlocked:    false,
+		ExpiresAt:    now.Add(refreshTokenDuration),
+		CreatedAt:    session.CreatedAt,
+		UpdatedAt:    now,
+	}
 
-	err = uc.sessionRepo.UpdateSession(ctx, session)
-	if err != nil {
-		return "", errors.New("error updating session")
+	if err := uc.sessionRepo.RotateSession(ctx, session, next); err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			uc.revokeFamily(ctx, session, userAgent, clientIP)
+			return nil, "", ErrRefreshTokenReused
+		}
+		return nil, "", errors.New("error updating session")
 	}
 
-	return accessToken, nil
+	return next, accessToken, nil
 }
 
-// Logout elimina la sesión del usuario
-func (uc *AuthUseCase) Logout(ctx context.Context, refreshToken string) error {
-	return uc.sessionRepo.DeleteSession(ctx, refreshToken)
+// Logout elimina la sesión del usuario junto con todos sus refresh tokens
+// rotados, y revoca el token de acceso con el que se pidió y los demás
+// emitidos para la sesión. Un refresh token de otro usuario se trata como
+// inexistente.
+func (uc *AuthUseCase) Logout(ctx context.Context, refreshToken string, accessToken *security.JWTClaims) error {
+	if accessToken != nil {
+		if err := uc.revocations.Revoke(ctx, accessToken); err != nil {
+			return err
+		}
+	}
+
+	session, err := uc.sessionRepo.GetSessionByToken(ctx, refreshToken)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	if accessToken == nil || session.UserID != accessToken.UserID {
+		return ErrSessionNotFound
+	}
+	return uc.sessions.EndSession(ctx, session.FamilyID)
+}
+
+// revokeFamily elimina todos los tokens de la familia, revoca los tokens de
+// acceso emitidos con ellos y registra el evento de reutilización
+func (uc *AuthUseCase) revokeFamily(ctx context.Context, session *domain.Session, userAgent, clientIP string) {
+	details := "se presentó un refresh token ya rotado; familia " + session.FamilyID + " revocada"
+	if err := uc.sessionRepo.DeleteSessionsByFamilyID(ctx, session.FamilyID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		details = "se presentó un refresh token ya rotado; error revocando la familia " + session.FamilyID + ": " + err.Error()
+	}
+	if err := uc.revocations.RevokeSession(ctx, session.FamilyID); err != nil {
+		details = "se presentó un refresh token ya rotado; error revocando los tokens de acceso de la familia " + session.FamilyID + ": " + err.Error()
+	}
+
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		UserID:    session.UserID,
+		Type:      domain.EventRefreshTokenReuse,
+		ClientIP:  clientIP,
+		UserAgent: userAgent,
+		Details:   details,
+	})
 }
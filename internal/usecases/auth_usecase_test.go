@@ -0,0 +1,48 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+// stubSessionRepository busca sesiones por refresh token y registra las
+// familias eliminadas
+type stubSessionRepository struct {
+	repositories.SessionRepository
+	sessions        map[string]*domain.Session
+	deletedFamilies []string
+}
+
+func (r *stubSessionRepository) GetSessionByToken(_ context.Context, refreshToken string) (*domain.Session, error) {
+	session, ok := r.sessions[refreshToken]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (r *stubSessionRepository) DeleteSessionsByFamilyID(_ context.Context, familyID string) error {
+	r.deletedFamilies = append(r.deletedFamilies, familyID)
+	return nil
+}
+
+func TestLogoutRejectsAnotherUsersSession(t *testing.T) {
+	sessionRepo := &stubSessionRepository{sessions: map[string]*domain.Session{
+		"refresh-de-ana": {ID: "s1", FamilyID: "familia-de-ana", UserID: "ana"},
+	}}
+	uc := NewAuthUseCase(nil, sessionRepo, nil, nil, nil, nil, nil, nil, NewTokenRevocationUseCase(nil), nil)
+
+	// Los claims sin jti no llegan al repositorio de revocaciones
+	err := uc.Logout(context.Background(), "refresh-de-ana", &security.JWTClaims{UserID: "luis"})
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("se esperaba ErrSessionNotFound, se obtuvo %v", err)
+	}
+	if len(sessionRepo.deletedFamilies) != 0 {
+		t.Fatalf("se cerró la sesión de otro usuario: %v", sessionRepo.deletedFamilies)
+	}
+}
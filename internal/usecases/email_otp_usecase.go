@@ -0,0 +1,166 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/mailer"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+var (
+	ErrInvalidEmailOTP         = errors.New("código de verificación inválido o expirado")
+	ErrTooManyEmailOTPAttempts = errors.New("demasiados intentos fallidos, solicita un nuevo código")
+	ErrEmailOTPThrottled       = errors.New("espera un momento antes de solicitar otro código")
+)
+
+const (
+	emailOTPDigits         = 6
+	emailOTPDuration       = 10 * time.Minute
+	emailOTPMaxAttempts    = 5
+	emailOTPResendInterval = time.Minute
+)
+
+type EmailOTPUseCase struct {
+	userRepo repositories.UserRepository
+	otpRepo  repositories.EmailOTPRepository
+	mailer   *mailer.Mailer
+	events   *SecurityEventUseCase
+}
+
+// NewEmailOTPUseCase crea una nueva instancia del caso de uso de códigos enviados por correo
+func NewEmailOTPUseCase(userRepo repositories.UserRepository, otpRepo repositories.EmailOTPRepository, mail *mailer.Mailer, events *SecurityEventUseCase) *EmailOTPUseCase {
+	return &EmailOTPUseCase{
+		userRepo: userRepo,
+		otpRepo:  otpRepo,
+		mailer:   mail,
+		events:   events,
+	}
+}
+
+// RequestLoginCode envía en segundo plano un código de inicio de sesión si el
+// correo pertenece a un usuario. No devuelve errores de negocio para no revelar
+// qué correos están registrados; los reenvíos demasiado seguidos se ignoran.
+func (uc *EmailOTPUseCase) RequestLoginCode(ctx context.Context, email, userAgent, clientIP string) {
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		user, err := uc.userRepo.FindByEmail(ctx, email)
+		if err != nil {
+			if !errors.Is(err, ErrUserNotFound) {
+				log.Printf("Error enviando el código de inicio de sesión: %v", err)
+			}
+			return
+		}
+		if err := uc.issue(ctx, user, domain.EmailOTPPurposeLogin, userAgent, clientIP); err != nil && !errors.Is(err, ErrEmailOTPThrottled) {
+			log.Printf("Error enviando el código de inicio de sesión: %v", err)
+		}
+	}()
+}
+
+// SendMFACode envía un código de segundo factor a un usuario cuya contraseña ya
+// fue validada. Devuelve ErrEmailOTPThrottled si el anterior es muy reciente.
+func (uc *EmailOTPUseCase) SendMFACode(ctx context.Context, user *domain.User, userAgent, clientIP string) error {
+	return uc.issue(ctx, user, domain.EmailOTPPurposeMFA, userAgent, clientIP)
+}
+
+// issue genera y envía un código nuevo, que reemplaza al anterior del mismo propósito
+func (uc *EmailOTPUseCase) issue(ctx context.Context, user *domain.User, purpose, userAgent, clientIP string) error {
+	code, err := security.GenerateNumericCode(emailOTPDigits)
+	if err != nil {
+		return errors.New("error al generar el código de verificación")
+	}
+	hashed, err := security.HashPassword(code)
+	if err != nil {
+		return errors.New("error al cifrar el código de verificación")
+	}
+
+	now := time.Now()
+	otp := &domain.EmailOTP{
+		UserID:    user.ID.String(),
+		Purpose:   purpose,
+		CodeHash:  hashed,
+		ExpiresAt: now.Add(emailOTPDuration),
+		CreatedAt: now,
+	}
+	saved, err := uc.otpRepo.SaveOTP(ctx, otp, now.Add(-emailOTPResendInterval))
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return ErrEmailOTPThrottled
+	}
+
+	return uc.mailer.SendTemplate(ctx, user.Email, mailer.TemplateEmailOTP, mailer.Data{
+		Name:      user.Name,
+		Code:      code,
+		ExpiresIn: emailOTPDuration,
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+	})
+}
+
+// VerifyCode valida el código del usuario para el propósito y lo consume. Cada
+// verificación cuenta como intento; al llegar al máximo el código deja de servir.
+func (uc *EmailOTPUseCase) VerifyCode(ctx context.Context, user *domain.User, purpose, code string) error {
+	otp, err := uc.otpRepo.GetOTP(ctx, user.ID.String(), purpose)
+	if err != nil {
+		return err
+	}
+	if time.Now().After(otp.ExpiresAt) {
+		_ = uc.otpRepo.DeleteOTP(ctx, otp.UserID, purpose)
+		return ErrInvalidEmailOTP
+	}
+
+	// El intento se reserva antes de comparar, para que las peticiones
+	// simultáneas no puedan probar más códigos que el máximo
+	reserved, err := uc.otpRepo.ReserveOTPAttempt(ctx, otp.UserID, purpose, emailOTPMaxAttempts)
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		_ = uc.otpRepo.DeleteOTP(ctx, otp.UserID, purpose)
+		return ErrTooManyEmailOTPAttempts
+	}
+
+	if !security.ComparePassword(otp.CodeHash, strings.TrimSpace(code)) {
+		return ErrInvalidEmailOTP
+	}
+
+	// Consumir el código; si otra petición ya lo usó, esta no puede continuar
+	return uc.otpRepo.DeleteOTP(ctx, otp.UserID, purpose)
+}
+
+// ConsumeLoginCode valida un código de inicio de sesión. Devuelve el usuario
+// para que el llamador complete el inicio de sesión.
+func (uc *EmailOTPUseCase) ConsumeLoginCode(ctx context.Context, email, code, userAgent, clientIP string) (*domain.User, error) {
+	user, err := uc.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, ErrInvalidEmailOTP
+	}
+	if err := uc.VerifyCode(ctx, user, domain.EmailOTPPurposeLogin, code); err != nil {
+		return nil, err
+	}
+
+	// Recibir el código demuestra que el correo le pertenece
+	if !user.EmailVerified() {
+		now := time.Now()
+		if err := uc.userRepo.MarkEmailVerified(ctx, user.ID, user.Email, now); err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		UserID:    user.ID.String(),
+		Type:      domain.EventEmailOTPLogin,
+		ClientIP:  clientIP,
+		UserAgent: userAgent,
+		Details:   "inicio de sesión con código enviado por correo",
+	})
+	return user, nil
+}
@@ -0,0 +1,155 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/mailer"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+var (
+	ErrEmailNotVerified        = errors.New("debes verificar tu correo antes de iniciar sesión")
+	ErrInvalidVerificationLink = errors.New("enlace de verificación inválido o expirado")
+)
+
+const (
+	emailVerificationPurpose   = "email_verification"
+	emailVerificationDuration  = 24 * time.Hour
+	verificationResendCooldown = 2 * time.Minute
+)
+
+type EmailVerificationUseCase struct {
+	userRepo  repositories.UserRepository
+	mailer    *mailer.Mailer
+	events    *SecurityEventUseCase
+	secret    []byte
+	verifyURL string
+}
+
+// NewEmailVerificationUseCase crea una nueva instancia del caso de uso de
+// verificación de correo. secret firma los enlaces y verifyURL los recibe.
+func NewEmailVerificationUseCase(userRepo repositories.UserRepository, mail *mailer.Mailer, events *SecurityEventUseCase, secret []byte, verifyURL string) *EmailVerificationUseCase {
+	return &EmailVerificationUseCase{
+		userRepo:  userRepo,
+		mailer:    mail,
+		events:    events,
+		secret:    secret,
+		verifyURL: verifyURL,
+	}
+}
+
+// SendVerification envía en segundo plano el enlace de verificación a un
+// usuario recién registrado
+func (uc *EmailVerificationUseCase) SendVerification(ctx context.Context, user *domain.User) {
+	recipient := *user
+	go func() {
+		if err := uc.sendLink(context.WithoutCancel(ctx), &recipient); err != nil {
+			log.Printf("Error enviando el enlace de verificación: %v", err)
+		}
+	}()
+}
+
+// ResendVerification reenvía el enlace si el correo pertenece a una cuenta sin
+// verificar y no se envió otro hace poco. No informa el resultado para no
+// revelar qué correos están registrados.
+func (uc *EmailVerificationUseCase) ResendVerification(ctx context.Context, email string) {
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		user, err := uc.userRepo.FindByEmail(ctx, email)
+		if err != nil {
+			if !errors.Is(err, ErrUserNotFound) {
+				log.Printf("Error reenviando el enlace de verificación: %v", err)
+			}
+			return
+		}
+		if err := uc.sendLink(ctx, user); err != nil {
+			log.Printf("Error reenviando el enlace de verificación: %v", err)
+		}
+	}()
+}
+
+// VerifyEmail confirma el correo con el token del enlace y da la bienvenida al
+// usuario. El token está atado al correo vigente al emitirlo, así que deja de
+// servir si el correo cambia.
+func (uc *EmailVerificationUseCase) VerifyEmail(ctx context.Context, token, userAgent, clientIP string) error {
+	subject, err := security.VerifyLink(uc.secret, emailVerificationPurpose, token, time.Now())
+	if err != nil {
+		return ErrInvalidVerificationLink
+	}
+	rawID, email, ok := strings.Cut(subject, "|")
+	if !ok {
+		return ErrInvalidVerificationLink
+	}
+	userID, err := uuid.Parse(rawID)
+	if err != nil {
+		return ErrInvalidVerificationLink
+	}
+
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil || user.Email != email {
+		return ErrInvalidVerificationLink
+	}
+	// Abrir el enlace de nuevo no tiene efecto
+	if user.EmailVerified() {
+		return nil
+	}
+
+	if err := uc.userRepo.MarkEmailVerified(ctx, userID, email, time.Now()); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return ErrInvalidVerificationLink
+		}
+		return err
+	}
+
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		UserID:    userID.String(),
+		Type:      domain.EventEmailVerified,
+		ClientIP:  clientIP,
+		UserAgent: userAgent,
+		Details:   "correo verificado",
+	})
+
+	recipient := *user
+	go func() {
+		err := uc.mailer.SendTemplate(context.WithoutCancel(ctx), recipient.Email, mailer.TemplateWelcome, mailer.Data{Name: recipient.Name})
+		if err != nil {
+			log.Printf("Error enviando el correo de bienvenida: %v", err)
+		}
+	}()
+	return nil
+}
+
+// sendLink firma y envía el enlace respetando el tiempo mínimo entre envíos
+func (uc *EmailVerificationUseCase) sendLink(ctx context.Context, user *domain.User) error {
+	if user.EmailVerified() {
+		return nil
+	}
+
+	now := time.Now()
+	reserved, err := uc.userRepo.ReserveVerificationEmail(ctx, user.ID, now, now.Add(-verificationResendCooldown))
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		return nil
+	}
+
+	token, err := security.SignLink(uc.secret, emailVerificationPurpose, user.ID.String()+"|"+user.Email, now.Add(emailVerificationDuration))
+	if err != nil {
+		return err
+	}
+
+	return uc.mailer.SendTemplate(ctx, user.Email, mailer.TemplateVerifyEmail, mailer.Data{
+		Name:      user.Name,
+		Link:      uc.verifyURL + "?token=" + url.QueryEscape(token),
+		ExpiresIn: emailVerificationDuration,
+	})
+}
@@ -0,0 +1,64 @@
+package usecases
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/mailer"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+type LoginAlertUseCase struct {
+	deviceRepo repositories.KnownDeviceRepository
+	mailer     *mailer.Mailer
+}
+
+// NewLoginAlertUseCase crea una nueva instancia del caso de uso de alertas de inicio de sesión
+func NewLoginAlertUseCase(deviceRepo repositories.KnownDeviceRepository, mail *mailer.Mailer) *LoginAlertUseCase {
+	return &LoginAlertUseCase{deviceRepo: deviceRepo, mailer: mail}
+}
+
+// NotifyLogin registra el dispositivo del inicio de sesión y, si el usuario no
+// lo había usado antes, le avisa por correo. El primer dispositivo de la cuenta
+// no genera aviso. Se ejecuta en segundo plano para no demorar el login.
+func (uc *LoginAlertUseCase) NotifyLogin(ctx context.Context, user *domain.User, userAgent, clientIP string) {
+	recipient := *user
+	go func() {
+		if err := uc.notify(context.WithoutCancel(ctx), &recipient, userAgent, clientIP); err != nil {
+			log.Printf("Error enviando la alerta de nuevo dispositivo: %v", err)
+		}
+	}()
+}
+
+func (uc *LoginAlertUseCase) notify(ctx context.Context, user *domain.User, userAgent, clientIP string) error {
+	known, err := uc.deviceRepo.CountDevices(ctx, user.ID.String())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	isNew, err := uc.deviceRepo.TouchDevice(ctx, &domain.KnownDevice{
+		UserID:      user.ID.String(),
+		Fingerprint: security.HashToken(userAgent),
+		UserAgent:   userAgent,
+		LastIP:      clientIP,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	})
+	if err != nil {
+		return err
+	}
+	if !isNew || known == 0 {
+		return nil
+	}
+
+	return uc.mailer.SendTemplate(ctx, user.Email, mailer.TemplateNewDeviceAlert, mailer.Data{
+		Name:      user.Name,
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+		Time:      now,
+	})
+}
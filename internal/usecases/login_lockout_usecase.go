@@ -0,0 +1,297 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+var ErrTooManyLoginAttempts = errors.New("demasiados intentos fallidos, inténtalo de nuevo más tarde")
+
+// LoginThrottledError indica que la cuenta o la IP debe esperar RetryAfter
+// antes de volver a intentar. Es igual exista o no la cuenta.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// LockoutPolicy define cómo se frenan los intentos fallidos. Tras FreeFailures
+// fallos cada intento exige esperar BaseDelay, que se duplica con cada fallo
+// hasta MaxDelay; al llegar a MaxFailures se bloquea durante LockDuration. El
+// contador vuelve a cero tras ResetAfter sin fallos o al terminar el bloqueo.
+type LockoutPolicy struct {
+	FreeFailures int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	MaxFailures  int
+	LockDuration time.Duration
+	ResetAfter   time.Duration
+}
+
+var (
+	// DefaultLockoutPolicy es la política de las cuentas sin un rol más estricto
+	DefaultLockoutPolicy = LockoutPolicy{
+		FreeFailures: 2,
+		BaseDelay:    time.Second,
+		MaxDelay:     30 * time.Second,
+		MaxFailures:  5,
+		LockDuration: 15 * time.Minute,
+		ResetAfter:   time.Hour,
+	}
+	// PrivilegedLockoutPolicy es más estricta, para los roles con acceso a datos sensibles
+	PrivilegedLockoutPolicy = LockoutPolicy{
+		FreeFailures: 1,
+		BaseDelay:    2 * time.Second,
+		MaxDelay:     time.Minute,
+		MaxFailures:  3,
+		LockDuration: 30 * time.Minute,
+		ResetAfter:   24 * time.Hour,
+	}
+	// DefaultIPLockoutPolicy tolera más fallos, porque varios usuarios pueden
+	// compartir una IP, pero frena a quien prueba muchas cuentas desde la misma
+	DefaultIPLockoutPolicy = LockoutPolicy{
+		FreeFailures: 10,
+		BaseDelay:    time.Second,
+		MaxDelay:     30 * time.Second,
+		MaxFailures:  50,
+		LockDuration: 30 * time.Minute,
+		ResetAfter:   time.Hour,
+	}
+)
+
+// delay devuelve la espera exigida tras el fallo número failures
+func (p LockoutPolicy) delay(failures int) time.Duration {
+	if failures <= p.FreeFailures || p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeFailures + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// delays devuelve la espera exigida tras cada fallo, hasta el que bloquea
+func (p LockoutPolicy) delays() []time.Duration {
+	delays := make([]time.Duration, max(p.MaxFailures, 0))
+	for i := range delays {
+		delays[i] = p.delay(i + 1)
+	}
+	return delays
+}
+
+type LoginLockoutUseCase struct {
+	repo          repositories.LoginAttemptRepository
+	userRepo      repositories.UserRepository
+	events        *SecurityEventUseCase
+	accountPolicy LockoutPolicy
+	ipPolicy      LockoutPolicy
+}
+
+// NewLoginLockoutUseCase crea una nueva instancia del caso de uso de bloqueo
+// por intentos fallidos. Todos los identificadores, existan o no, siguen la
+// política más estricta entre defaultPolicy y las de rolePolicies: si la
+// espera dependiera del rol, unos pocos fallos revelarían si un correo o un
+// documento pertenece a un administrador o a un médico. ipPolicy se aplica por IP.
+func NewLoginLockoutUseCase(repo repositories.LoginAttemptRepository, userRepo repositories.UserRepository, events *SecurityEventUseCase, defaultPolicy LockoutPolicy, rolePolicies map[string]LockoutPolicy, ipPolicy LockoutPolicy) *LoginLockoutUseCase {
+	accountPolicy := defaultPolicy
+	for _, policy := range rolePolicies {
+		accountPolicy = stricterPolicy(accountPolicy, policy)
+	}
+	return &LoginLockoutUseCase{
+		repo:          repo,
+		userRepo:      userRepo,
+		events:        events,
+		accountPolicy: accountPolicy,
+		ipPolicy:      ipPolicy,
+	}
+}
+
+// stricterPolicy combina dos políticas en una que tras cada fallo exige al
+// menos la espera de ambas y bloquea en cuanto lo haría cualquiera de ellas
+func stricterPolicy(a, b LockoutPolicy) LockoutPolicy {
+	return LockoutPolicy{
+		FreeFailures: min(a.FreeFailures, b.FreeFailures),
+		BaseDelay:    max(a.BaseDelay, b.BaseDelay),
+		MaxDelay:     max(a.MaxDelay, b.MaxDelay),
+		MaxFailures:  min(a.MaxFailures, b.MaxFailures),
+		LockDuration: max(a.LockDuration, b.LockDuration),
+		ResetAfter:   max(a.ResetAfter, b.ResetAfter),
+	}
+}
+
+// LoginReservation es un intento de inicio de sesión que Reserve ya contó
+// como fallido. Tras verificar la contraseña se confirma con RecordFailure o
+// se descuenta con RecordSuccess.
+type LoginReservation struct {
+	identifier domain.LoginIdentifier
+	user       *domain.User
+	clientIP   string
+	throttles  map[string]*domain.LoginThrottle
+}
+
+// Reserve cuenta el intento como fallido antes de verificar la contraseña, o
+// falla con un LoginThrottledError si la cuenta o la IP todavía deben esperar.
+// Reservar primero evita que varias peticiones simultáneas verifiquen la
+// contraseña con el mismo contador. La cuenta se cuenta por el identificador
+// usado, exista o no, y cada identificador lleva su propio contador:
+// compartirlo entre el correo y el documento de una misma cuenta revelaría
+// que ambos le pertenecen. user es nil si el identificador no corresponde a
+// ninguna cuenta.
+func (uc *LoginLockoutUseCase) Reserve(ctx context.Context, identifier domain.LoginIdentifier, user *domain.User, clientIP string) (*LoginReservation, error) {
+	reservation := &LoginReservation{
+		identifier: identifier,
+		user:       user,
+		clientIP:   clientIP,
+		throttles:  map[string]*domain.LoginThrottle{},
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for kind, key := range throttleKeys(identifier, clientIP) {
+		policy := uc.policy(kind)
+		throttle, reserved, err := uc.repo.ReserveLoginAttempt(ctx, kind, key, now, now.Add(-policy.ResetAfter), policy.delays(), policy.LockDuration)
+		if err != nil {
+			uc.release(ctx, reservation)
+			return nil, err
+		}
+		if !reserved {
+			wait = max(wait, throttle.RetryAfter(now))
+			continue
+		}
+		reservation.throttles[kind] = throttle
+	}
+
+	// Un intento rechazado no cuenta en ninguno de los contadores
+	if wait > 0 {
+		uc.release(ctx, reservation)
+		return nil, &LoginThrottledError{RetryAfter: wait}
+	}
+	return reservation, nil
+}
+
+// RecordFailure registra el intento reservado como fallido y el bloqueo que
+// haya provocado
+func (uc *LoginLockoutUseCase) RecordFailure(ctx context.Context, reservation *LoginReservation, userAgent string) error {
+	if err := uc.recordAttempt(ctx, reservation, userAgent, false); err != nil {
+		return err
+	}
+
+	for kind, throttle := range reservation.throttles {
+		if throttle.LockedUntil != nil && throttle.Failures == uc.policy(kind).MaxFailures {
+			uc.recordLock(ctx, kind, reservation.user, userAgent, reservation.clientIP, throttle.Failures, *throttle.LockedUntil)
+		}
+	}
+	return nil
+}
+
+// RecordSuccess registra un inicio de sesión con la contraseña correcta y
+// reinicia el contador del identificador usado. En el de la IP solo se
+// descuenta el intento reservado, para que una cuenta propia no sirva para
+// seguir probando las ajenas.
+func (uc *LoginLockoutUseCase) RecordSuccess(ctx context.Context, reservation *LoginReservation, userAgent string) error {
+	if err := uc.recordAttempt(ctx, reservation, userAgent, true); err != nil {
+		return err
+	}
+	if err := uc.repo.DeleteLoginThrottle(ctx, domain.LoginThrottleAccount, reservation.identifier.Key()); err != nil {
+		return err
+	}
+	return uc.repo.ReleaseLoginAttempt(ctx, domain.LoginThrottleIP, reservation.clientIP)
+}
+
+// release descuenta los contadores ya reservados de un intento que no llegó a verificarse
+func (uc *LoginLockoutUseCase) release(ctx context.Context, reservation *LoginReservation) {
+	keys := throttleKeys(reservation.identifier, reservation.clientIP)
+	for kind := range reservation.throttles {
+		if err := uc.repo.ReleaseLoginAttempt(ctx, kind, keys[kind]); err != nil {
+			log.Printf("Error descontando el intento de inicio de sesión: %v", err)
+		}
+	}
+}
+
+// Unlock desbloquea la cuenta de un usuario antes de que termine el bloqueo. Uso exclusivo de administradores.
+func (uc *LoginLockoutUseCase) Unlock(ctx context.Context, actor Actor, userID uuid.UUID) error {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, identifier := range user.LoginIdentifiers() {
+		if err := uc.repo.DeleteLoginThrottle(ctx, domain.LoginThrottleAccount, identifier.Key()); err != nil {
+			return err
+		}
+	}
+
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		UserID:    user.ID.String(),
+		ActorID:   actor.UserID,
+		Type:      domain.EventAccountUnlocked,
+		ClientIP:  actor.ClientIP,
+		UserAgent: actor.UserAgent,
+		Details:   "cuenta desbloqueada por un administrador",
+	})
+	return nil
+}
+
+// policy devuelve la política del contador: la de la IP o la de las cuentas,
+// que no depende de si el identificador existe ni de su rol
+func (uc *LoginLockoutUseCase) policy(kind string) LockoutPolicy {
+	if kind == domain.LoginThrottleIP {
+		return uc.ipPolicy
+	}
+	return uc.accountPolicy
+}
+
+func (uc *LoginLockoutUseCase) recordAttempt(ctx context.Context, reservation *LoginReservation, userAgent string, success bool) error {
+	attempt := &domain.LoginAttempt{
+		ID:         uuid.New().String(),
+		Identifier: reservation.identifier.Key(),
+		ClientIP:   reservation.clientIP,
+		UserAgent:  userAgent,
+		Success:    success,
+		CreatedAt:  time.Now(),
+	}
+	if reservation.user != nil {
+		attempt.UserID = reservation.user.ID.String()
+	}
+	return uc.repo.RecordLoginAttempt(ctx, attempt)
+}
+
+func (uc *LoginLockoutUseCase) recordLock(ctx context.Context, kind string, user *domain.User, userAgent, clientIP string, failures int, lockedUntil time.Time) {
+	event := &domain.SecurityEvent{
+		Type:      domain.EventIPLocked,
+		ClientIP:  clientIP,
+		UserAgent: userAgent,
+		Details:   fmt.Sprintf("IP %s bloqueada hasta %s tras %d intentos fallidos", clientIP, lockedUntil.Format(time.RFC3339), failures),
+	}
+	if kind == domain.LoginThrottleAccount {
+		// Los identificadores que no existen también se bloquean, pero no hay a quién asociar el evento
+		if user == nil {
+			return
+		}
+		event.UserID = user.ID.String()
+		event.Type = domain.EventAccountLocked
+		event.Details = fmt.Sprintf("cuenta bloqueada hasta %s tras %d intentos fallidos", lockedUntil.Format(time.RFC3339), failures)
+	}
+	uc.events.Record(ctx, event)
+}
+
+// throttleKeys devuelve los contadores que aplican a un intento: la cuenta y la IP
+func throttleKeys(identifier domain.LoginIdentifier, clientIP string) map[string]string {
+	return map[string]string{
+		domain.LoginThrottleAccount: identifier.Key(),
+		domain.LoginThrottleIP:      clientIP,
+	}
+}
@@ -0,0 +1,237 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+func TestLockoutPolicyDelay(t *testing.T) {
+	policy := LockoutPolicy{
+		FreeFailures: 2,
+		BaseDelay:    time.Second,
+		MaxDelay:     10 * time.Second,
+		MaxFailures:  8,
+		LockDuration: 15 * time.Minute,
+	}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 1, want: 0},
+		{failures: 2, want: 0},
+		{failures: 3, want: time.Second},
+		{failures: 4, want: 2 * time.Second},
+		{failures: 5, want: 4 * time.Second},
+		{failures: 6, want: 8 * time.Second},
+		{failures: 7, want: 10 * time.Second},
+		{failures: 100, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := policy.delay(tt.failures); got != tt.want {
+			t.Fatalf("fallo %d: se esperaba %v, se obtuvo %v", tt.failures, tt.want, got)
+		}
+	}
+}
+
+func TestLockoutPolicyDelayWithoutBase(t *testing.T) {
+	policy := LockoutPolicy{FreeFailures: 0, MaxDelay: time.Minute, MaxFailures: 3}
+	for failures := 0; failures <= 5; failures++ {
+		if got := policy.delay(failures); got != 0 {
+			t.Fatalf("fallo %d: se esperaba 0, se obtuvo %v", failures, got)
+		}
+	}
+}
+
+func TestLockoutPolicyDelays(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy LockoutPolicy
+		want   []time.Duration
+	}{
+		{
+			name:   "por defecto",
+			policy: DefaultLockoutPolicy,
+			want:   []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:   "privilegiada",
+			policy: PrivilegedLockoutPolicy,
+			want:   []time.Duration{0, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:   "sin fallos permitidos",
+			policy: LockoutPolicy{BaseDelay: time.Second, MaxDelay: time.Minute},
+			want:   []time.Duration{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.delays(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("se esperaba %v, se obtuvo %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStricterPolicy(t *testing.T) {
+	got := stricterPolicy(DefaultLockoutPolicy, PrivilegedLockoutPolicy)
+	want := LockoutPolicy{
+		FreeFailures: 1,
+		BaseDelay:    2 * time.Second,
+		MaxDelay:     time.Minute,
+		MaxFailures:  3,
+		LockDuration: 30 * time.Minute,
+		ResetAfter:   24 * time.Hour,
+	}
+	if got != want {
+		t.Fatalf("se esperaba %+v, se obtuvo %+v", want, got)
+	}
+
+	// Tras cada fallo la espera combinada no es menor que la de ninguna de las dos
+	for failures := 1; failures <= 10; failures++ {
+		if got.delay(failures) < DefaultLockoutPolicy.delay(failures) || got.delay(failures) < PrivilegedLockoutPolicy.delay(failures) {
+			t.Fatalf("fallo %d: espera combinada %v menor que la de una política", failures, got.delay(failures))
+		}
+	}
+}
+
+// memoryLoginAttemptRepository guarda los contadores en memoria
+type memoryLoginAttemptRepository struct {
+	throttles map[string]*domain.LoginThrottle
+}
+
+func newMemoryLoginAttemptRepository() *memoryLoginAttemptRepository {
+	return &memoryLoginAttemptRepository{throttles: map[string]*domain.LoginThrottle{}}
+}
+
+func (r *memoryLoginAttemptRepository) RecordLoginAttempt(context.Context, *domain.LoginAttempt) error {
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) ReserveLoginAttempt(_ context.Context, kind, key string, now, resetBefore time.Time, delays []time.Duration, lockDuration time.Duration) (*domain.LoginThrottle, bool, error) {
+	throttle, ok := r.throttles[kind+":"+key]
+	if !ok {
+		throttle = &domain.LoginThrottle{Kind: kind, Key: key, UpdatedAt: now}
+		r.throttles[kind+":"+key] = throttle
+	}
+	reserved := throttle.Reserve(now, resetBefore, delays, lockDuration)
+	copied := *throttle
+	return &copied, reserved, nil
+}
+
+func (r *memoryLoginAttemptRepository) ReleaseLoginAttempt(_ context.Context, kind, key string) error {
+	if throttle, ok := r.throttles[kind+":"+key]; ok && throttle.Failures > 0 {
+		throttle.Failures--
+	}
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) DeleteLoginThrottle(_ context.Context, kind, key string) error {
+	delete(r.throttles, kind+":"+key)
+	return nil
+}
+
+// elapse adelanta el reloj de los contadores: equivale a que pase d
+func (r *memoryLoginAttemptRepository) elapse(d time.Duration) {
+	for _, throttle := range r.throttles {
+		throttle.UpdatedAt = throttle.UpdatedAt.Add(-d)
+		if throttle.NextAttemptAt != nil {
+			next := throttle.NextAttemptAt.Add(-d)
+			throttle.NextAttemptAt = &next
+		}
+		if throttle.LockedUntil != nil {
+			until := throttle.LockedUntil.Add(-d)
+			throttle.LockedUntil = &until
+		}
+	}
+}
+
+type discardSecurityEvents struct{}
+
+func (discardSecurityEvents) CreateEvent(context.Context, *domain.SecurityEvent) error {
+	return nil
+}
+
+// retryAfterSequence falla la contraseña attempts veces seguidas, esperando
+// cada vez lo que se pida, y devuelve el Retry-After en segundos que vio el
+// cliente antes de cada intento, o 0 si no tuvo que esperar
+func retryAfterSequence(t *testing.T, identifier domain.LoginIdentifier, user *domain.User, attempts int) []int {
+	t.Helper()
+	repo := newMemoryLoginAttemptRepository()
+	rolePolicies := map[string]LockoutPolicy{
+		string(domain.RoleUser):   DefaultLockoutPolicy,
+		string(domain.RoleAdmin):  PrivilegedLockoutPolicy,
+		string(domain.RoleDoctor): PrivilegedLockoutPolicy,
+	}
+	uc := NewLoginLockoutUseCase(repo, nil, NewSecurityEventUseCase(discardSecurityEvents{}), DefaultLockoutPolicy, rolePolicies, DefaultIPLockoutPolicy)
+	ctx := context.Background()
+
+	var waits []int
+	for i := 0; i < attempts; i++ {
+		wait := 0
+		reservation, err := uc.Reserve(ctx, identifier, user, "203.0.113.5")
+		var throttled *LoginThrottledError
+		if errors.As(err, &throttled) {
+			wait = int(math.Ceil(throttled.RetryAfter.Seconds()))
+			repo.elapse(throttled.RetryAfter + time.Millisecond)
+			reservation, err = uc.Reserve(ctx, identifier, user, "203.0.113.5")
+		}
+		if err != nil {
+			t.Fatalf("intento %d rechazado tras esperar: %v", i+1, err)
+		}
+		if err := uc.RecordFailure(ctx, reservation, "test"); err != nil {
+			t.Fatalf("error inesperado: %v", err)
+		}
+		waits = append(waits, wait)
+	}
+	return waits
+}
+
+func TestLockoutDoesNotRevealAccounts(t *testing.T) {
+	admin := &domain.User{ID: uuid.New(), Role: string(domain.RoleAdmin), Email: "admin@example.com", DocumentType: domain.DocumentTypeCC, Identification: "1000000001"}
+	doctor := &domain.User{ID: uuid.New(), Role: string(domain.RoleDoctor), Email: "doctor@example.com", DocumentType: domain.DocumentTypeCC, Identification: "1000000002"}
+	patient := &domain.User{ID: uuid.New(), Role: string(domain.RoleUser), Email: "paciente@example.com", DocumentType: domain.DocumentTypeCC, Identification: "1000000003"}
+
+	// Dos ciclos completos de bloqueo con la política combinada
+	attempts := 2 * PrivilegedLockoutPolicy.MaxFailures
+	want := retryAfterSequence(t, domain.LoginIdentifier{Email: "nadie@example.com"}, nil, attempts)
+
+	tests := []struct {
+		name       string
+		identifier domain.LoginIdentifier
+		user       *domain.User
+	}{
+		{name: "correo de administrador", identifier: domain.LoginIdentifier{Email: admin.Email}, user: admin},
+		{name: "correo de médico", identifier: domain.LoginIdentifier{Email: doctor.Email}, user: doctor},
+		{name: "correo de paciente", identifier: domain.LoginIdentifier{Email: patient.Email}, user: patient},
+		{name: "documento inexistente", identifier: domain.LoginIdentifier{DocumentType: domain.DocumentTypeCC, Identification: "1999999999"}},
+		{name: "documento de administrador", identifier: domain.LoginIdentifier{DocumentType: admin.DocumentType, Identification: admin.Identification}, user: admin},
+		{name: "documento de médico", identifier: domain.LoginIdentifier{DocumentType: doctor.DocumentType, Identification: doctor.Identification}, user: doctor},
+		{name: "documento de paciente", identifier: domain.LoginIdentifier{DocumentType: patient.DocumentType, Identification: patient.Identification}, user: patient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAfterSequence(t, tt.identifier, tt.user, attempts); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Retry-After %v distinto del de un identificador inexistente %v", got, want)
+			}
+		})
+	}
+
+	// La secuencia es la de la política más estricta: bloqueo al tercer fallo
+	lock := int(PrivilegedLockoutPolicy.LockDuration.Seconds())
+	if expected := []int{0, 0, 2, lock, 0, 2}; !reflect.DeepEqual(want, expected) {
+		t.Fatalf("se esperaba %v, se obtuvo %v", expected, want)
+	}
+}
@@ -0,0 +1,153 @@
+package usecases
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/mailer"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+var (
+	ErrInvalidMagicLink    = errors.New("enlace de inicio de sesión inválido o expirado")
+	ErrMagicLinkOtherAgent = errors.New("abre el enlace en el mismo navegador en el que lo solicitaste")
+)
+
+const (
+	magicLinkPurpose  = "magic_link"
+	MagicLinkDuration = 10 * time.Minute
+)
+
+type MagicLinkUseCase struct {
+	userRepo repositories.UserRepository
+	linkRepo repositories.MagicLinkRepository
+	mailer   *mailer.Mailer
+	events   *SecurityEventUseCase
+	secret   []byte
+	loginURL string
+}
+
+// NewMagicLinkUseCase crea una nueva instancia del caso de uso de enlaces de
+// inicio de sesión. secret firma los enlaces y loginURL los recibe.
+func NewMagicLinkUseCase(userRepo repositories.UserRepository, linkRepo repositories.MagicLinkRepository, mail *mailer.Mailer, events *SecurityEventUseCase, secret []byte, loginURL string) *MagicLinkUseCase {
+	return &MagicLinkUseCase{
+		userRepo: userRepo,
+		linkRepo: linkRepo,
+		mailer:   mail,
+		events:   events,
+		secret:   secret,
+		loginURL: loginURL,
+	}
+}
+
+// RequestLink genera el nonce que el navegador debe guardar y envía en segundo
+// plano el enlace si el correo pertenece a un usuario. El nonce se devuelve
+// siempre, de modo que la respuesta no revela si el correo está registrado.
+func (uc *MagicLinkUseCase) RequestLink(ctx context.Context, email, userAgent, clientIP string) (string, error) {
+	nonce, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return "", errors.New("error al generar el enlace de inicio de sesión")
+	}
+
+	go func() {
+		if err := uc.sendLink(context.WithoutCancel(ctx), email, nonce, userAgent, clientIP); err != nil {
+			log.Printf("Error enviando el enlace de inicio de sesión: %v", err)
+		}
+	}()
+	return nonce, nil
+}
+
+func (uc *MagicLinkUseCase) sendLink(ctx context.Context, email, nonce, userAgent, clientIP string) error {
+	user, err := uc.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	link := &domain.MagicLink{
+		ID:        uuid.New().String(),
+		UserID:    user.ID.String(),
+		NonceHash: security.HashToken(nonce),
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+		ExpiresAt: time.Now().Add(MagicLinkDuration),
+		CreatedAt: time.Now(),
+	}
+	if err := uc.linkRepo.CreateMagicLink(ctx, link); err != nil {
+		return err
+	}
+
+	token, err := security.SignLink(uc.secret, magicLinkPurpose, link.ID, link.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return uc.mailer.SendTemplate(ctx, user.Email, mailer.TemplateMagicLink, mailer.Data{
+		Name:      user.Name,
+		Link:      uc.loginURL + "?token=" + url.QueryEscape(token),
+		ExpiresIn: MagicLinkDuration,
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+	})
+}
+
+// ConsumeLink valida el enlace y el nonce del navegador y lo consume. Devuelve
+// el usuario para que el llamador complete el inicio de sesión.
+func (uc *MagicLinkUseCase) ConsumeLink(ctx context.Context, token, nonce, userAgent, clientIP string) (*domain.User, error) {
+	id, err := security.VerifyLink(uc.secret, magicLinkPurpose, token, time.Now())
+	if err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+	link, err := uc.linkRepo.GetMagicLink(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if link.UsedAt != nil || time.Now().After(link.ExpiresAt) {
+		return nil, ErrInvalidMagicLink
+	}
+
+	// Un enlace reenviado o interceptado no sirve sin la cookie del navegador original
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(security.HashToken(nonce)), []byte(link.NonceHash)) != 1 {
+		return nil, ErrMagicLinkOtherAgent
+	}
+
+	if err := uc.linkRepo.MarkMagicLinkUsed(ctx, link.ID); err != nil {
+		return nil, err
+	}
+
+	userID, err := uuid.Parse(link.UserID)
+	if err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+
+	// Recibir el enlace demuestra que el correo le pertenece
+	if !user.EmailVerified() {
+		now := time.Now()
+		if err := uc.userRepo.MarkEmailVerified(ctx, user.ID, user.Email, now); err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		UserID:    user.ID.String(),
+		Type:      domain.EventMagicLinkUsed,
+		ClientIP:  clientIP,
+		UserAgent: userAgent,
+		Details:   "inicio de sesión con enlace enviado por correo",
+	})
+	return user, nil
+}
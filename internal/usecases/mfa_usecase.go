@@ -0,0 +1,471 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("el segundo factor ya está activo")
+	ErrMFANotEnrolled      = errors.New("no hay un segundo factor enrolado")
+	ErrInvalidMFACode      = errors.New("código de verificación inválido")
+	ErrInvalidMFAChallenge = errors.New("reto de segundo factor inválido o expirado")
+	ErrTooManyMFAAttempts  = errors.New("demasiados intentos fallidos, inicia sesión nuevamente")
+	ErrUnsupportedMFA      = errors.New("método de segundo factor no soportado")
+	// ErrMFAEnrollmentRequired indica que el rol exige segundo factor y el usuario aún no lo configuró
+	ErrMFAEnrollmentRequired = errors.New("debes configurar un segundo factor para continuar")
+)
+
+const (
+	mfaChallengeDuration    = 5 * time.Minute
+	mfaChallengeMaxAttempts = 5
+
+	recoveryCodeCount       = 10
+	recoveryCodesLowWarning = 3
+)
+
+// MFAResult es el resultado de resolver un reto MFA
+type MFAResult struct {
+	User *domain.User
+	// RecoveryCodesRemaining solo se informa cuando se usó un código de recuperación
+	RecoveryCodesRemaining *int
+}
+
+// RecoveryCodesLow indica si conviene advertir al usuario que genere nuevos códigos
+func (r *MFAResult) RecoveryCodesLow() bool {
+	return r.RecoveryCodesRemaining != nil && *r.RecoveryCodesRemaining <= recoveryCodesLowWarning
+}
+
+type MFAUseCase struct {
+	userRepo     repositories.UserRepository
+	mfaRepo      repositories.MFARepository
+	webauthnRepo repositories.WebAuthnRepository
+	emailOTP     *EmailOTPUseCase
+	events       *SecurityEventUseCase
+	issuer       string
+}
+
+// NewMFAUseCase crea una nueva instancia del caso de uso de segundo factor
+func NewMFAUseCase(userRepo repositories.UserRepository, mfaRepo repositories.MFARepository, webauthnRepo repositories.WebAuthnRepository, emailOTP *EmailOTPUseCase, events *SecurityEventUseCase, issuer string) *MFAUseCase {
+	return &MFAUseCase{
+		userRepo:     userRepo,
+		mfaRepo:      mfaRepo,
+		webauthnRepo: webauthnRepo,
+		emailOTP:     emailOTP,
+		events:       events,
+		issuer:       issuer,
+	}
+}
+
+// EnrollTOTP genera un nuevo secreto TOTP pendiente de confirmación y el URI
+// otpauth:// que la aplicación cliente muestra como código QR
+func (uc *MFAUseCase) EnrollTOTP(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", "", ErrUserNotFound
+	}
+	if factor, err := uc.mfaRepo.GetTOTPFactor(ctx, user.ID.String()); err == nil && factor.ConfirmedAt != nil {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", errors.New("error al generar el secreto TOTP")
+	}
+
+	factor := &domain.TOTPFactor{
+		UserID:    user.ID.String(),
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+	if err := uc.mfaRepo.SaveTOTPFactor(ctx, factor); err != nil {
+		return "", "", err
+	}
+
+	return secret, security.TOTPURI(uc.issuer, user.Email, secret), nil
+}
+
+// ConfirmTOTP activa el segundo factor tras validar el primer código generado.
+// Devuelve los códigos de recuperación si es el primer factor del usuario; solo
+// se muestran esta vez.
+func (uc *MFAUseCase) ConfirmTOTP(ctx context.Context, actor Actor, userID uuid.UUID, code string) ([]string, error) {
+	factor, err := uc.mfaRepo.GetTOTPFactor(ctx, userID.String())
+	if err != nil {
+		return nil, err
+	}
+	if factor.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := security.ValidateTOTP(factor.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	if err := uc.mfaRepo.ConfirmTOTPFactor(ctx, userID.String(), step); err != nil {
+		return nil, err
+	}
+	uc.recordEvent(ctx, actor, userID.String(), domain.EventMFAEnabled, "segundo factor TOTP activado")
+
+	return uc.EnableSecondFactor(ctx, actor, userID)
+}
+
+// DisableTOTP desactiva el segundo factor. Exige un código válido para evitar
+// que un token de acceso robado baste para quitar la protección.
+func (uc *MFAUseCase) DisableTOTP(ctx context.Context, actor Actor, userID uuid.UUID, code string) error {
+	factor, err := uc.mfaRepo.GetTOTPFactor(ctx, userID.String())
+	if err != nil {
+		return err
+	}
+	if factor.ConfirmedAt == nil {
+		return ErrMFANotEnrolled
+	}
+	if err := uc.verifyTOTP(ctx, factor, code); err != nil {
+		return err
+	}
+
+	if err := uc.mfaRepo.DeleteTOTPFactor(ctx, userID.String()); err != nil {
+		return err
+	}
+	uc.recordEvent(ctx, actor, userID.String(), domain.EventMFADisabled, "segundo factor TOTP desactivado")
+
+	return uc.SecondFactorRemoved(ctx, userID)
+}
+
+// EnableSecondFactor marca al usuario como protegido por segundo factor. Si aún
+// no tiene códigos de recuperación, genera y devuelve un juego nuevo.
+func (uc *MFAUseCase) EnableSecondFactor(ctx context.Context, actor Actor, userID uuid.UUID) ([]string, error) {
+	if err := uc.userRepo.UpdateMFAEnabled(ctx, userID, true); err != nil {
+		return nil, err
+	}
+
+	remaining, err := uc.RecoveryCodesRemaining(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if remaining > 0 {
+		return nil, nil
+	}
+	return uc.issueRecoveryCodes(ctx, actor, userID.String())
+}
+
+// SecondFactorRemoved desactiva la exigencia de segundo factor y elimina los
+// códigos de recuperación cuando el usuario ya no tiene ningún factor activo
+func (uc *MFAUseCase) SecondFactorRemoved(ctx context.Context, userID uuid.UUID) error {
+	if factor, err := uc.mfaRepo.GetTOTPFactor(ctx, userID.String()); err == nil && factor.ConfirmedAt != nil {
+		return nil
+	}
+	credentials, err := uc.webauthnRepo.ListCredentialsByUserID(ctx, userID.String())
+	if err != nil {
+		return err
+	}
+	if len(credentials) > 0 {
+		return nil
+	}
+
+	if err := uc.mfaRepo.DeleteRecoveryCodes(ctx, userID.String()); err != nil {
+		return err
+	}
+	return uc.userRepo.UpdateMFAEnabled(ctx, userID, false)
+}
+
+// RegenerateRecoveryCodes invalida los códigos de recuperación anteriores y
+// genera un nuevo juego. Exige la contraseña del usuario.
+func (uc *MFAUseCase) RegenerateRecoveryCodes(ctx context.Context, actor Actor, userID uuid.UUID, password string) ([]string, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !security.ComparePassword(user.Password, password) {
+		return nil, ErrInvalidCredentials
+	}
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnrolled
+	}
+
+	return uc.issueRecoveryCodes(ctx, actor, user.ID.String())
+}
+
+// RecoveryCodesRemaining cuenta los códigos de recuperación sin usar del usuario
+func (uc *MFAUseCase) RecoveryCodesRemaining(ctx context.Context, userID uuid.UUID) (int, error) {
+	codes, err := uc.mfaRepo.ListUnusedRecoveryCodes(ctx, userID.String())
+	if err != nil {
+		return 0, err
+	}
+	return len(codes), nil
+}
+
+// issueRecoveryCodes genera y guarda hasheado un nuevo juego de códigos de recuperación
+func (uc *MFAUseCase) issueRecoveryCodes(ctx context.Context, actor Actor, userID string) ([]string, error) {
+	plain := make([]string, 0, recoveryCodeCount)
+	codes := make([]*domain.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := security.GenerateRecoveryCode()
+		if err != nil {
+			return nil, errors.New("error al generar los códigos de recuperación")
+		}
+		hashed, err := security.HashPassword(code)
+		if err != nil {
+			return nil, errors.New("error al cifrar los códigos de recuperación")
+		}
+		plain = append(plain, code)
+		codes = append(codes, &domain.RecoveryCode{
+			ID:         uuid.New().String(),
+			UserID:     userID,
+			CodeHash:   hashed,
+			CodePrefix: security.RecoveryCodePrefix(code),
+			CreatedAt:  time.Now(),
+		})
+	}
+
+	if err := uc.mfaRepo.ReplaceRecoveryCodes(ctx, userID, codes); err != nil {
+		return nil, err
+	}
+
+	uc.recordEvent(ctx, actor, userID, domain.EventRecoveryCodesIssued, "nuevo juego de códigos de recuperación generado")
+	return plain, nil
+}
+
+// Methods lista los métodos de segundo factor disponibles para el usuario según
+// el método con el que validó el primer factor
+func (uc *MFAUseCase) Methods(ctx context.Context, user *domain.User, firstFactor string) []string {
+	methods := []string{}
+	if factor, err := uc.mfaRepo.GetTOTPFactor(ctx, user.ID.String()); err == nil && factor.ConfirmedAt != nil {
+		methods = append(methods, domain.MFAMethodTOTP)
+	}
+	if credentials, err := uc.webauthnRepo.ListCredentialsByUserID(ctx, user.ID.String()); err == nil && len(credentials) > 0 {
+		methods = append(methods, domain.MFAMethodWebAuthn)
+	}
+	if codes, err := uc.mfaRepo.ListUnusedRecoveryCodes(ctx, user.ID.String()); err == nil && len(codes) > 0 {
+		methods = append(methods, domain.MFAMethodRecoveryCode)
+	}
+	if emailFallbackAllowed(user, firstFactor) {
+		methods = append(methods, domain.MFAMethodEmailOTP)
+	}
+	return methods
+}
+
+// emailFallbackAllowed indica si un código enviado por correo puede servir de
+// segundo factor. No se ofrece cuando el primer factor ya fue el propio correo,
+// porque ambos pasos quedarían en manos de quien controle el buzón.
+func emailFallbackAllowed(user *domain.User, firstFactor string) bool {
+	return user.EmailVerified() && firstFactor == domain.LoginMethodPassword
+}
+
+// CreateChallenge abre un reto MFA de corta duración tras validar el primer
+// factor con firstFactor. Devuelve el token opaco que el cliente debe presentar
+// junto al código.
+func (uc *MFAUseCase) CreateChallenge(ctx context.Context, user *domain.User, firstFactor, userAgent, clientIP string) (string, error) {
+	token, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return "", errors.New("error al generar el reto de segundo factor")
+	}
+
+	challenge := &domain.MFAChallenge{
+		ID:          uuid.New().String(),
+		UserID:      user.ID.String(),
+		TokenHash:   security.HashToken(token),
+		FirstFactor: firstFactor,
+		UserAgent:   userAgent,
+		ClientIP:    clientIP,
+		ExpiresAt:   time.Now().Add(mfaChallengeDuration),
+		CreatedAt:   time.Now(),
+	}
+	if err := uc.mfaRepo.CreateChallenge(ctx, challenge); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ResolveChallenge valida el segundo factor de un reto pendiente y lo consume.
+// Devuelve el usuario para que el llamador emita la sesión.
+func (uc *MFAUseCase) ResolveChallenge(ctx context.Context, token, method, code, userAgent, clientIP string) (*MFAResult, error) {
+	return uc.resolveChallenge(ctx, token, method, userAgent, clientIP, func(actor Actor, challenge *domain.MFAChallenge, user *domain.User) (*MFAResult, error) {
+		return uc.verifyMethod(ctx, actor, challenge, user, method, code)
+	})
+}
+
+// ResolveChallengeWith resuelve un reto con un verificador externo, para los
+// factores cuya prueba no es un código, como WebAuthn. El verificador debe
+// devolver ErrInvalidMFACode cuando la prueba no es válida.
+func (uc *MFAUseCase) ResolveChallengeWith(ctx context.Context, token, method, userAgent, clientIP string, verify func(user *domain.User) error) (*MFAResult, error) {
+	return uc.resolveChallenge(ctx, token, method, userAgent, clientIP, func(_ Actor, _ *domain.MFAChallenge, user *domain.User) (*MFAResult, error) {
+		if err := verify(user); err != nil {
+			return nil, err
+		}
+		return &MFAResult{User: user}, nil
+	})
+}
+
+// SendEmailCode envía por correo el código de respaldo de un reto pendiente, sin consumirlo
+func (uc *MFAUseCase) SendEmailCode(ctx context.Context, token, userAgent, clientIP string) error {
+	challenge, user, err := uc.activeChallenge(ctx, token)
+	if err != nil {
+		return err
+	}
+	if !emailFallbackAllowed(user, challenge.FirstFactor) {
+		return ErrUnsupportedMFA
+	}
+	return uc.emailOTP.SendMFACode(ctx, user, userAgent, clientIP)
+}
+
+// ChallengeUser devuelve el usuario de un reto pendiente sin consumirlo
+func (uc *MFAUseCase) ChallengeUser(ctx context.Context, token string) (*domain.User, error) {
+	_, user, err := uc.activeChallenge(ctx, token)
+	return user, err
+}
+
+// activeChallenge busca un reto vigente y el usuario al que pertenece
+func (uc *MFAUseCase) activeChallenge(ctx context.Context, token string) (*domain.MFAChallenge, *domain.User, error) {
+	challenge, err := uc.mfaRepo.GetChallengeByTokenHash(ctx, security.HashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		_ = uc.mfaRepo.DeleteChallenge(ctx, challenge.ID)
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	if challenge.Attempts >= mfaChallengeMaxAttempts {
+		_ = uc.mfaRepo.DeleteChallenge(ctx, challenge.ID)
+		return nil, nil, ErrTooManyMFAAttempts
+	}
+
+	userID, err := uuid.Parse(challenge.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	return challenge, user, nil
+}
+
+func (uc *MFAUseCase) resolveChallenge(ctx context.Context, token, method, userAgent, clientIP string, verify func(actor Actor, challenge *domain.MFAChallenge, user *domain.User) (*MFAResult, error)) (*MFAResult, error) {
+	challenge, user, err := uc.activeChallenge(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// El intento se reserva antes de verificar: si se contara después de un
+	// fallo, varias peticiones simultáneas podrían probar más códigos que el máximo
+	reserved, err := uc.mfaRepo.ReserveChallengeAttempt(ctx, challenge.ID, mfaChallengeMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		_ = uc.mfaRepo.DeleteChallenge(ctx, challenge.ID)
+		return nil, ErrTooManyMFAAttempts
+	}
+
+	actor := Actor{UserID: user.ID.String(), ClientIP: clientIP, UserAgent: userAgent}
+	result, err := verify(actor, challenge, user)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			uc.recordEvent(ctx, actor, user.ID.String(), domain.EventMFAFailed, "código de segundo factor inválido ("+method+")")
+		}
+		return nil, err
+	}
+
+	// Consumir el reto; si otra petición ya lo usó, esta no puede continuar
+	if err := uc.mfaRepo.DeleteChallenge(ctx, challenge.ID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// verifyMethod valida el código según el método de segundo factor elegido
+func (uc *MFAUseCase) verifyMethod(ctx context.Context, actor Actor, challenge *domain.MFAChallenge, user *domain.User, method, code string) (*MFAResult, error) {
+	switch method {
+	case "", domain.MFAMethodTOTP:
+		factor, err := uc.mfaRepo.GetTOTPFactor(ctx, user.ID.String())
+		if err != nil || factor.ConfirmedAt == nil {
+			return nil, ErrUnsupportedMFA
+		}
+		if err := uc.verifyTOTP(ctx, factor, code); err != nil {
+			return nil, err
+		}
+		return &MFAResult{User: user}, nil
+	case domain.MFAMethodRecoveryCode:
+		remaining, err := uc.useRecoveryCode(ctx, actor, user.ID.String(), code)
+		if err != nil {
+			return nil, err
+		}
+		return &MFAResult{User: user, RecoveryCodesRemaining: &remaining}, nil
+	case domain.MFAMethodEmailOTP:
+		if !emailFallbackAllowed(user, challenge.FirstFactor) {
+			return nil, ErrUnsupportedMFA
+		}
+		if err := uc.emailOTP.VerifyCode(ctx, user, domain.EmailOTPPurposeMFA, code); err != nil {
+			if errors.Is(err, ErrInvalidEmailOTP) {
+				return nil, ErrInvalidMFACode
+			}
+			return nil, err
+		}
+		return &MFAResult{User: user}, nil
+	default:
+		return nil, ErrUnsupportedMFA
+	}
+}
+
+// useRecoveryCode consume el código de recuperación que coincida y devuelve
+// cuántos le quedan al usuario
+func (uc *MFAUseCase) useRecoveryCode(ctx context.Context, actor Actor, userID, code string) (int, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+
+	codes, err := uc.mfaRepo.ListUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Solo se verifican los hashes cuyo prefijo coincide, de modo que cada
+	// intento cuesta una verificación y no una por código
+	prefix := security.RecoveryCodePrefix(code)
+	for _, candidate := range codes {
+		if candidate.CodePrefix != "" && candidate.CodePrefix != prefix {
+			continue
+		}
+		if !security.ComparePassword(candidate.CodeHash, code) {
+			continue
+		}
+		if err := uc.mfaRepo.MarkRecoveryCodeUsed(ctx, candidate.ID); err != nil {
+			return 0, err
+		}
+
+		remaining := len(codes) - 1
+		uc.recordEvent(ctx, actor, userID, domain.EventRecoveryCodeUsed,
+			fmt.Sprintf("código de recuperación usado; quedan %d", remaining))
+		return remaining, nil
+	}
+	return 0, ErrInvalidMFACode
+}
+
+// verifyTOTP valida un código y registra su paso para que no pueda reutilizarse
+func (uc *MFAUseCase) verifyTOTP(ctx context.Context, factor *domain.TOTPFactor, code string) error {
+	step, ok := security.ValidateTOTP(factor.Secret, code, time.Now())
+	if !ok || step <= factor.LastUsedStep {
+		return ErrInvalidMFACode
+	}
+	return uc.mfaRepo.UpdateTOTPLastStep(ctx, factor.UserID, step)
+}
+
+func (uc *MFAUseCase) recordEvent(ctx context.Context, actor Actor, userID string, eventType domain.SecurityEventType, details string) {
+	event := &domain.SecurityEvent{
+		UserID:    userID,
+		Type:      eventType,
+		ClientIP:  actor.ClientIP,
+		UserAgent: actor.UserAgent,
+		Details:   details,
+	}
+	if actor.UserID != userID {
+		event.ActorID = actor.UserID
+	}
+	uc.events.Record(ctx, event)
+}
@@ -0,0 +1,683 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+// OAuthError es un error del protocolo OAuth 2.0. Code es el valor del campo
+// "error" de la respuesta (RFC 6749, secciones 4.1.2.1 y 5.2).
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Description
+}
+
+var (
+	ErrOAuthClientNotFound     = errors.New("cliente OAuth no encontrado")
+	ErrInvalidOAuthClient      = errors.New("datos del cliente OAuth inválidos")
+	ErrOAuthInvalidRedirectURI = errors.New("redirect_uri no registrada para el cliente")
+
+	ErrOAuthInvalidClient      = &OAuthError{Code: "invalid_client", Description: "autenticación del cliente fallida"}
+	ErrOAuthInvalidGrant       = &OAuthError{Code: "invalid_grant", Description: "código de autorización inválido o expirado"}
+	ErrOAuthAccessDenied       = &OAuthError{Code: "access_denied", Description: "el usuario rechazó la autorización"}
+	ErrOAuthUnauthorizedClient = &OAuthError{Code: "unauthorized_client", Description: "el cliente no puede usar este grant_type"}
+
+	// Errores del acceso a recursos protegidos con Bearer (RFC 6750, sección 3.1)
+	ErrOAuthInvalidToken      = &OAuthError{Code: "invalid_token", Description: "token de acceso inválido o expirado"}
+	ErrOAuthInsufficientScope = &OAuthError{Code: "insufficient_scope", Description: "el token no incluye el scope openid"}
+)
+
+const (
+	OAuthResponseTypeCode       = "code"
+	OAuthGrantAuthorizationCode = "authorization_code"
+	OAuthGrantClientCredentials = "client_credentials"
+
+	oauthCodeDuration        = 5 * time.Minute
+	oauthAccessTokenDuration = time.Hour
+	oidcIDTokenDuration      = time.Hour
+)
+
+// AuthorizationRequest son los parámetros de una petición a /authorize
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	// Nonce es el valor de OpenID Connect que se devuelve en el id_token
+	Nonce string
+}
+
+// OAuthTokenRequest son los parámetros de una petición a /token
+type OAuthTokenRequest struct {
+	GrantType    string
+	Scope        string
+	Code         string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+	CodeVerifier string
+}
+
+// OAuthTokenResult es la respuesta exitosa de /token
+type OAuthTokenResult struct {
+	AccessToken string
+	TokenType   string
+	ExpiresIn   int
+	Scope       string
+	// IDToken solo se emite cuando se concedió el scope openid
+	IDToken string
+}
+
+// OAuthRevocationRequest son los parámetros de una petición a /revoke (RFC 7009)
+type OAuthRevocationRequest struct {
+	Token         string
+	TokenTypeHint string
+	ClientID      string
+	ClientSecret  string
+}
+
+// OAuthIntrospectionRequest son los parámetros de una petición a /introspect (RFC 7662)
+type OAuthIntrospectionRequest struct {
+	Token         string
+	TokenTypeHint string
+	ClientID      string
+	ClientSecret  string
+}
+
+// TokenIntrospection es la respuesta de /introspect. Un token inactivo solo
+// lleva active en false, sin más detalles.
+type TokenIntrospection struct {
+	Active    bool   `json:"active"`
+	Subject   string `json:"sub,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// UserInfo es la respuesta del endpoint userinfo de OpenID Connect
+type UserInfo struct {
+	Subject string `json:"sub"`
+	security.UserClaims
+}
+
+// OAuthClientInput son los datos para registrar un cliente OAuth. Sin
+// GrantTypes, el cliente solo usa authorization_code.
+type OAuthClientInput struct {
+	Name         string
+	Type         string
+	RedirectURIs []string
+	Scopes       []string
+	GrantTypes   []string
+}
+
+type OAuthUseCase struct {
+	oauthRepo   repositories.OAuthRepository
+	userRepo    repositories.UserRepository
+	sessions    *SessionUseCase
+	revocations *TokenRevocationUseCase
+	events      *SecurityEventUseCase
+	issuer      string
+}
+
+// NewOAuthUseCase crea una nueva instancia del caso de uso del servidor de
+// autorización OAuth 2.0 y proveedor OpenID Connect. issuer es la URL pública
+// del servicio, que identifica a los tokens emitidos.
+func NewOAuthUseCase(oauthRepo repositories.OAuthRepository, userRepo repositories.UserRepository, sessions *SessionUseCase, revocations *TokenRevocationUseCase, events *SecurityEventUseCase, issuer string) *OAuthUseCase {
+	return &OAuthUseCase{
+		oauthRepo:   oauthRepo,
+		userRepo:    userRepo,
+		sessions:    sessions,
+		revocations: revocations,
+		events:      events,
+		issuer:      issuer,
+	}
+}
+
+// Issuer devuelve el identificador del emisor publicado en el documento de descubrimiento
+func (uc *OAuthUseCase) Issuer() string {
+	return uc.issuer
+}
+
+// RegisterClient registra un cliente OAuth. Los clientes confidenciales reciben
+// un secreto que solo se muestra esta vez.
+func (uc *OAuthUseCase) RegisterClient(ctx context.Context, actor Actor, input OAuthClientInput) (*domain.OAuthClient, string, error) {
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, "", fmt.Errorf("%w: el nombre es obligatorio", ErrInvalidOAuthClient)
+	}
+	if input.Type != domain.OAuthClientConfidential && input.Type != domain.OAuthClientPublic {
+		return nil, "", fmt.Errorf("%w: el tipo debe ser confidential o public", ErrInvalidOAuthClient)
+	}
+	grantTypes := dedupe(input.GrantTypes)
+	if len(grantTypes) == 0 {
+		grantTypes = []string{OAuthGrantAuthorizationCode}
+	}
+	for _, grantType := range grantTypes {
+		switch grantType {
+		case OAuthGrantAuthorizationCode:
+			if len(input.RedirectURIs) == 0 {
+				return nil, "", fmt.Errorf("%w: se requiere al menos una redirect_uri", ErrInvalidOAuthClient)
+			}
+		case OAuthGrantClientCredentials:
+			// Un servicio solo se puede identificar con un secreto
+			if input.Type != domain.OAuthClientConfidential {
+				return nil, "", fmt.Errorf("%w: client_credentials requiere un cliente confidential", ErrInvalidOAuthClient)
+			}
+		default:
+			return nil, "", fmt.Errorf("%w: grant_type no soportado: %q", ErrInvalidOAuthClient, grantType)
+		}
+	}
+	for _, uri := range input.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return nil, "", fmt.Errorf("%w: redirect_uri no permitida: %s", ErrInvalidOAuthClient, uri)
+		}
+	}
+	for _, scope := range input.Scopes {
+		if !validScopeToken(scope) {
+			return nil, "", fmt.Errorf("%w: scope inválido: %q", ErrInvalidOAuthClient, scope)
+		}
+	}
+
+	client := &domain.OAuthClient{
+		ClientID:     uuid.New().String(),
+		Name:         strings.TrimSpace(input.Name),
+		Type:         input.Type,
+		RedirectURIs: input.RedirectURIs,
+		Scopes:       dedupe(input.Scopes),
+		GrantTypes:   grantTypes,
+		CreatedBy:    actor.UserID,
+		CreatedAt:    time.Now(),
+	}
+
+	var secret string
+	if client.Confidential() {
+		var err error
+		secret, err = security.GenerateOpaqueToken()
+		if err != nil {
+			return nil, "", errors.New("error al generar el secreto del cliente")
+		}
+		client.SecretHash, err = security.HashPassword(secret)
+		if err != nil {
+			return nil, "", errors.New("error al cifrar el secreto del cliente")
+		}
+	}
+
+	if err := uc.oauthRepo.CreateClient(ctx, client); err != nil {
+		return nil, "", err
+	}
+
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		ActorID:   actor.UserID,
+		Type:      domain.EventOAuthClientCreated,
+		ClientIP:  actor.ClientIP,
+		UserAgent: actor.UserAgent,
+		Details:   fmt.Sprintf("cliente OAuth %s (%s) registrado", client.ClientID, client.Name),
+	})
+	return client, secret, nil
+}
+
+// ListClients lista los clientes OAuth registrados
+func (uc *OAuthUseCase) ListClients(ctx context.Context) ([]*domain.OAuthClient, error) {
+	return uc.oauthRepo.ListClients(ctx)
+}
+
+// DeleteClient elimina un cliente OAuth. Los tokens ya emitidos siguen vigentes
+// hasta su expiración.
+func (uc *OAuthUseCase) DeleteClient(ctx context.Context, actor Actor, clientID string) error {
+	if err := uc.oauthRepo.DeleteClient(ctx, clientID); err != nil {
+		return err
+	}
+
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		ActorID:   actor.UserID,
+		Type:      domain.EventOAuthClientDeleted,
+		ClientIP:  actor.ClientIP,
+		UserAgent: actor.UserAgent,
+		Details:   "cliente OAuth " + clientID + " eliminado",
+	})
+	return nil
+}
+
+// ValidateAuthorizationRequest valida una petición de autorización y devuelve
+// el cliente y los scopes concedibles. ErrOAuthClientNotFound y
+// ErrOAuthInvalidRedirectURI no deben informarse redirigiendo, ya que la URI no
+// es de confianza; el resto son *OAuthError para devolver a la redirect_uri.
+func (uc *OAuthUseCase) ValidateAuthorizationRequest(ctx context.Context, req AuthorizationRequest) (*domain.OAuthClient, []string, error) {
+	client, err := uc.oauthRepo.GetClient(ctx, req.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !client.AllowsRedirectURI(req.RedirectURI) {
+		return nil, nil, ErrOAuthInvalidRedirectURI
+	}
+	if !client.AllowsGrant(OAuthGrantAuthorizationCode) {
+		return nil, nil, ErrOAuthUnauthorizedClient
+	}
+
+	if req.ResponseType != OAuthResponseTypeCode {
+		return nil, nil, &OAuthError{Code: "unsupported_response_type", Description: "solo se admite response_type=code"}
+	}
+	// PKCE se exige a todos los clientes, no solo a los públicos
+	if req.CodeChallenge == "" {
+		return nil, nil, &OAuthError{Code: "invalid_request", Description: "se requiere code_challenge (PKCE)"}
+	}
+	if req.CodeChallengeMethod != security.PKCEMethodS256 {
+		return nil, nil, &OAuthError{Code: "invalid_request", Description: "solo se admite code_challenge_method=S256"}
+	}
+
+	scopes, err := resolveScopes(client, req.Scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, scopes, nil
+}
+
+// Authorize emite un código de autorización para el usuario autenticado que
+// aprobó la petición. Devuelve la URL a la que debe volver el navegador.
+func (uc *OAuthUseCase) Authorize(ctx context.Context, actor Actor, req AuthorizationRequest) (string, error) {
+	client, scopes, err := uc.ValidateAuthorizationRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return "", errors.New("error al generar el código de autorización")
+	}
+
+	authCode := &domain.OAuthAuthorizationCode{
+		CodeHash:            security.HashToken(code),
+		ClientID:            client.ClientID,
+		UserID:              actor.UserID,
+		RedirectURI:         req.RedirectURI,
+		Scopes:              scopes,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		ExpiresAt:           time.Now().Add(oauthCodeDuration),
+		CreatedAt:           time.Now(),
+	}
+	if err := uc.oauthRepo.CreateAuthorizationCode(ctx, authCode); err != nil {
+		return "", err
+	}
+
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		UserID:    actor.UserID,
+		Type:      domain.EventOAuthAuthorized,
+		ClientIP:  actor.ClientIP,
+		UserAgent: actor.UserAgent,
+		Details:   fmt.Sprintf("acceso concedido a %s (%s) con scopes %q", client.Name, client.ClientID, strings.Join(scopes, " ")),
+	})
+
+	return OAuthRedirectURL(req.RedirectURI, url.Values{"code": {code}, "state": {req.State}}), nil
+}
+
+// Token atiende el endpoint /token según el grant_type solicitado
+func (uc *OAuthUseCase) Token(ctx context.Context, req OAuthTokenRequest) (*OAuthTokenResult, error) {
+	switch req.GrantType {
+	case OAuthGrantAuthorizationCode:
+		return uc.exchangeCode(ctx, req)
+	case OAuthGrantClientCredentials:
+		return uc.clientCredentials(ctx, req)
+	case "":
+		return nil, &OAuthError{Code: "invalid_request", Description: "se requiere grant_type"}
+	default:
+		return nil, &OAuthError{Code: "unsupported_grant_type", Description: "grant_type no soportado"}
+	}
+}
+
+// exchangeCode canjea un código de autorización por un token de acceso
+func (uc *OAuthUseCase) exchangeCode(ctx context.Context, req OAuthTokenRequest) (*OAuthTokenResult, error) {
+	client, err := uc.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrant(OAuthGrantAuthorizationCode) {
+		return nil, ErrOAuthUnauthorizedClient
+	}
+	if req.Code == "" {
+		return nil, &OAuthError{Code: "invalid_request", Description: "se requiere code"}
+	}
+
+	// El código se consume antes de validarlo: un intento fallido también lo invalida
+	code, err := uc.oauthRepo.ConsumeAuthorizationCode(ctx, security.HashToken(req.Code))
+	if err != nil {
+		return nil, err
+	}
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI || time.Now().After(code.ExpiresAt) {
+		return nil, ErrOAuthInvalidGrant
+	}
+	if !security.VerifyPKCE(req.CodeVerifier, code.CodeChallenge) {
+		return nil, ErrOAuthInvalidGrant
+	}
+
+	userID, err := uuid.Parse(code.UserID)
+	if err != nil {
+		return nil, ErrOAuthInvalidGrant
+	}
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrOAuthInvalidGrant
+	}
+
+	scope := strings.Join(code.Scopes, " ")
+	accessToken, err := security.GenerateToken(user.ID.String(), user.Role, oauthAccessTokenDuration,
+		security.WithIssuer(uc.issuer), security.WithAudience(client.ClientID), security.WithScope(scope))
+	if err != nil {
+		return nil, errors.New("error generating access token")
+	}
+
+	result := &OAuthTokenResult{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(oauthAccessTokenDuration.Seconds()),
+		Scope:       scope,
+	}
+	if hasScope(code.Scopes, domain.ScopeOpenID) {
+		result.IDToken, err = security.GenerateIDToken(uc.issuer, user.ID.String(), client.ClientID, code.Nonce,
+			userClaims(user, code.Scopes), oidcIDTokenDuration)
+		if err != nil {
+			return nil, errors.New("error generating id token")
+		}
+	}
+	return result, nil
+}
+
+// clientCredentials emite un token de acceso para un servicio que se autentica
+// con sus propias credenciales (RFC 6749, sección 4.4). No hay usuario ni
+// refresh token: el servicio pide un token nuevo cuando el anterior expira.
+func (uc *OAuthUseCase) clientCredentials(ctx context.Context, req OAuthTokenRequest) (*OAuthTokenResult, error) {
+	client, err := uc.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential() || !client.AllowsGrant(OAuthGrantClientCredentials) {
+		return nil, ErrOAuthUnauthorizedClient
+	}
+
+	scopes, err := resolveServiceScopes(client, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := strings.Join(scopes, " ")
+	accessToken, err := security.GenerateClientToken(client.ClientID, oauthAccessTokenDuration,
+		security.WithIssuer(uc.issuer), security.WithScope(scope))
+	if err != nil {
+		return nil, errors.New("error generating access token")
+	}
+
+	return &OAuthTokenResult{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(oauthAccessTokenDuration.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// UserInfo devuelve los claims del usuario dueño de un token de acceso OAuth
+// emitido con el scope openid
+func (uc *OAuthUseCase) UserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	claims, err := security.ValidateToken(accessToken)
+	if err != nil || claims.Issuer != uc.issuer || len(claims.Audience) == 0 || uc.revocations.IsRevoked(claims) {
+		return nil, ErrOAuthInvalidToken
+	}
+	scopes := strings.Fields(claims.Scope)
+	if !hasScope(scopes, domain.ScopeOpenID) {
+		return nil, ErrOAuthInsufficientScope
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, ErrOAuthInvalidToken
+	}
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrOAuthInvalidToken
+	}
+
+	return &UserInfo{Subject: user.ID.String(), UserClaims: userClaims(user, scopes)}, nil
+}
+
+// Revoke atiende el endpoint /revoke (RFC 7009). Un token inválido, expirado o
+// emitido para otro cliente no es un error: la respuesta es la misma, para no
+// revelarle a un cliente qué tokens existen. El token_type_hint se ignora
+// porque el servidor solo emite tokens de acceso.
+func (uc *OAuthUseCase) Revoke(ctx context.Context, req OAuthRevocationRequest) error {
+	client, err := uc.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+	if req.Token == "" {
+		return &OAuthError{Code: "invalid_request", Description: "se requiere token"}
+	}
+
+	claims, err := security.ValidateToken(req.Token)
+	if err != nil || claims.Issuer != uc.issuer {
+		return nil
+	}
+	if claims.ClientID != client.ClientID && !slices.Contains(claims.Audience, client.ClientID) {
+		return nil
+	}
+	return uc.revocations.Revoke(ctx, claims)
+}
+
+// Introspect atiende el endpoint /introspect (RFC 7662) para los servidores de
+// recursos que no verifican los JWT por su cuenta. Solo lo pueden usar los
+// clientes confidenciales. Además de la firma y la expiración, un token deja de
+// estar activo si fue revocado o si su sesión se cerró o fue bloqueada.
+func (uc *OAuthUseCase) Introspect(ctx context.Context, req OAuthIntrospectionRequest) (*TokenIntrospection, error) {
+	client, err := uc.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential() {
+		return nil, ErrOAuthInvalidClient
+	}
+	if req.Token == "" {
+		return nil, &OAuthError{Code: "invalid_request", Description: "se requiere token"}
+	}
+
+	inactive := &TokenIntrospection{Active: false}
+	claims, err := security.ValidateToken(req.Token)
+	// Los id_token también van firmados, pero no son tokens de acceso
+	// Los tokens de registro del segundo factor no dan acceso a los servidores de recursos
+	if err != nil || (claims.UserID == "" && claims.ClientID == "") || claims.HasScope(domain.ScopeMFAEnrollment) || uc.revocations.IsRevoked(claims) {
+		return inactive, nil
+	}
+	if claims.SessionID != "" {
+		active, err := uc.sessions.IsSessionActive(ctx, claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return inactive, nil
+		}
+	}
+
+	result := &TokenIntrospection{
+		Active:    true,
+		Subject:   claims.UserID,
+		Role:      claims.Role,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		ExpiresAt: claims.ExpiresAt.Unix(),
+	}
+	if claims.ClientID != "" {
+		// Los tokens de servicio tienen como sujeto al propio cliente
+		result.Subject = claims.ClientID
+	} else if len(claims.Audience) > 0 {
+		result.ClientID = claims.Audience[0]
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Unix()
+	}
+	return result, nil
+}
+
+// userClaims arma los claims de OpenID Connect que cubren los scopes concedidos
+func userClaims(user *domain.User, scopes []string) security.UserClaims {
+	claims := security.UserClaims{}
+	if hasScope(scopes, domain.ScopeEmail) {
+		verified := user.EmailVerified()
+		claims.Email = user.Email
+		claims.EmailVerified = &verified
+	}
+	if hasScope(scopes, domain.ScopeProfile) {
+		claims.Name = user.Name
+		claims.FamilyName = user.Lastname
+		claims.Role = user.Role
+	}
+	return claims
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateClient identifica al cliente en /token, /revoke e /introspect. Los clientes
+// confidenciales deben presentar su secreto; los públicos dependen de PKCE.
+func (uc *OAuthUseCase) authenticateClient(ctx context.Context, clientID, secret string) (*domain.OAuthClient, error) {
+	if clientID == "" {
+		return nil, ErrOAuthInvalidClient
+	}
+	client, err := uc.oauthRepo.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, ErrOAuthClientNotFound) {
+			return nil, ErrOAuthInvalidClient
+		}
+		return nil, err
+	}
+	if client.Confidential() && (secret == "" || !security.ComparePassword(client.SecretHash, secret)) {
+		return nil, ErrOAuthInvalidClient
+	}
+	return client, nil
+}
+
+// OAuthRedirectURL agrega los parámetros de la respuesta a la redirect_uri,
+// conservando los que ya tuviera. Los valores vacíos se omiten.
+func OAuthRedirectURL(redirectURI string, params url.Values) string {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := target.Query()
+	for key, values := range params {
+		for _, value := range values {
+			if value != "" {
+				query.Add(key, value)
+			}
+		}
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
+
+// resolveScopes valida los scopes pedidos contra los del cliente y los estándar
+// de OpenID Connect. Sin scope se conceden todos los del cliente.
+func resolveScopes(client *domain.OAuthClient, requested string) ([]string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return client.Scopes, nil
+	}
+
+	allowed := make(map[string]bool, len(client.Scopes)+len(domain.OIDCScopes))
+	for _, scope := range domain.OIDCScopes {
+		allowed[scope] = true
+	}
+	for _, scope := range client.Scopes {
+		allowed[scope] = true
+	}
+	scopes := dedupe(strings.Fields(requested))
+	for _, scope := range scopes {
+		if !allowed[scope] {
+			return nil, &OAuthError{Code: "invalid_scope", Description: "scope no permitido para el cliente: " + scope}
+		}
+	}
+	return scopes, nil
+}
+
+// resolveServiceScopes valida los scopes pedidos por un servicio. Solo puede
+// pedir los registrados para el cliente: los de OpenID Connect describen a un
+// usuario y no aplican. Sin scope se conceden todos los registrados.
+func resolveServiceScopes(client *domain.OAuthClient, requested string) ([]string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return client.Scopes, nil
+	}
+
+	scopes := dedupe(strings.Fields(requested))
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return nil, &OAuthError{Code: "invalid_scope", Description: "scope no permitido para el cliente: " + scope}
+		}
+	}
+	return scopes, nil
+}
+
+// validRedirectURI acepta URIs absolutas sin fragmento: https, http solo en
+// loopback y esquemas privados de aplicaciones nativas (RFC 8252, sección 7)
+func validRedirectURI(raw string) bool {
+	uri, err := url.Parse(raw)
+	if err != nil || !uri.IsAbs() || uri.Fragment != "" {
+		return false
+	}
+	switch uri.Scheme {
+	case "https":
+		return uri.Host != ""
+	case "http":
+		host := uri.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	default:
+		// Los esquemas privados deben seguir el formato de dominio invertido
+		return strings.Contains(uri.Scheme, ".")
+	}
+}
+
+// validScopeToken valida la sintaxis de un scope (RFC 6749, sección 3.3)
+func validScopeToken(scope string) bool {
+	if scope == "" {
+		return false
+	}
+	for _, r := range scope {
+		if r < 0x21 || r > 0x7e || r == '"' || r == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
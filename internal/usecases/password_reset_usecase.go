@@ -0,0 +1,169 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/mailer"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+	"github.com/kevinhc2110/Auth_UCP/pck/validation"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("enlace de restablecimiento inválido o expirado")
+	ErrWeakPassword      = errors.New("la contraseña no cumple los requisitos de seguridad")
+)
+
+const passwordResetDuration = 30 * time.Minute
+
+type PasswordResetUseCase struct {
+	userRepo    repositories.UserRepository
+	resetRepo   repositories.PasswordResetRepository
+	sessionRepo repositories.SessionRepository
+	revocations *TokenRevocationUseCase
+	mailer      *mailer.Mailer
+	events      *SecurityEventUseCase
+	resetURL    string
+}
+
+// NewPasswordResetUseCase crea una nueva instancia del caso de uso de
+// restablecimiento de contraseña. resetURL es la página del cliente que recibe
+// el token como parámetro.
+func NewPasswordResetUseCase(userRepo repositories.UserRepository, resetRepo repositories.PasswordResetRepository, sessionRepo repositories.SessionRepository, revocations *TokenRevocationUseCase, mail *mailer.Mailer, events *SecurityEventUseCase, resetURL string) *PasswordResetUseCase {
+	return &PasswordResetUseCase{
+		userRepo:    userRepo,
+		resetRepo:   resetRepo,
+		sessionRepo: sessionRepo,
+		revocations: revocations,
+		mailer:      mail,
+		events:      events,
+		resetURL:    resetURL,
+	}
+}
+
+// RequestReset envía un enlace de restablecimiento si el correo pertenece a un
+// usuario. Todo el trabajo ocurre en segundo plano, de modo que ni la respuesta
+// ni su tiempo revelan si el correo está registrado.
+func (uc *PasswordResetUseCase) RequestReset(ctx context.Context, email, userAgent, clientIP string) {
+	actor := Actor{ClientIP: clientIP, UserAgent: userAgent}
+	go func() {
+		if err := uc.sendResetLink(context.WithoutCancel(ctx), actor, email); err != nil {
+			log.Printf("Error enviando el enlace de restablecimiento: %v", err)
+		}
+	}()
+}
+
+func (uc *PasswordResetUseCase) sendResetLink(ctx context.Context, actor Actor, email string) error {
+	user, err := uc.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	// Un nuevo enlace invalida los anteriores que sigan pendientes
+	if err := uc.resetRepo.DeleteTokensByUserID(ctx, user.ID.String()); err != nil {
+		return err
+	}
+	reset := &domain.PasswordResetToken{
+		ID:        uuid.New().String(),
+		UserID:    user.ID.String(),
+		TokenHash: security.HashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetDuration),
+		CreatedAt: time.Now(),
+	}
+	if err := uc.resetRepo.CreateToken(ctx, reset); err != nil {
+		return err
+	}
+
+	err = uc.mailer.SendTemplate(ctx, user.Email, mailer.TemplateResetPassword, mailer.Data{
+		Name:      user.Name,
+		Link:      uc.resetURL + "?token=" + url.QueryEscape(token),
+		ExpiresIn: passwordResetDuration,
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		UserID:    user.ID.String(),
+		Type:      domain.EventPasswordResetSent,
+		ClientIP:  actor.ClientIP,
+		UserAgent: actor.UserAgent,
+		Details:   "enlace de restablecimiento enviado",
+	})
+	return nil
+}
+
+// ResetPassword cambia la contraseña con un token de restablecimiento válido y
+// cierra todas las sesiones del usuario
+func (uc *PasswordResetUseCase) ResetPassword(ctx context.Context, token, newPassword, userAgent, clientIP string) error {
+	reset, err := uc.resetRepo.GetTokenByHash(ctx, security.HashToken(token))
+	if err != nil {
+		return err
+	}
+	if reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+	userID, err := uuid.Parse(reset.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	// Validar antes de consumir el token para que el usuario pueda reintentar
+	if err := validation.ValidatePassword(newPassword); err != nil {
+		return fmt.Errorf("%w: %v", ErrWeakPassword, err)
+	}
+	hashed, err := security.HashPassword(newPassword)
+	if err != nil {
+		return errors.New("error al cifrar la contraseña")
+	}
+
+	// Consumir el token; si otra petición ya lo usó, esta no puede continuar
+	if err := uc.resetRepo.MarkTokenUsed(ctx, reset.ID); err != nil {
+		return err
+	}
+	if err := uc.userRepo.UpdatePassword(ctx, userID, hashed); err != nil {
+		return err
+	}
+	// Recibir el enlace demuestra que el correo le pertenece
+	if user, err := uc.userRepo.FindByID(ctx, userID); err == nil && !user.EmailVerified() {
+		if err := uc.userRepo.MarkEmailVerified(ctx, userID, user.Email, time.Now()); err != nil {
+			return err
+		}
+	}
+	if err := uc.resetRepo.DeleteTokensByUserID(ctx, reset.UserID); err != nil {
+		return err
+	}
+
+	// Quien tenga una sesión abierta con la contraseña anterior queda fuera,
+	// también con los tokens de acceso que ya tenía
+	if err := uc.sessionRepo.DeleteSessionsByUserID(ctx, reset.UserID); err != nil {
+		return err
+	}
+	if err := uc.revocations.RevokeUser(ctx, reset.UserID); err != nil {
+		return err
+	}
+
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		UserID:    reset.UserID,
+		Type:      domain.EventPasswordReset,
+		ClientIP:  clientIP,
+		UserAgent: userAgent,
+		Details:   "contraseña restablecida; sesiones revocadas",
+	})
+	return nil
+}
@@ -0,0 +1,38 @@
+package usecases
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+// Actor identifica a quien ejecuta una acción auditada
+type Actor struct {
+	UserID    string
+	ClientIP  string
+	UserAgent string
+}
+
+type SecurityEventUseCase struct {
+	repo repositories.SecurityEventRepository
+}
+
+// NewSecurityEventUseCase crea una nueva instancia del caso de uso de eventos de seguridad
+func NewSecurityEventUseCase(repo repositories.SecurityEventRepository) *SecurityEventUseCase {
+	return &SecurityEventUseCase{repo: repo}
+}
+
+// Record registra un evento de seguridad. Un fallo al guardarlo solo se escribe
+// en el log para no interrumpir el flujo que originó el evento.
+func (uc *SecurityEventUseCase) Record(ctx context.Context, event *domain.SecurityEvent) {
+	event.ID = uuid.New().String()
+	event.CreatedAt = time.Now()
+
+	if err := uc.repo.CreateEvent(ctx, event); err != nil {
+		log.Printf("Error registrando evento de seguridad %s: %v", event.Type, err)
+	}
+}
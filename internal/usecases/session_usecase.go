@@ -3,6 +3,9 @@ package usecases
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,21 +14,31 @@ import (
 )
 
 var (
-	ErrSessionNotFound = errors.New("sessión no encontrada")
-	ErrSessionBlocked  = errors.New("sessión esta bloqueada")
-	ErrInvalidSession  = errors.New("token de sessión invalido")
-	ErrSessionExpired  = errors.New("sessión a expirado")
-	ErrInvalidDuration = errors.New("duración de sesión no válida")
-	ErrInvalidToken    = errors.New("token invalido")
+	ErrSessionNotFound    = errors.New("sessión no encontrada")
+	ErrSessionBlocked     = errors.New("sessión esta bloqueada")
+	ErrInvalidSession     = errors.New("token de sessión invalido")
+	ErrSessionExpired     = errors.New("sessión a expirado")
+	ErrInvalidDuration    = errors.New("duración de sesión no válida")
+	ErrInvalidToken       = errors.New("token invalido")
+	ErrRefreshTokenReused = errors.New("refresh token reutilizado, la sesión fue revocada")
+	ErrBlockReasonMissing = errors.New("se requiere un motivo para bloquear la sesión")
+)
+
+const (
+	defaultSessionSearchLimit = 50
+	maxSessionSearchLimit     = 200
 )
 
 type SessionUseCase struct {
-	repo repositories.SessionRepository
+	repo        repositories.SessionRepository
+	revocations *TokenRevocationUseCase
+	events      *SecurityEventUseCase
 }
 
-// NewSessionUseCase crea una nueva instancia del caso de uso de sesión
-func NewSessionUseCase(repo repositories.SessionRepository) *SessionUseCase {
-	return &SessionUseCase{repo: repo}
+// NewSessionUseCase crea una nueva instancia del caso de uso de sesión. Al
+// cerrar o bloquear sesiones se revocan también sus tokens de acceso.
+func NewSessionUseCase(repo repositories.SessionRepository, revocations *TokenRevocationUseCase, events *SecurityEventUseCase) *SessionUseCase {
+	return &SessionUseCase{repo: repo, revocations: revocations, events: events}
 }
 
 // CreateSession crea una nueva sesión para un usuario
@@ -35,8 +48,10 @@ func (uc *SessionUseCase) CreateSession(ctx context.Context, userID, userAgent,
 		return nil, ErrInvalidDuration
 	}
 
+	id := uuid.New().String()
 	session := &domain.Session{
-		ID:           uuid.New().String(),
+		ID:           id,
+		FamilyID:     id,
 		UserID:       userID,
 		RefreshToken: refreshToken,
 		UserAgent:    userAgent,
@@ -82,6 +97,82 @@ func (uc *SessionUseCase) GetSessionByToken(ctx context.Context, refreshToken st
 	return session, nil
 }
 
+// ListUserSessions lista las sesiones activas de un usuario, una por dispositivo
+func (uc *SessionUseCase) ListUserSessions(ctx context.Context, userID string) ([]*domain.Session, error) {
+	return uc.repo.ListActiveSessionsByUserID(ctx, userID)
+}
+
+// RevokeUserSession cierra una sesión (familia de refresh tokens) del propio
+// usuario y revoca los tokens de acceso emitidos para ella
+func (uc *SessionUseCase) RevokeUserSession(ctx context.Context, userID, familyID string) error {
+	sessions, err := uc.repo.ListActiveSessionsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Solo se permite revocar sesiones que pertenezcan al usuario
+	for _, session := range sessions {
+		if session.FamilyID == familyID {
+			return uc.EndSession(ctx, familyID)
+		}
+	}
+	return ErrSessionNotFound
+}
+
+// RevokeOtherSessions cierra todas las sesiones del usuario excepto la actual
+// y revoca los tokens de acceso emitidos para ellas
+func (uc *SessionUseCase) RevokeOtherSessions(ctx context.Context, userID, currentFamilyID string) error {
+	if currentFamilyID == "" {
+		return ErrInvalidSession
+	}
+
+	// Las sesiones bloqueadas o expiradas no tienen tokens de acceso vigentes
+	sessions, err := uc.repo.ListActiveSessionsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := uc.repo.DeleteOtherSessionsByUserID(ctx, userID, currentFamilyID); err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.FamilyID == currentFamilyID {
+			continue
+		}
+		if err := uc.revocations.RevokeSession(ctx, session.FamilyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EndSession elimina todos los refresh tokens de una sesión y revoca sus tokens de acceso
+func (uc *SessionUseCase) EndSession(ctx context.Context, familyID string) error {
+	if err := uc.repo.DeleteSessionsByFamilyID(ctx, familyID); err != nil {
+		return err
+	}
+	return uc.revocations.RevokeSession(ctx, familyID)
+}
+
+// StartCleanup elimina cada interval las sesiones expiradas, junto con los
+// refresh tokens rotados de su familia
+func (uc *SessionUseCase) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := uc.repo.DeleteExpiredSessions(ctx, time.Now()); err != nil {
+					log.Printf("Error eliminando las sesiones expiradas: %v", err)
+				}
+			}
+		}
+	}()
+}
+
 // DeleteSession elimina una sesión por ID
 func (uc *SessionUseCase) DeleteSession(ctx context.Context, id string) error {
 	return uc.repo.DeleteSession(ctx, id)
@@ -92,16 +183,121 @@ func (uc *SessionUseCase) DeleteSessionsByUserID(ctx context.Context, userID str
 	return uc.repo.DeleteSessionsByUserID(ctx, userID)
 }
 
-// BlockSession bloquea una sesión específica
-func (uc *SessionUseCase) BlockSession(ctx context.Context, id string) error {
-	session, err := uc.repo.GetSessionByID(ctx, id)
+// SearchSessions busca sesiones de cualquier usuario. Uso exclusivo de administradores.
+func (uc *SessionUseCase) SearchSessions(ctx context.Context, actor Actor, filter domain.SessionFilter) ([]*domain.Session, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSessionSearchLimit
+	}
+	if filter.Limit > maxSessionSearchLimit {
+		filter.Limit = maxSessionSearchLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	sessions, err := uc.repo.SearchSessions(ctx, filter)
 	if err != nil {
-		return ErrSessionNotFound
+		return nil, err
 	}
 
-	session.IsBlocked = true
-	session.UpdatedAt = time.Now()
-	return uc.repo.CreateSession(ctx, session)
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		UserID:    filter.UserID,
+		ActorID:   actor.UserID,
+		Type:      domain.EventAdminSessionSearch,
+		ClientIP:  actor.ClientIP,
+		UserAgent: actor.UserAgent,
+		Details:   fmt.Sprintf("family_id=%q user_id=%q ip=%q user_agent=%q", filter.FamilyID, filter.UserID, filter.ClientIP, filter.UserAgent),
+	})
+	return sessions, nil
+}
+
+// BlockSession bloquea una sesión (familia de refresh tokens) indicando el
+// motivo, y revoca los tokens de acceso ya emitidos para ella. Si se
+// desbloquea, esos tokens siguen revocados.
+func (uc *SessionUseCase) BlockSession(ctx context.Context, actor Actor, familyID, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return ErrBlockReasonMissing
+	}
+	if err := uc.setBlocked(ctx, actor, familyID, true, reason); err != nil {
+		return err
+	}
+	return uc.revocations.RevokeSession(ctx, familyID)
+}
+
+// UnblockSession desbloquea una sesión previamente bloqueada
+func (uc *SessionUseCase) UnblockSession(ctx context.Context, actor Actor, familyID string) error {
+	return uc.setBlocked(ctx, actor, familyID, false, "")
+}
+
+func (uc *SessionUseCase) setBlocked(ctx context.Context, actor Actor, familyID string, blocked bool, reason string) error {
+	session, err := uc.findFamily(ctx, familyID)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.repo.SetFamilyBlocked(ctx, familyID, blocked, reason, actor.UserID); err != nil {
+		return err
+	}
+
+	eventType, details := domain.EventSessionUnblocked, "sesión "+familyID+" desbloqueada"
+	if blocked {
+		eventType, details = domain.EventSessionBlocked, "sesión "+familyID+" bloqueada: "+reason
+	}
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		UserID:    session.UserID,
+		ActorID:   actor.UserID,
+		Type:      eventType,
+		ClientIP:  actor.ClientIP,
+		UserAgent: actor.UserAgent,
+		Details:   details,
+	})
+	return nil
+}
+
+// ForceLogoutUser cierra todas las sesiones de un usuario en todos sus
+// dispositivos y revoca todos sus tokens de acceso
+func (uc *SessionUseCase) ForceLogoutUser(ctx context.Context, actor Actor, userID string) error {
+	if err := uc.repo.DeleteSessionsByUserID(ctx, userID); err != nil {
+		return err
+	}
+	if err := uc.revocations.RevokeUser(ctx, userID); err != nil {
+		return err
+	}
+
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		UserID:    userID,
+		ActorID:   actor.UserID,
+		Type:      domain.EventUserSessionsRevoked,
+		ClientIP:  actor.ClientIP,
+		UserAgent: actor.UserAgent,
+		Details:   "cierre forzado de todas las sesiones del usuario",
+	})
+	return nil
+}
+
+// IsSessionActive indica si la sesión (familia de refresh tokens) sigue
+// abierta: no se cerró, no está bloqueada y no expiró
+func (uc *SessionUseCase) IsSessionActive(ctx context.Context, familyID string) (bool, error) {
+	session, err := uc.findFamily(ctx, familyID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !session.IsBlocked && time.Now().Before(session.ExpiresAt), nil
+}
+
+// findFamily obtiene el token vigente de una familia de sesiones
+func (uc *SessionUseCase) findFamily(ctx context.Context, familyID string) (*domain.Session, error) {
+	sessions, err := uc.repo.SearchSessions(ctx, domain.SessionFilter{FamilyID: familyID, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, ErrSessionNotFound
+	}
+	return sessions[0], nil
 }
 
 // UpdateSession actualiza la sesión en el repositorio
@@ -0,0 +1,221 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+var (
+	ErrNoActiveSigningKey = errors.New("no hay una clave de firma activa")
+	ErrSigningKeyRotated  = errors.New("la clave de firma ya fue rotada")
+)
+
+// signingKeyGracePeriod es cuánto sigue publicada una clave retirada. Debe
+// cubrir el JWT de mayor duración que emite el servicio.
+const signingKeyGracePeriod = accessTokenDuration
+
+type SigningKeyUseCase struct {
+	repo             repositories.SigningKeyRepository
+	events           *SecurityEventUseCase
+	secret           []byte
+	algorithm        string
+	rotationInterval time.Duration
+}
+
+// NewSigningKeyUseCase crea una nueva instancia del caso de uso de claves de
+// firma. secret cifra las claves privadas guardadas y algorithm es el de las
+// claves que se generan; con rotationInterval en cero solo se rota a pedido de
+// un administrador.
+func NewSigningKeyUseCase(repo repositories.SigningKeyRepository, events *SecurityEventUseCase, secret []byte, algorithm string, rotationInterval time.Duration) *SigningKeyUseCase {
+	return &SigningKeyUseCase{
+		repo:             repo,
+		events:           events,
+		secret:           secret,
+		algorithm:        algorithm,
+		rotationInterval: rotationInterval,
+	}
+}
+
+// Init carga las claves guardadas. Si aún no hay ninguna, guarda initial como
+// clave activa, o una nueva si initial es nil o usa otro algoritmo. Si la
+// clave activa usa un algoritmo distinto al configurado, se rota de inmediato.
+func (uc *SigningKeyUseCase) Init(ctx context.Context, initial *security.SigningKey) error {
+	keys, err := uc.repo.ListPublishedKeys(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		if initial == nil || initial.Algorithm != uc.algorithm {
+			if initial, err = security.GenerateSigningKey(uc.algorithm); err != nil {
+				return fmt.Errorf("error al generar la clave de firma: %w", err)
+			}
+		}
+		stored, err := uc.storedKey(initial)
+		if err != nil {
+			return err
+		}
+		if err := uc.repo.CreateInitialKey(ctx, stored); err != nil {
+			return err
+		}
+	}
+
+	active, err := uc.Reload(ctx)
+	if err != nil {
+		return err
+	}
+	if active.Algorithm != uc.algorithm {
+		if _, err := uc.rotate(ctx, Actor{}, active); err != nil && !errors.Is(err, ErrSigningKeyRotated) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reload instala en el keyring las claves publicadas, de modo que cada
+// instancia adopta las rotaciones hechas por las demás. Devuelve la clave activa.
+func (uc *SigningKeyUseCase) Reload(ctx context.Context) (*domain.SigningKey, error) {
+	keys, err := uc.repo.ListPublishedKeys(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var active *domain.SigningKey
+	var activeKey *security.SigningKey
+	published := make([]*security.SigningKey, 0, len(keys))
+	for _, key := range keys {
+		privatePEM, err := security.OpenPrivateKey(uc.secret, key.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("error al descifrar la clave de firma %s: %w", key.ID, err)
+		}
+		parsed, err := security.ParseSigningKey(privatePEM)
+		if err != nil {
+			return nil, fmt.Errorf("error al cargar la clave de firma %s: %w", key.ID, err)
+		}
+		published = append(published, parsed)
+
+		// Las claves vienen ordenadas por antigüedad: gana la activa más reciente
+		if key.Active() {
+			active, activeKey = key, parsed
+		}
+	}
+	if active == nil {
+		return nil, ErrNoActiveSigningKey
+	}
+
+	security.InstallKeys(activeKey, published)
+	return active, nil
+}
+
+// ListKeys lista la clave activa y las retiradas aún publicadas
+func (uc *SigningKeyUseCase) ListKeys(ctx context.Context) ([]*domain.SigningKey, error) {
+	return uc.repo.ListPublishedKeys(ctx, time.Now())
+}
+
+// Rotate genera una nueva clave activa. La anterior deja de firmar pero sigue
+// verificando hasta que expiran los tokens que firmó, así que nadie pierde su sesión.
+func (uc *SigningKeyUseCase) Rotate(ctx context.Context, actor Actor) (*domain.SigningKey, error) {
+	// Partir de la clave activa guardada, por si otra instancia ya rotó
+	current, err := uc.Reload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return uc.rotate(ctx, actor, current)
+}
+
+func (uc *SigningKeyUseCase) rotate(ctx context.Context, actor Actor, current *domain.SigningKey) (*domain.SigningKey, error) {
+	next, err := security.GenerateSigningKey(uc.algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("error al generar la clave de firma: %w", err)
+	}
+	stored, err := uc.storedKey(next)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := uc.repo.RotateKey(ctx, current.ID, stored, now, now.Add(signingKeyGracePeriod)); err != nil {
+		return nil, err
+	}
+
+	details := fmt.Sprintf("clave %s retirada; nueva clave activa %s", current.ID, next.ID)
+	if actor.UserID == "" {
+		details = "rotación programada: " + details
+	}
+	uc.events.Record(ctx, &domain.SecurityEvent{
+		ActorID:   actor.UserID,
+		Type:      domain.EventSigningKeyRotated,
+		ClientIP:  actor.ClientIP,
+		UserAgent: actor.UserAgent,
+		Details:   details,
+	})
+
+	if _, err := uc.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// StartRotation revisa cada checkInterval las claves en segundo plano: adopta
+// las rotaciones de otras instancias, rota la clave activa cuando cumple
+// rotationInterval y elimina las retiradas que ya no verifican ningún token.
+func (uc *SigningKeyUseCase) StartRotation(ctx context.Context, checkInterval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := uc.rotateIfDue(ctx); err != nil {
+					log.Printf("Error en la rotación de claves de firma: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (uc *SigningKeyUseCase) rotateIfDue(ctx context.Context) error {
+	active, err := uc.Reload(ctx)
+	if err != nil {
+		return err
+	}
+
+	if uc.rotationInterval > 0 && time.Since(active.CreatedAt) >= uc.rotationInterval {
+		if _, err := uc.rotate(ctx, Actor{}, active); err != nil && !errors.Is(err, ErrSigningKeyRotated) {
+			return err
+		}
+	}
+
+	if _, err := uc.repo.DeleteExpiredKeys(ctx, time.Now()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// storedKey prepara una clave para guardarla, cifrando la parte privada
+func (uc *SigningKeyUseCase) storedKey(key *security.SigningKey) (*domain.SigningKey, error) {
+	privatePEM, err := key.PrivateKeyPEM()
+	if err != nil {
+		return nil, fmt.Errorf("error al serializar la clave de firma: %w", err)
+	}
+	sealed, err := security.SealPrivateKey(uc.secret, privatePEM)
+	if err != nil {
+		return nil, fmt.Errorf("error al cifrar la clave de firma: %w", err)
+	}
+	return &domain.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: sealed,
+		CreatedAt:  time.Now(),
+	}, nil
+}
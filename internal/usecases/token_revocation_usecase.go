@@ -0,0 +1,247 @@
+package usecases
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+const (
+	// revocationSyncOverlap vuelve a leer en cada sincronización las revocaciones
+	// de este último tramo, para no perder las que otra instancia guardó con el
+	// reloj algo desfasado o en una transacción que tardó en confirmarse
+	revocationSyncOverlap = time.Minute
+	// revocationPurgeInterval es cada cuánto se eliminan los tokens revocados que ya expiraron
+	revocationPurgeInterval = time.Hour
+)
+
+// sessionRevocationTTL es cuánto se conserva la revocación de una sesión o de
+// un usuario: lo que dura el token de acceso más largo emitido antes de ella
+var sessionRevocationTTL = max(accessTokenDuration, oauthAccessTokenDuration)
+
+// sessionRevocation es la copia en memoria de un domain.SessionRevocation
+type sessionRevocation struct {
+	revokedAt time.Time
+	expiresAt time.Time
+}
+
+// TokenRevocationUseCase mantiene la lista de tokens de acceso revocados, uno
+// a uno por su jti o en bloque por sesión o por usuario. Se guarda en la base
+// de datos y se replica en memoria, de modo que comprobar un token en cada
+// petición no consulta la base de datos.
+type TokenRevocationUseCase struct {
+	repo repositories.RevokedTokenRepository
+
+	mu       sync.RWMutex
+	revoked  map[string]time.Time // jti -> expiración del token
+	sessions map[string]sessionRevocation
+	users    map[string]sessionRevocation
+	lastSync time.Time
+}
+
+// NewTokenRevocationUseCase crea una nueva instancia del caso de uso de revocación de tokens
+func NewTokenRevocationUseCase(repo repositories.RevokedTokenRepository) *TokenRevocationUseCase {
+	return &TokenRevocationUseCase{
+		repo:     repo,
+		revoked:  map[string]time.Time{},
+		sessions: map[string]sessionRevocation{},
+		users:    map[string]sessionRevocation{},
+	}
+}
+
+// Revoke revoca un token de acceso hasta que expire. Los tokens sin jti,
+// emitidos antes de que existiera la revocación, no se pueden revocar.
+func (uc *TokenRevocationUseCase) Revoke(ctx context.Context, claims *security.JWTClaims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	now := time.Now()
+	if !claims.ExpiresAt.After(now) {
+		return nil
+	}
+
+	token := &domain.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+		RevokedAt: now,
+	}
+	if claims.ClientID != "" {
+		token.ClientID = claims.ClientID
+	} else if len(claims.Audience) > 0 {
+		token.ClientID = claims.Audience[0]
+	}
+	if err := uc.repo.RevokeToken(ctx, token); err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	uc.revoked[token.JTI] = token.ExpiresAt
+	uc.mu.Unlock()
+	return nil
+}
+
+// RevokeSession revoca los tokens de acceso ya emitidos para una sesión
+// (familia de refresh tokens), por ejemplo al cerrarla o bloquearla
+func (uc *TokenRevocationUseCase) RevokeSession(ctx context.Context, familyID string) error {
+	return uc.revokeSessions(ctx, domain.SessionRevocationFamily, familyID)
+}
+
+// RevokeUser revoca los tokens de acceso ya emitidos para un usuario, en
+// todas sus sesiones y aplicaciones
+func (uc *TokenRevocationUseCase) RevokeUser(ctx context.Context, userID string) error {
+	return uc.revokeSessions(ctx, domain.SessionRevocationUser, userID)
+}
+
+func (uc *TokenRevocationUseCase) revokeSessions(ctx context.Context, kind, subject string) error {
+	if subject == "" {
+		return nil
+	}
+	now := time.Now()
+	revocation := &domain.SessionRevocation{
+		Kind:      kind,
+		Subject:   subject,
+		RevokedAt: now,
+		ExpiresAt: now.Add(sessionRevocationTTL),
+	}
+	if err := uc.repo.RevokeSessions(ctx, revocation); err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	uc.addSessionRevocation(revocation)
+	uc.mu.Unlock()
+	return nil
+}
+
+// IsRevoked indica si el token fue revocado, por su jti o porque se revocó su
+// sesión o su usuario después de emitirlo. Solo consulta la copia en memoria:
+// las revocaciones hechas en otras instancias se ven tras la siguiente
+// sincronización.
+func (uc *TokenRevocationUseCase) IsRevoked(claims *security.JWTClaims) bool {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+	if _, revoked := uc.revoked[claims.ID]; revoked && claims.ID != "" {
+		return true
+	}
+
+	// iat tiene resolución de segundos: un token emitido en el mismo segundo
+	// que la revocación se da por revocado, igual que uno sin iat
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	if revocation, ok := uc.sessions[claims.SessionID]; ok && claims.SessionID != "" && !issuedAt.After(revocation.revokedAt) {
+		return true
+	}
+	if revocation, ok := uc.users[claims.UserID]; ok && claims.UserID != "" && !issuedAt.After(revocation.revokedAt) {
+		return true
+	}
+	return false
+}
+
+// addSessionRevocation agrega una revocación a la copia en memoria. Debe
+// llamarse con uc.mu bloqueado.
+func (uc *TokenRevocationUseCase) addSessionRevocation(revocation *domain.SessionRevocation) {
+	revocations := uc.sessions
+	if revocation.Kind == domain.SessionRevocationUser {
+		revocations = uc.users
+	}
+	current, ok := revocations[revocation.Subject]
+	if ok && current.revokedAt.After(revocation.RevokedAt) {
+		return
+	}
+	revocations[revocation.Subject] = sessionRevocation{revokedAt: revocation.RevokedAt, expiresAt: revocation.ExpiresAt}
+}
+
+// Load carga los tokens y sesiones revocados que aún no expiran
+func (uc *TokenRevocationUseCase) Load(ctx context.Context) error {
+	return uc.sync(ctx)
+}
+
+// StartSync trae cada syncInterval las revocaciones hechas por otras
+// instancias y elimina cada hora los tokens revocados que ya expiraron
+func (uc *TokenRevocationUseCase) StartSync(ctx context.Context, syncInterval time.Duration) {
+	go func() {
+		syncTicker := time.NewTicker(syncInterval)
+		defer syncTicker.Stop()
+		purgeTicker := time.NewTicker(revocationPurgeInterval)
+		defer purgeTicker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-syncTicker.C:
+				if err := uc.sync(ctx); err != nil {
+					log.Printf("Error sincronizando los tokens revocados: %v", err)
+				}
+			case <-purgeTicker.C:
+				if err := uc.purge(ctx); err != nil {
+					log.Printf("Error eliminando los tokens revocados expirados: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// sync agrega a la copia en memoria las revocaciones guardadas desde la última sincronización
+func (uc *TokenRevocationUseCase) sync(ctx context.Context) error {
+	uc.mu.RLock()
+	since := uc.lastSync
+	uc.mu.RUnlock()
+	if !since.IsZero() {
+		since = since.Add(-revocationSyncOverlap)
+	}
+
+	now := time.Now()
+	tokens, err := uc.repo.ListRevokedTokens(ctx, since, now)
+	if err != nil {
+		return err
+	}
+	sessionRevocations, err := uc.repo.ListSessionRevocations(ctx, since, now)
+	if err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	for _, token := range tokens {
+		uc.revoked[token.JTI] = token.ExpiresAt
+	}
+	for _, revocation := range sessionRevocations {
+		uc.addSessionRevocation(revocation)
+	}
+	uc.lastSync = now
+	return nil
+}
+
+// purge elimina los tokens y sesiones revocados que ya expiraron, en memoria y en la base de datos
+func (uc *TokenRevocationUseCase) purge(ctx context.Context) error {
+	now := time.Now()
+	uc.mu.Lock()
+	for jti, expiresAt := range uc.revoked {
+		if !expiresAt.After(now) {
+			delete(uc.revoked, jti)
+		}
+	}
+	for _, revocations := range []map[string]sessionRevocation{uc.sessions, uc.users} {
+		for subject, revocation := range revocations {
+			if !revocation.expiresAt.After(now) {
+				delete(revocations, subject)
+			}
+		}
+	}
+	uc.mu.Unlock()
+
+	if _, err := uc.repo.DeleteExpiredRevokedTokens(ctx, now); err != nil {
+		return err
+	}
+	_, err := uc.repo.DeleteExpiredSessionRevocations(ctx, now)
+	return err
+}
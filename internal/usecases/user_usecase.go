@@ -3,7 +3,6 @@ package usecases
 import (
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,19 +16,27 @@ import (
 var (
 	ErrUserNotFound       = errors.New("usario no encontrado")
 	ErrEmailAlreadyExists = errors.New("el email ya esta registrado en una cuenta")
+	ErrIdentificationAlreadyExists = errors.New("el documento ya esta registrado en una cuenta")
 	ErrInvalidCredentials = errors.New("credenciales invalidas")
 )
 
 type UserUseCase struct {
-	repo repositories.UserRepository
+	repo         repositories.UserRepository
+	verification *EmailVerificationUseCase
 }
 
 // NewUserUseCase crea una nueva instancia de UserUseCase
-func NewUserUseCase(repo repositories.UserRepository) *UserUseCase {
-	return &UserUseCase{repo: repo}
+func NewUserUseCase(repo repositories.UserRepository, verification *EmailVerificationUseCase) *UserUseCase {
+	return &UserUseCase{repo: repo, verification: verification}
 }
 
 func (uc *UserUseCase) CreateUser(ctx context.Context, user *domain.User) error {
+	// Los clientes anteriores a los tipos de documento solo registraban cédulas
+	if user.DocumentType == "" {
+		user.DocumentType = domain.DocumentTypeCC
+	}
+	user.Identification = domain.NormalizeIdentification(user.Identification)
+
 	// Validar usuario
 	if err := validation.ValidateUser(user); err != nil {
 		return err
@@ -40,11 +47,15 @@ func (uc *UserUseCase) CreateUser(ctx context.Context, user *domain.User) error
 		return ErrEmailAlreadyExists
 	}
 
-	// Si el usuario vacio, asignarle el rol de usuario
-	if user.Role == "" {
-		user.Role = string(domain.RoleUser) 
+	// Verificar si el documento ya está registrado
+	if existingUser, _ := uc.repo.FindByIdentification(ctx, user.DocumentType, user.Identification); existingUser != nil {
+		return ErrIdentificationAlreadyExists
 	}
 
+	// El registro es público: el rol que envíe el cliente se ignora y los
+	// demás roles solo los asigna un administrador
+	user.Role = string(domain.RoleUser)
+
 	// Cifrar la contraseña
 	hashedPassword, err := security.HashPassword(user.Password)
 	if err != nil {
@@ -57,14 +68,21 @@ func (uc *UserUseCase) CreateUser(ctx context.Context, user *domain.User) error
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 	user.Active = true
-	fmt.Printf("ANTES DE GUARDAR: %+v\n", user)
-
+	user.MFAEnabled = false
+	// La cuenta no puede iniciar sesión hasta confirmar el correo
+	user.EmailVerifiedAt = nil
+	user.VerificationSentAt = nil
 
 	// Guardar el usuario en la base de datos
 	if err := uc.repo.Create(ctx, user); err != nil {
+		if errors.Is(err, ErrEmailAlreadyExists) || errors.Is(err, ErrIdentificationAlreadyExists) {
+			return err
+		}
 		return errors.New("error al guardar el usuario")
 	}
 
+	uc.verification.SendVerification(ctx, user)
+
 	return nil
 }
 
@@ -77,9 +95,9 @@ func (uc *UserUseCase) GetUserByID(ctx context.Context, ID uuid.UUID) (*domain.U
 	return user, nil
 }
 
-// GetUserByIdentification obtiene un usuario por su identification
-func (uc *UserUseCase) GetUserByIdentification(ctx context.Context, identification string) (*domain.User, error) {
-	user, err := uc.repo.FindByIdentification(ctx, identification)
+// GetUserByIdentification obtiene un usuario por el tipo y número de su documento
+func (uc *UserUseCase) GetUserByIdentification(ctx context.Context, documentType, identification string) (*domain.User, error) {
+	user, err := uc.repo.FindByIdentification(ctx, documentType, domain.NormalizeIdentification(identification))
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
@@ -97,10 +115,36 @@ func (uc *UserUseCase) GetUserByEmail(ctx context.Context, email string) (*domai
 
 // UpdateUser actualiza la información de un usuario
 func (uc *UserUseCase) UpdateUser(ctx context.Context, user *domain.User) error {
+	previous, err := uc.repo.FindByID(ctx, user.ID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	// Los clientes anteriores a los tipos de documento no lo envían: se
+	// conserva el de la cuenta
+	if user.DocumentType == "" {
+		user.DocumentType = previous.DocumentType
+	}
+	user.Identification = domain.NormalizeIdentification(user.Identification)
+	if err := validation.ValidateDocument(user.DocumentType, user.Identification); err != nil {
+		return err
+	}
+
 	user.UpdatedAt = time.Now()
 	if err := uc.repo.Update(ctx, user); err != nil {
+		if errors.Is(err, ErrEmailAlreadyExists) || errors.Is(err, ErrIdentificationAlreadyExists) {
+			return err
+		}
 		return errors.New("error al actualizar el usuario")
 	}
+
+	// El repositorio deja sin verificar el correo nuevo; hay que confirmarlo
+	// antes del próximo inicio de sesión
+	if previous.Email != user.Email {
+		user.EmailVerifiedAt = nil
+		user.VerificationSentAt = nil
+		uc.verification.SendVerification(ctx, user)
+	}
 	return nil
 }
 
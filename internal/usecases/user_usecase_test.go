@@ -0,0 +1,53 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+// updatingUserRepository guarda en memoria las actualizaciones de usuarios
+type updatingUserRepository struct {
+	stubUserRepository
+}
+
+func (r *updatingUserRepository) Update(_ context.Context, user *domain.User) error {
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func TestUpdateUserKeepsDocumentType(t *testing.T) {
+	existing := &domain.User{
+		ID:             uuid.New(),
+		DocumentType:   domain.DocumentTypePA,
+		Identification: "AB12345",
+		Email:          "ana@example.com",
+	}
+	repo := &updatingUserRepository{stubUserRepository{users: map[uuid.UUID]*domain.User{existing.ID: existing}}}
+	uc := NewUserUseCase(repo, nil)
+
+	tests := []struct {
+		name           string
+		documentType   string
+		identification string
+		want           string
+	}{
+		{name: "sin tipo de documento", documentType: "", identification: "ab12345", want: domain.DocumentTypePA},
+		{name: "con tipo de documento", documentType: domain.DocumentTypeCE, identification: "1234567", want: domain.DocumentTypeCE},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := &domain.User{ID: existing.ID, DocumentType: tt.documentType, Identification: tt.identification, Email: existing.Email}
+			if err := uc.UpdateUser(context.Background(), update); err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got := repo.users[existing.ID].DocumentType; got != tt.want {
+				t.Fatalf("se esperaba el tipo %s, se obtuvo %s", tt.want, got)
+			}
+		})
+	}
+}
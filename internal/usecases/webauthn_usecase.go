@@ -0,0 +1,333 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security/webauthn"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+var (
+	ErrWebAuthnChallenge          = errors.New("reto WebAuthn inválido o expirado")
+	ErrWebAuthnVerification       = errors.New("no se pudo verificar la credencial WebAuthn")
+	ErrWebAuthnCredentialNotFound = errors.New("credencial WebAuthn no encontrada")
+	ErrWebAuthnCredentialExists   = errors.New("la credencial WebAuthn ya está registrada")
+	ErrUserVerificationRequired   = errors.New("el autenticador debe verificar al usuario con PIN o biometría")
+)
+
+const maxCredentialNicknameLength = 100
+
+type WebAuthnUseCase struct {
+	userRepo     repositories.UserRepository
+	webauthnRepo repositories.WebAuthnRepository
+	mfa          *MFAUseCase
+	events       *SecurityEventUseCase
+	config       webauthn.Config
+}
+
+// NewWebAuthnUseCase crea una nueva instancia del caso de uso de llaves de acceso
+func NewWebAuthnUseCase(userRepo repositories.UserRepository, webauthnRepo repositories.WebAuthnRepository, mfa *MFAUseCase, events *SecurityEventUseCase, config webauthn.Config) *WebAuthnUseCase {
+	return &WebAuthnUseCase{
+		userRepo:     userRepo,
+		webauthnRepo: webauthnRepo,
+		mfa:          mfa,
+		events:       events,
+		config:       config,
+	}
+}
+
+// BeginRegistration emite el reto y las opciones para registrar una nueva
+// credencial. Devuelve el ID del reto que debe acompañar a la respuesta.
+func (uc *WebAuthnUseCase) BeginRegistration(ctx context.Context, userID uuid.UUID) (string, *webauthn.CreationOptions, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", nil, ErrUserNotFound
+	}
+
+	credentials, err := uc.webauthnRepo.ListCredentialsByUserID(ctx, user.ID.String())
+	if err != nil {
+		return "", nil, err
+	}
+
+	challenge, err := uc.newChallenge(ctx, user.ID.String(), domain.WebAuthnCeremonyRegistration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	entity := webauthn.UserEntity{
+		ID:          webauthn.EncodeBase64URL(user.ID[:]),
+		Name:        user.Email,
+		DisplayName: strings.TrimSpace(user.Name + " " + user.Lastname),
+	}
+	options := uc.config.NewCreationOptions(challenge.Challenge, entity, descriptors(credentials))
+	return challenge.ID, &options, nil
+}
+
+// FinishRegistration verifica la respuesta del autenticador y guarda la
+// credencial. Exige la contraseña actual para que un token de acceso robado no
+// baste para agregar una llave. La primera credencial activa el segundo factor
+// del usuario; en ese caso también devuelve los códigos de recuperación.
+func (uc *WebAuthnUseCase) FinishRegistration(ctx context.Context, actor Actor, userID uuid.UUID, challengeID, password, nickname string, resp webauthn.AttestationResponse) (*domain.WebAuthnCredential, []string, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, nil, ErrUserNotFound
+	}
+	if !security.ComparePassword(user.Password, password) {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	challenge, err := uc.consumeChallenge(ctx, challengeID, domain.WebAuthnCeremonyRegistration)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge.UserID != userID.String() {
+		return nil, nil, ErrWebAuthnChallenge
+	}
+
+	verified, err := uc.config.VerifyRegistration(challenge.Challenge, resp)
+	if err != nil {
+		return nil, nil, ErrWebAuthnVerification
+	}
+
+	nickname = strings.TrimSpace(nickname)
+	if len(nickname) > maxCredentialNicknameLength {
+		nickname = nickname[:maxCredentialNicknameLength]
+	}
+	transports := resp.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+	aaguid := ""
+	if id, err := uuid.FromBytes(verified.AAGUID); err == nil {
+		aaguid = id.String()
+	}
+
+	credential := &domain.WebAuthnCredential{
+		ID:           uuid.New().String(),
+		UserID:       userID.String(),
+		CredentialID: webauthn.EncodeBase64URL(verified.ID),
+		PublicKey:    verified.PublicKey,
+		SignCount:    verified.SignCount,
+		Transports:   transports,
+		AAGUID:       aaguid,
+		Nickname:     nickname,
+		CreatedAt:    time.Now(),
+	}
+	if err := uc.webauthnRepo.CreateCredential(ctx, credential); err != nil {
+		return nil, nil, err
+	}
+	uc.mfa.recordEvent(ctx, actor, userID.String(), domain.EventWebAuthnRegistered, "credencial WebAuthn registrada: "+credential.ID)
+
+	recoveryCodes, err := uc.mfa.EnableSecondFactor(ctx, actor, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return credential, recoveryCodes, nil
+}
+
+// ListCredentials lista las credenciales WebAuthn del usuario
+func (uc *WebAuthnUseCase) ListCredentials(ctx context.Context, userID uuid.UUID) ([]*domain.WebAuthnCredential, error) {
+	return uc.webauthnRepo.ListCredentialsByUserID(ctx, userID.String())
+}
+
+// DeleteCredential elimina una credencial del usuario. Exige la contraseña
+// actual, como al registrarla, para que un token de acceso robado no baste
+// para quitar el segundo factor. Si era el último, deja de exigirse MFA.
+func (uc *WebAuthnUseCase) DeleteCredential(ctx context.Context, actor Actor, userID uuid.UUID, id, password string) error {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !security.ComparePassword(user.Password, password) {
+		return ErrInvalidCredentials
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrWebAuthnCredentialNotFound
+	}
+	if err := uc.webauthnRepo.DeleteCredential(ctx, userID.String(), id); err != nil {
+		return err
+	}
+	uc.mfa.recordEvent(ctx, actor, userID.String(), domain.EventWebAuthnRemoved, "credencial WebAuthn eliminada: "+id)
+
+	return uc.mfa.SecondFactorRemoved(ctx, userID)
+}
+
+// BeginLogin emite el reto de autenticación. Sin mfaToken es un inicio de
+// sesión sin contraseña con credenciales residentes; con mfaToken la llave se
+// usa como segundo factor del reto MFA pendiente.
+func (uc *WebAuthnUseCase) BeginLogin(ctx context.Context, mfaToken string) (string, *webauthn.RequestOptions, error) {
+	if mfaToken == "" {
+		challenge, err := uc.newChallenge(ctx, "", domain.WebAuthnCeremonyLogin)
+		if err != nil {
+			return "", nil, err
+		}
+		options := uc.config.NewRequestOptions(challenge.Challenge, []webauthn.CredentialDescriptor{}, webauthn.Required)
+		return challenge.ID, &options, nil
+	}
+
+	user, err := uc.mfa.ChallengeUser(ctx, mfaToken)
+	if err != nil {
+		return "", nil, err
+	}
+	credentials, err := uc.webauthnRepo.ListCredentialsByUserID(ctx, user.ID.String())
+	if err != nil {
+		return "", nil, err
+	}
+	if len(credentials) == 0 {
+		return "", nil, ErrUnsupportedMFA
+	}
+
+	challenge, err := uc.newChallenge(ctx, user.ID.String(), domain.WebAuthnCeremonyMFA)
+	if err != nil {
+		return "", nil, err
+	}
+	options := uc.config.NewRequestOptions(challenge.Challenge, descriptors(credentials), webauthn.Preferred)
+	return challenge.ID, &options, nil
+}
+
+// FinishLogin verifica la firma del autenticador y devuelve el usuario para
+// que el llamador emita la sesión
+func (uc *WebAuthnUseCase) FinishLogin(ctx context.Context, challengeID, mfaToken string, resp webauthn.AssertionResponse, userAgent, clientIP string) (*domain.User, error) {
+	if mfaToken == "" {
+		challenge, err := uc.consumeChallenge(ctx, challengeID, domain.WebAuthnCeremonyLogin)
+		if err != nil {
+			return nil, err
+		}
+
+		actor := Actor{ClientIP: clientIP, UserAgent: userAgent}
+		// Sin contraseña, la llave debe aportar por sí sola dos factores
+		credential, err := uc.verifyAssertion(ctx, actor, challenge, "", webauthn.Required, resp)
+		if err != nil {
+			return nil, err
+		}
+
+		userID, err := uuid.Parse(credential.UserID)
+		if err != nil {
+			return nil, ErrWebAuthnVerification
+		}
+		user, err := uc.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			return nil, ErrWebAuthnVerification
+		}
+		return user, nil
+	}
+
+	challenge, err := uc.consumeChallenge(ctx, challengeID, domain.WebAuthnCeremonyMFA)
+	if err != nil {
+		return nil, err
+	}
+	result, err := uc.mfa.ResolveChallengeWith(ctx, mfaToken, domain.MFAMethodWebAuthn, userAgent, clientIP, func(user *domain.User) error {
+		if challenge.UserID != user.ID.String() {
+			return ErrWebAuthnChallenge
+		}
+		actor := Actor{UserID: user.ID.String(), ClientIP: clientIP, UserAgent: userAgent}
+		if _, err := uc.verifyAssertion(ctx, actor, challenge, user.ID.String(), webauthn.Preferred, resp); err != nil {
+			if errors.Is(err, ErrWebAuthnVerification) {
+				return ErrInvalidMFACode
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.User, nil
+}
+
+// verifyAssertion valida la firma con la credencial presentada y actualiza su
+// contador. Si expectedUserID no está vacío, la credencial debe pertenecerle.
+func (uc *WebAuthnUseCase) verifyAssertion(ctx context.Context, actor Actor, challenge *domain.WebAuthnChallenge, expectedUserID, userVerification string, resp webauthn.AssertionResponse) (*domain.WebAuthnCredential, error) {
+	credential, err := uc.webauthnRepo.GetCredentialByCredentialID(ctx, webauthn.EncodeBase64URL(resp.CredentialID))
+	if err != nil {
+		if errors.Is(err, ErrWebAuthnCredentialNotFound) {
+			return nil, ErrWebAuthnVerification
+		}
+		return nil, err
+	}
+	if expectedUserID != "" && credential.UserID != expectedUserID {
+		return nil, ErrWebAuthnVerification
+	}
+
+	// El user handle, si viene, es el ID del usuario fijado durante el registro
+	if len(resp.UserHandle) > 0 {
+		userID, err := uuid.Parse(credential.UserID)
+		if err != nil || string(resp.UserHandle) != string(userID[:]) {
+			return nil, ErrWebAuthnVerification
+		}
+	}
+
+	assertion, err := uc.config.VerifyAssertion(challenge.Challenge, userVerification, credential.PublicKey, credential.SignCount, resp)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrClonedAuthenticator) {
+			uc.mfa.recordEvent(ctx, actor, credential.UserID, domain.EventWebAuthnCloned, "contador de firmas no avanzó en la credencial "+credential.ID)
+		}
+		if errors.Is(err, webauthn.ErrUserNotVerified) {
+			return nil, ErrUserVerificationRequired
+		}
+		return nil, ErrWebAuthnVerification
+	}
+
+	if err := uc.webauthnRepo.UpdateCredentialUsage(ctx, credential.ID, assertion.SignCount, time.Now()); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// newChallenge genera y guarda el reto de una ceremonia
+func (uc *WebAuthnUseCase) newChallenge(ctx context.Context, userID, ceremony string) (*domain.WebAuthnChallenge, error) {
+	value, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, errors.New("error al generar el reto WebAuthn")
+	}
+
+	challenge := &domain.WebAuthnChallenge{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Ceremony:  ceremony,
+		Challenge: value,
+		ExpiresAt: time.Now().Add(webauthn.Timeout),
+		CreatedAt: time.Now(),
+	}
+	if err := uc.webauthnRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// consumeChallenge obtiene y elimina un reto, de modo que solo pueda usarse una vez
+func (uc *WebAuthnUseCase) consumeChallenge(ctx context.Context, id, ceremony string) (*domain.WebAuthnChallenge, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrWebAuthnChallenge
+	}
+	challenge, err := uc.webauthnRepo.GetChallenge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.webauthnRepo.DeleteChallenge(ctx, challenge.ID); err != nil {
+		return nil, err
+	}
+	if challenge.Ceremony != ceremony || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrWebAuthnChallenge
+	}
+	return challenge, nil
+}
+
+// descriptors convierte las credenciales guardadas en descriptores para el navegador
+func descriptors(credentials []*domain.WebAuthnCredential) []webauthn.CredentialDescriptor {
+	result := make([]webauthn.CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		result = append(result, webauthn.CredentialDescriptor{
+			Type:       "public-key",
+			ID:         credential.CredentialID,
+			Transports: credential.Transports,
+		})
+	}
+	return result
+}
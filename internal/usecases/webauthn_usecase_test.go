@@ -0,0 +1,72 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security"
+	"github.com/kevinhc2110/Auth_UCP/internal/infrastructure/security/webauthn"
+	"github.com/kevinhc2110/Auth_UCP/internal/repositories"
+)
+
+// stubUserRepository solo implementa FindByID; el resto de métodos no se usa
+type stubUserRepository struct {
+	repositories.UserRepository
+	users map[uuid.UUID]*domain.User
+}
+
+func (r *stubUserRepository) FindByID(_ context.Context, id uuid.UUID) (*domain.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.New("usuario no encontrado")
+	}
+	return user, nil
+}
+
+// stubWebAuthnRepository registra las credenciales eliminadas
+type stubWebAuthnRepository struct {
+	repositories.WebAuthnRepository
+	deleted []string
+}
+
+func (r *stubWebAuthnRepository) DeleteCredential(_ context.Context, _, id string) error {
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+func TestDeleteCredentialRequiresPassword(t *testing.T) {
+	security.SetPasswordHasher(security.NewArgon2idHasher(security.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}))
+	t.Cleanup(func() { security.SetPasswordHasher(security.NewArgon2idHasher(security.DefaultArgon2idParams)) })
+
+	hash, err := security.HashPassword("Contraseña1!")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Password: hash, MFAEnabled: true}
+	userRepo := &stubUserRepository{users: map[uuid.UUID]*domain.User{user.ID: user}}
+	webauthnRepo := &stubWebAuthnRepository{}
+	uc := NewWebAuthnUseCase(userRepo, webauthnRepo, nil, nil, webauthn.Config{})
+
+	tests := []struct {
+		name     string
+		password string
+	}{
+		{name: "solo el token de acceso", password: ""},
+		{name: "contraseña incorrecta", password: "Contraseña2!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := uc.DeleteCredential(context.Background(), Actor{UserID: user.ID.String()}, user.ID, uuid.NewString(), tt.password)
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("se esperaba ErrInvalidCredentials, se obtuvo %v", err)
+			}
+			if len(webauthnRepo.deleted) != 0 {
+				t.Fatalf("se eliminó la credencial sin confirmar la contraseña: %v", webauthnRepo.deleted)
+			}
+		})
+	}
+}
@@ -4,7 +4,8 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 -- Crear la tabla users
 CREATE TABLE IF NOT EXISTS users (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    identification VARCHAR(50) UNIQUE NOT NULL,
+    document_type VARCHAR(3) NOT NULL DEFAULT 'CC' CHECK (document_type IN ('CC', 'TI', 'CE', 'PEP', 'PA', 'PPT')),
+    identification VARCHAR(50) NOT NULL,
     name VARCHAR(100) NOT NULL,
     lastname VARCHAR(100) NOT NULL,
     email VARCHAR(150) UNIQUE NOT NULL,
@@ -13,18 +14,241 @@ CREATE TABLE IF NOT EXISTS users (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     lastlogin_at TIMESTAMP,
-    active BOOLEAN DEFAULT TRUE
+    active BOOLEAN DEFAULT TRUE,
+    mfa_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    email_verified_at TIMESTAMP,
+    verification_sent_at TIMESTAMP, -- Último envío del enlace de verificación, para limitar reenvíos
+    -- El mismo número puede existir en documentos de distinto tipo
+    CONSTRAINT users_document_key UNIQUE (document_type, identification)
 );
 
 CREATE TABLE IF NOT EXISTS sessions (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    family_id UUID NOT NULL, -- Todos los refresh tokens rotados desde un mismo login
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     refresh_token TEXT NOT NULL UNIQUE,
     user_agent TEXT NOT NULL,
     client_ip VARCHAR(45) NOT NULL, -- IPv6 compatible
     is_blocked BOOLEAN DEFAULT FALSE,
+    blocked_reason TEXT NOT NULL DEFAULT '',
+    blocked_by UUID REFERENCES users(id) ON DELETE SET NULL, -- Administrador que bloqueó la sesión
+    blocked_at TIMESTAMP,
+    rotated_at TIMESTAMP, -- Fecha en que el token fue reemplazado; volver a usarlo revoca la familia
     expires_at TIMESTAMP NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP 
 );
 
+CREATE INDEX IF NOT EXISTS idx_sessions_family_id ON sessions(family_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_client_ip ON sessions(client_ip);
+
+CREATE TABLE IF NOT EXISTS security_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    event_type VARCHAR(50) NOT NULL,
+    client_ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_user_id ON security_events(user_id);
+
+CREATE TABLE IF NOT EXISTS mfa_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0, -- Evita reutilizar un código ya aceptado
+    confirmed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    attempts INT NOT NULL DEFAULT 0,
+    first_factor VARCHAR(20) NOT NULL DEFAULT 'password', -- Método con el que se validó el primer factor
+    user_agent TEXT NOT NULL DEFAULT '',
+    client_ip VARCHAR(45) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    code_prefix VARCHAR(2) NOT NULL DEFAULT '', -- Parte no secreta del código para elegir qué hash verificar
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id TEXT NOT NULL UNIQUE, -- base64url
+    public_key BYTEA NOT NULL, -- Clave pública COSE
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    transports TEXT[] NOT NULL DEFAULT '{}',
+    aaguid TEXT NOT NULL DEFAULT '',
+    nickname VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_challenges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE, -- NULL en el inicio de sesión sin contraseña
+    ceremony VARCHAR(20) NOT NULL,
+    challenge TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS known_devices (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fingerprint TEXT NOT NULL, -- Hash del user agent
+    user_agent TEXT NOT NULL,
+    last_ip VARCHAR(45) NOT NULL DEFAULT '',
+    first_seen_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, fingerprint)
+);
+
+CREATE TABLE IF NOT EXISTS magic_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    nonce_hash TEXT NOT NULL, -- Hash del nonce guardado en la cookie del navegador que pidió el enlace
+    user_agent TEXT NOT NULL DEFAULT '',
+    client_ip VARCHAR(45) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS email_otps (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(20) NOT NULL, -- login o mfa
+    code_hash TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL, -- Último envío, para limitar reenvíos
+    PRIMARY KEY (user_id, purpose)
+);
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    client_id TEXT PRIMARY KEY,
+    secret_hash TEXT NOT NULL DEFAULT '', -- Vacío en los clientes públicos
+    name VARCHAR(100) NOT NULL,
+    client_type VARCHAR(20) NOT NULL CHECK (client_type IN ('confidential', 'public')),
+    redirect_uris TEXT[] NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}', -- Scopes que el cliente puede solicitar
+    grant_types TEXT[] NOT NULL DEFAULT '{authorization_code}',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    code_hash TEXT PRIMARY KEY,
+    client_id TEXT NOT NULL REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    code_challenge TEXT NOT NULL, -- PKCE
+    code_challenge_method VARCHAR(10) NOT NULL,
+    nonce TEXT NOT NULL DEFAULT '', -- OpenID Connect: se devuelve en el id_token
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid TEXT PRIMARY KEY, -- Huella RFC 7638 de la clave pública
+    algorithm VARCHAR(10) NOT NULL,
+    private_key TEXT NOT NULL, -- PEM, cifrada con SIGNING_KEY_SECRET si está configurado
+    created_at TIMESTAMP NOT NULL,
+    retired_at TIMESTAMP, -- NULL en la clave activa
+    published_until TIMESTAMP -- Hasta cuándo se publica en el JWKS tras retirarse
+);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti TEXT PRIMARY KEY,
+    user_id UUID, -- Dueño del token, si lo tiene
+    client_id TEXT NOT NULL DEFAULT '', -- Vacío en los tokens de la propia API
+    expires_at TIMESTAMP NOT NULL, -- Se conserva hasta que el token expira
+    revoked_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_revoked_at ON revoked_tokens(revoked_at);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+CREATE TABLE IF NOT EXISTS session_revocations (
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('session', 'user')),
+    subject TEXT NOT NULL, -- family_id de la sesión o id del usuario
+    revoked_at TIMESTAMP NOT NULL, -- Se invalidan los tokens de acceso emitidos hasta este momento
+    expires_at TIMESTAMP NOT NULL, -- Cuando el último de esos tokens expira
+    PRIMARY KEY (kind, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_revocations_revoked_at ON session_revocations(revoked_at);
+CREATE INDEX IF NOT EXISTS idx_session_revocations_expires_at ON session_revocations(expires_at);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id UUID PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL si el identificador no corresponde a ninguna cuenta
+    identifier VARCHAR(255) NOT NULL, -- Correo normalizado o documento, p. ej. CC:1020304050
+    client_ip VARCHAR(45) NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    success BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_id ON login_attempts(user_id);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_client_ip ON login_attempts(client_ip);
+
+CREATE TABLE IF NOT EXISTS login_throttles (
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('account', 'ip')),
+    throttle_key TEXT NOT NULL, -- Identificador normalizado de la cuenta o IP
+    failures INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP, -- Demora progresiva tras cada fallo
+    locked_until TIMESTAMP, -- Bloqueo temporal al llegar al máximo de fallos
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (kind, throttle_key)
+);
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    bucket_key TEXT PRIMARY KEY, -- Ruta, tipo de clave y valor, p. ej. login:ip:203.0.113.7
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    full_at TIMESTAMP NOT NULL -- A partir de aquí el cubo está lleno y la fila sobra
+);
+
+-- Actualizaciones de migrations/upgrades ya aplicadas. Una base creada con
+-- este archivo ya tiene el esquema actual, así que se registran todas; cada
+-- actualización nueva debe añadirse también aquí.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version TEXT PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO schema_migrations (version) VALUES
+    ('001_email_verification'),
+    ('002_document_type'),
+    ('003_mfa_enabled'),
+    ('004_session_blocking'),
+    ('005_session_families')
+ON CONFLICT DO NOTHING;
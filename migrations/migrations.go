@@ -0,0 +1,9 @@
+// Package migrations contiene el esquema de la base de datos. init.sql crea el
+// esquema completo y upgrades/ los cambios para las bases creadas con una
+// versión anterior, en el orden de sus nombres.
+package migrations
+
+import "embed"
+
+//go:embed init.sql upgrades/*.sql
+var FS embed.FS
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+)
+
+// Una base creada con init.sql no debe volver a aplicar las actualizaciones,
+// así que init.sql tiene que registrarlas todas
+func TestInitRegistersEveryUpgrade(t *testing.T) {
+	schema, err := FS.ReadFile("init.sql")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	upgrades, err := fs.Glob(FS, "upgrades/*.sql")
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(upgrades) == 0 {
+		t.Fatal("no hay actualizaciones embebidas")
+	}
+
+	for _, name := range upgrades {
+		version := strings.TrimSuffix(path.Base(name), ".sql")
+		if !strings.Contains(string(schema), "('"+version+"')") {
+			t.Fatalf("init.sql no registra la actualización %s", version)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+-- Tipo de documento. Las cuentas que ya existían se registraron con cédula de
+-- ciudadanía, y el mismo número puede existir en documentos de distinto tipo,
+-- así que la unicidad pasa a ser por tipo y número.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS document_type VARCHAR(3) NOT NULL DEFAULT 'CC'
+    CHECK (document_type IN ('CC', 'TI', 'CE', 'PEP', 'PA', 'PPT'));
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_identification_key;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conrelid = 'users'::regclass AND conname = 'users_document_key') THEN
+        ALTER TABLE users ADD CONSTRAINT users_document_key UNIQUE (document_type, identification);
+    END IF;
+END $$;
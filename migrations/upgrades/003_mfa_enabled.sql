@@ -0,0 +1,2 @@
+-- Segundo factor. Ninguna cuenta que ya existía lo tiene configurado.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_enabled BOOLEAN NOT NULL DEFAULT FALSE;
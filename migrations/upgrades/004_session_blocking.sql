@@ -0,0 +1,4 @@
+-- Bloqueo de sesiones por un administrador
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS blocked_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS blocked_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS blocked_at TIMESTAMP;
@@ -0,0 +1,6 @@
+-- Rotación de refresh tokens. Cada sesión que ya existía forma su propia familia.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS family_id UUID;
+UPDATE sessions SET family_id = id WHERE family_id IS NULL;
+ALTER TABLE sessions ALTER COLUMN family_id SET NOT NULL;
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS rotated_at TIMESTAMP;
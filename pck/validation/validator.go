@@ -14,10 +14,55 @@ var validate = validator.New()
 
 // Expresiones regulares
 var (
-	identificationRegex = regexp.MustCompile(`^\d{6,12}$`) // Solo números, entre 6 y 12 dígitos
-	nameRegex          = regexp.MustCompile(`^[A-Za-záéíóúÁÉÍÓÚñÑ\s-]{2,50}$`) // Letras y espacios, 2-50 caracteres
+	nameRegex = regexp.MustCompile(`^[A-Za-záéíóúÁÉÍÓÚñÑ\s-]{2,50}$`) // Letras y espacios, 2-50 caracteres
 )
 
+// documentRule es el formato del número de cada tipo de documento
+type documentRule struct {
+	regex   *regexp.Regexp
+	message string
+}
+
+var documentRules = map[string]documentRule{
+	domain.DocumentTypeCC: {
+		regex:   regexp.MustCompile(`^\d{6,12}$`),
+		message: "la cédula de ciudadanía debe contener solo números y tener entre 6 y 12 dígitos",
+	},
+	domain.DocumentTypeTI: {
+		regex:   regexp.MustCompile(`^\d{10,11}$`),
+		message: "la tarjeta de identidad debe contener solo números y tener 10 u 11 dígitos",
+	},
+	domain.DocumentTypeCE: {
+		regex:   regexp.MustCompile(`^\d{6,10}$`),
+		message: "la cédula de extranjería debe contener solo números y tener entre 6 y 10 dígitos",
+	},
+	domain.DocumentTypePEP: {
+		regex:   regexp.MustCompile(`^\d{15}$`),
+		message: "el permiso especial de permanencia debe tener 15 dígitos",
+	},
+	domain.DocumentTypePA: {
+		regex:   regexp.MustCompile(`^[A-Z0-9]{5,20}$`),
+		message: "el pasaporte debe contener solo letras y números, entre 5 y 20 caracteres",
+	},
+	domain.DocumentTypePPT: {
+		regex:   regexp.MustCompile(`^\d{6,10}$`),
+		message: "el permiso por protección temporal debe contener solo números y tener entre 6 y 10 dígitos",
+	},
+}
+
+// ValidateDocument verifica el número del documento según su tipo. Espera el
+// número ya normalizado con domain.NormalizeIdentification.
+func ValidateDocument(documentType, identification string) error {
+	rule, ok := documentRules[documentType]
+	if !ok {
+		return errors.New("tipo de documento no soportado")
+	}
+	if !rule.regex.MatchString(identification) {
+		return errors.New(rule.message)
+	}
+	return nil
+}
+
 // ValidatePassword verifica que la contraseña cumpla con los requisitos de seguridad
 func ValidatePassword(password string) error {
 	var hasUpper, hasDigit, hasSpecial bool
@@ -50,6 +95,25 @@ func ValidatePassword(password string) error {
 	return nil
 }
 
+// ValidateLoginIdentifier verifica que el inicio de sesión traiga un correo o
+// un documento de identidad válido, pero no ambos
+func ValidateLoginIdentifier(identifier domain.LoginIdentifier) error {
+	if identifier.Email != "" {
+		if identifier.DocumentType != "" || identifier.Identification != "" {
+			return errors.New("indica el correo o el documento de identidad, no ambos")
+		}
+		if err := validate.Var(identifier.Email, "email"); err != nil {
+			return errors.New("el correo electrónico no es válido")
+		}
+		return nil
+	}
+
+	if identifier.Identification == "" {
+		return errors.New("indica el correo o el documento de identidad")
+	}
+	return ValidateDocument(identifier.DocumentType, domain.NormalizeIdentification(identifier.Identification))
+}
+
 // ValidateUser valida los campos de un usuario antes de guardarlo
 func ValidateUser(user *domain.User) error {
 	// Validar campos obligatorios
@@ -57,9 +121,9 @@ func ValidateUser(user *domain.User) error {
 		return errors.New("todos los campos son obligatorios")
 	}
 
-	// Validar identificación
-	if !identificationRegex.MatchString(user.Identification) {
-		return errors.New("la identificación debe contener solo números y tener entre 6 y 12 dígitos")
+	// Validar identificación según el tipo de documento
+	if err := ValidateDocument(user.DocumentType, user.Identification); err != nil {
+		return err
 	}
 
 	// Validar nombre y apellido
@@ -0,0 +1,112 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/kevinhc2110/Auth_UCP/internal/domain"
+)
+
+func TestValidateDocument(t *testing.T) {
+	tests := []struct {
+		name           string
+		documentType   string
+		identification string
+		valid          bool
+	}{
+		{name: "CC mínima", documentType: domain.DocumentTypeCC, identification: "123456", valid: true},
+		{name: "CC máxima", documentType: domain.DocumentTypeCC, identification: "123456789012", valid: true},
+		{name: "CC corta", documentType: domain.DocumentTypeCC, identification: "12345", valid: false},
+		{name: "CC larga", documentType: domain.DocumentTypeCC, identification: "1234567890123", valid: false},
+		{name: "CC con letras", documentType: domain.DocumentTypeCC, identification: "12345A", valid: false},
+		{name: "CC con puntos", documentType: domain.DocumentTypeCC, identification: "1.234.567", valid: false},
+
+		{name: "TI de 10 dígitos", documentType: domain.DocumentTypeTI, identification: "1234567890", valid: true},
+		{name: "TI de 11 dígitos", documentType: domain.DocumentTypeTI, identification: "12345678901", valid: true},
+		{name: "TI de 9 dígitos", documentType: domain.DocumentTypeTI, identification: "123456789", valid: false},
+		{name: "TI de 12 dígitos", documentType: domain.DocumentTypeTI, identification: "123456789012", valid: false},
+
+		{name: "CE mínima", documentType: domain.DocumentTypeCE, identification: "123456", valid: true},
+		{name: "CE máxima", documentType: domain.DocumentTypeCE, identification: "1234567890", valid: true},
+		{name: "CE larga", documentType: domain.DocumentTypeCE, identification: "12345678901", valid: false},
+
+		{name: "PEP de 15 dígitos", documentType: domain.DocumentTypePEP, identification: "123456789012345", valid: true},
+		{name: "PEP de 14 dígitos", documentType: domain.DocumentTypePEP, identification: "12345678901234", valid: false},
+		{name: "PEP de 16 dígitos", documentType: domain.DocumentTypePEP, identification: "1234567890123456", valid: false},
+
+		{name: "PA alfanumérico", documentType: domain.DocumentTypePA, identification: "AB12345", valid: true},
+		{name: "PA máximo", documentType: domain.DocumentTypePA, identification: "ABCDEFGHIJ0123456789", valid: true},
+		{name: "PA corto", documentType: domain.DocumentTypePA, identification: "AB12", valid: false},
+		{name: "PA largo", documentType: domain.DocumentTypePA, identification: "ABCDEFGHIJ01234567890", valid: false},
+		{name: "PA en minúsculas", documentType: domain.DocumentTypePA, identification: "ab12345", valid: false},
+		{name: "PA con guion", documentType: domain.DocumentTypePA, identification: "AB-12345", valid: false},
+
+		{name: "PPT mínimo", documentType: domain.DocumentTypePPT, identification: "123456", valid: true},
+		{name: "PPT máximo", documentType: domain.DocumentTypePPT, identification: "1234567890", valid: true},
+		{name: "PPT con letras", documentType: domain.DocumentTypePPT, identification: "ABC123", valid: false},
+
+		{name: "tipo vacío", documentType: "", identification: "123456", valid: false},
+		{name: "tipo desconocido", documentType: "NIT", identification: "123456", valid: false},
+		{name: "número vacío", documentType: domain.DocumentTypeCC, identification: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDocument(tt.documentType, tt.identification)
+			if tt.valid && err != nil {
+				t.Fatalf("documento válido rechazado: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("se esperaba un error")
+			}
+		})
+	}
+}
+
+func TestValidateDocumentCoversEveryType(t *testing.T) {
+	types := []string{
+		domain.DocumentTypeCC,
+		domain.DocumentTypeTI,
+		domain.DocumentTypeCE,
+		domain.DocumentTypePEP,
+		domain.DocumentTypePA,
+		domain.DocumentTypePPT,
+	}
+	for _, documentType := range types {
+		if _, ok := documentRules[documentType]; !ok {
+			t.Fatalf("el tipo %s no tiene regla de validación", documentType)
+		}
+	}
+	if len(documentRules) != len(types) {
+		t.Fatalf("se esperaban %d reglas, hay %d", len(types), len(documentRules))
+	}
+}
+
+func TestValidateLoginIdentifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		identifier domain.LoginIdentifier
+		valid      bool
+	}{
+		{name: "correo", identifier: domain.LoginIdentifier{Email: "ana@example.com"}, valid: true},
+		{name: "documento", identifier: domain.LoginIdentifier{DocumentType: domain.DocumentTypeCC, Identification: "1234567890"}, valid: true},
+		{name: "documento sin normalizar", identifier: domain.LoginIdentifier{DocumentType: domain.DocumentTypePA, Identification: " ab12345 "}, valid: true},
+		{name: "vacío", identifier: domain.LoginIdentifier{}, valid: false},
+		{name: "correo inválido", identifier: domain.LoginIdentifier{Email: "ana@"}, valid: false},
+		{name: "correo y documento", identifier: domain.LoginIdentifier{Email: "ana@example.com", DocumentType: domain.DocumentTypeCC, Identification: "1234567890"}, valid: false},
+		{name: "correo y tipo de documento", identifier: domain.LoginIdentifier{Email: "ana@example.com", DocumentType: domain.DocumentTypeCC}, valid: false},
+		{name: "documento sin tipo", identifier: domain.LoginIdentifier{Identification: "1234567890"}, valid: false},
+		{name: "documento inválido", identifier: domain.LoginIdentifier{DocumentType: domain.DocumentTypeTI, Identification: "123"}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLoginIdentifier(tt.identifier)
+			if tt.valid && err != nil {
+				t.Fatalf("identificador válido rechazado: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("se esperaba un error")
+			}
+		})
+	}
+}